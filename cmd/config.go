@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"WudangMeta/cmn"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// configCmd 配置管理命令
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect application configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file and environment overrides",
+	Long: `The validate command loads the config file and WUDANG_ environment overrides
+the same way serve does, and reports every problem at once. It exits with a
+non-zero status when the config is invalid. Checks that need the database,
+such as whether a points type exists, are not performed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := cmn.LoadConfig()
+		if cfg == nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		var problems cmn.ConfigErrors
+		errors.As(err, &problems)
+		problems = append(problems, cfg.Validate()...)
+
+		if path := cmn.ConfigFileUsed(); path != "" {
+			fmt.Println("config file:", path)
+		}
+		if len(problems) == 0 {
+			fmt.Println("config is valid")
+			return
+		}
+
+		fmt.Printf("found %d problem(s):\n", len(problems))
+		for _, problem := range problems {
+			fmt.Println("  -", problem)
+		}
+		os.Exit(1)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
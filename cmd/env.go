@@ -1,3 +1,6 @@
 package cmd
 
-var debug bool
+var (
+	debug   bool
+	cfgFile string // 配置文件路径，为空时按默认路径查找 .config.json
+)
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	migrateTarget int64 // migrate up 的目标版本，0 表示最新版本
+	migrateSteps  int   // migrate down 回滚的版本数
+)
+
+// migrateCmd 数据库迁移命令
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage database schema migrations",
+	Long: `The migrate command applies, rolls back and lists the versioned SQL migrations
+embedded in this binary. The serve command only verifies the schema version,
+so run "migrate up" before starting a new version.`,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply pending migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := initMigrate()
+		defer stop()
+
+		done, err := cmn.MigrateUp(ctx, cmn.GormDB, migrateTarget)
+		for _, m := range done {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			cmn.GetLogger().Fatal("[ FAIL ] migrate up failed", zap.Error(err))
+		}
+		if len(done) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back the most recently applied migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := initMigrate()
+		defer stop()
+
+		done, err := cmn.MigrateDown(ctx, cmn.GormDB, migrateSteps)
+		for _, m := range done {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			cmn.GetLogger().Fatal("[ FAIL ] migrate down failed", zap.Error(err))
+		}
+		if len(done) == 0 {
+			fmt.Println("no applied migrations")
+		}
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := initMigrate()
+		defer stop()
+
+		statuses, err := cmn.QueryMigrationStatus(ctx, cmn.GormDB)
+		if err != nil {
+			cmn.GetLogger().Fatal("[ FAIL ] query migration status failed", zap.Error(err))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			state, appliedAt := "pending", "-"
+			if s.Applied {
+				state = "applied"
+				appliedAt = time.UnixMilli(s.AppliedAt).Format(time.DateTime)
+			}
+			if s.Unknown {
+				state = "unknown"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		_ = w.Flush()
+	},
+}
+
+// initMigrate 初始化迁移命令需要的日志、配置与数据库连接，返回收到中断信号时取消的上下文
+func initMigrate() (context.Context, context.CancelFunc) {
+	cmn.InitLogger(debug)
+	cmn.InitConfig()
+	cmn.ConnectDB(debug)
+
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+func init() {
+	migrateUpCmd.Flags().Int64Var(&migrateTarget, "to", 0, "目标版本，默认执行到最新版本")
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "回滚的版本数")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
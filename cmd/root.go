@@ -4,6 +4,7 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"WudangMeta/cmn"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -38,7 +39,10 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.WudangMeta.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "配置文件路径（默认在当前目录及上级目录查找 .config.json）")
+	cobra.OnInitialize(func() {
+		cmn.SetConfigFile(cfgFile)
+	})
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -5,21 +5,33 @@ package cmd
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/captcha"
 	"WudangMeta/cmn/llm"
+	"WudangMeta/cmn/metrics"
 	"WudangMeta/cmn/points_core"
+	"WudangMeta/cmn/scheduler"
+	"WudangMeta/cmn/settings"
 	"WudangMeta/cmn/sms"
+	"WudangMeta/cmn/tracing"
 	"WudangMeta/cmn/ubanquan_core"
 	"WudangMeta/router"
+	"WudangMeta/serve/admin"
 	"WudangMeta/serve/asset"
+	"WudangMeta/serve/health"
 	"WudangMeta/serve/points"
 	"WudangMeta/serve/raffle"
 	"WudangMeta/serve/ranking"
 	"WudangMeta/serve/task"
 	"WudangMeta/serve/ubanquan"
 	"WudangMeta/serve/user"
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 
 	"github.com/gin-gonic/gin"
@@ -51,6 +63,11 @@ var serveCmd = &cobra.Command{
 		}
 
 		r.Use(gin.Recovery())
+		r.Use(cmn.RequestMiddleware())
+		r.Use(tracing.GinMiddleware())
+		r.Use(metrics.GinMiddleware())
+		// 处理函数以 *gin.Context 作为 ctx 传递，需回退到 c.Request 的上下文才能取到链路信息
+		r.ContextWithFallback = true
 
 		// 初始化地基模块（顺序不能改变）
 		cmn.InitLogger(debug)
@@ -58,39 +75,143 @@ var serveCmd = &cobra.Command{
 		cmn.InitDB(debug)
 		logger := cmn.GetLogger()
 
-		// 初始化公共模块
-		sms.Init()
-		points_core.Init()
-		llm.Init()
-		ubanquan_core.Init()
+		// 根上下文，收到 SIGINT/SIGTERM 时取消，传递给所有模块用于停止后台任务
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		// 每天零点切割日志文件，收到退出信号后停止
+		cmn.StartLogRotation(ctx)
+
+		// 初始化公共模块（调度器与运行时配置需先于注册任务、配置项的模块初始化）
+		tracing.Init(ctx)
+		scheduler.Init(ctx)
+		settings.Init(ctx)
+		sms.Init(ctx)
+		captcha.Init(ctx)
+		points_core.Init(ctx)
+		llm.Init(ctx)
+		ubanquan_core.Init(ctx)
 
 		// 初始化服务模块
-		user.Init()
-		asset.Init()
-		ubanquan.Init()
-		points.Init()
-		ranking.Init()
-		task.Init()
-		raffle.Init()
+		admin.Init(ctx)
+		user.Init(ctx)
+		asset.Init(ctx)
+		ubanquan.Init(ctx)
+		points.Init(ctx)
+		ranking.Init(ctx)
+		task.Init(ctx)
+		raffle.Init(ctx)
+		health.Init(ctx)
+
+		// 所有模块订阅配置变更后再开始监听配置文件
+		cmn.WatchConfig(ctx)
+
+		// 启动定时任务调度
+		err := scheduler.Start(ctx)
+		if err != nil {
+			logger.Fatal("[ FAIL ] failed to start scheduler", zap.Error(err))
+		}
 
 		cmn.MiniLogger.Info("[ YES ] all modules initialed", zap.String("version", cmn.Version))
 
+		// 读取运行配置
+		serverConfig := cmn.GetConfig().Server
+
+		// 仅信任配置的代理转发的客户端IP，限流与图形验证风险均依赖客户端IP
+		err = r.SetTrustedProxies(serverConfig.TrustedProxies)
+		if err != nil {
+			logger.Fatal("[ FAIL ] failed to set trusted proxies", zap.Error(err))
+		}
+
 		// 引入模块化路由
 		router.InitRoutes(r)
-
-		// 读取运行配置
-		host := viper.GetString("server.host")
-		port := viper.GetString("server.port")
+		shutdownTimeout := serverConfig.ShutdownTimeout
 
 		// 启动服务
-		err := r.Run(host + ":" + port)
-		if err != nil {
-			logger.Error("gin run failed", zap.Error(err))
-			return
+		srv := &http.Server{
+			Addr:    serverConfig.Host + ":" + serverConfig.Port,
+			Handler: r,
+		}
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- srv.ListenAndServe()
+		}()
+
+		// Prometheus 指标在独立的内部地址上提供，不经过对外的业务端口
+		var metricsSrv *http.Server
+		if metricsConfig := cmn.GetConfig().Metrics; metricsConfig.Enable {
+			metricsSrv = metrics.NewServer(metricsConfig.Listen)
+			go func() {
+				err := metricsSrv.ListenAndServe()
+				if !errors.Is(err, http.ErrServerClosed) {
+					logger.Error("metrics server run failed", zap.Error(err), zap.String("listen", metricsConfig.Listen))
+				}
+			}()
+		}
+
+		select {
+		case err = <-serveErr:
+			if !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("http server run failed", zap.Error(err))
+			}
+			stop()
+		case <-ctx.Done():
+			cmn.MiniLogger.Info("[ -- ] shutdown signal received", zap.Duration("timeout", shutdownTimeout))
 		}
+
+		shutdown(srv, metricsSrv, shutdownTimeout)
 	},
 }
 
+// shutdown 按顺序关闭服务：停止接收新请求并等待处理中的请求完成、停止定时任务、等待后台协程退出、关闭数据库连接池
+// 所有步骤共享同一个超时时间，超时的步骤记录日志后继续执行后续步骤
+func shutdown(srv, metricsSrv *http.Server, timeout time.Duration) {
+	logger := cmn.GetLogger()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		logger.Error("http server shutdown failed", zap.Error(err))
+	} else {
+		cmn.MiniLogger.Info("[ OK ] http server stopped")
+	}
+
+	if metricsSrv != nil {
+		err = metricsSrv.Shutdown(ctx)
+		if err != nil {
+			logger.Error("metrics server shutdown failed", zap.Error(err))
+		}
+	}
+
+	err = scheduler.Stop(ctx)
+	if err != nil {
+		logger.Error("scheduler stop failed", zap.Error(err))
+	} else {
+		cmn.MiniLogger.Info("[ OK ] scheduler stopped")
+	}
+
+	err = cmn.WaitBackground(ctx)
+	if err != nil {
+		logger.Error("background goroutines did not exit in time", zap.Error(err))
+	} else {
+		cmn.MiniLogger.Info("[ OK ] background goroutines stopped")
+	}
+
+	err = cmn.CloseDB()
+	if err != nil {
+		logger.Error("failed to close db pool", zap.Error(err))
+	}
+
+	err = tracing.Shutdown(ctx)
+	if err != nil {
+		logger.Error("failed to flush traces", zap.Error(err))
+	}
+
+	cmn.MiniLogger.Info("[ BYE ] server exited")
+	_ = logger.Sync()
+}
+
 func init() {
 	rootCmd.AddCommand(serveCmd)
 }
@@ -0,0 +1,293 @@
+package captcha
+
+import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/metrics"
+	"WudangMeta/cmn/settings"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 图形验证
+//
+// 客户端获取算术题图片，提交答案通过后获得一次性票据，在需要人机验证的接口（如发送短信验证码）中携带票据。
+// 答案与票据仅保存在服务端数据库中，多实例部署时共享；图片由内置点阵字体绘制，不依赖外部服务。
+// 每个挑战只能提交一次答案，答错需重新获取，票据使用一次后失效。
+// 票据与提交答案的客户端IP绑定，只能由该IP使用，避免解题后将票据转交给其他客户端。
+// 获取挑战按IP限流，限流依据 t_captcha 中同一IP的记录数，过期记录在限流窗口之后才会被清理。
+
+// 挑战状态
+const (
+	StatusPending  = "00" // 待验证
+	StatusPassed   = "01" // 已通过，票据可用
+	StatusConsumed = "02" // 票据已使用
+	StatusFailed   = "03" // 验证失败
+)
+
+// 验证结果，用于指标统计
+const (
+	resultPassed   = "passed"
+	resultWrong    = "wrong"
+	resultNotFound = "not_found"
+)
+
+const (
+	idBytes     = 16 // 挑战ID随机字节数
+	ticketBytes = 24 // 票据随机字节数
+
+	limitRetention = time.Hour // 限流的最长窗口，清理过期挑战时保留该时长内创建的记录
+)
+
+var (
+	ErrChallengeNotFound = errors.New("captcha challenge not found or expired")
+	ErrWrongAnswer       = errors.New("captcha answer is wrong")
+	ErrTicketInvalid     = errors.New("captcha ticket is invalid, used or expired")
+)
+
+// RateLimitError 同一IP获取挑战过于频繁
+type RateLimitError struct {
+	RetryAfter time.Duration // 距可再次获取的时长
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("too many captcha requests, retry after %v", e.RetryAfter)
+}
+
+// Challenge 返回给客户端的挑战
+type Challenge struct {
+	Id        string `json:"id"`        // 挑战ID，提交答案时携带
+	Image     string `json:"image"`     // 题目图片 data URL
+	ExpiresAt int64  `json:"expiresAt"` // 过期时间
+}
+
+// Ticket 验证通过后签发的一次性票据
+type Ticket struct {
+	Ticket    string `json:"ticket"`    // 票据
+	ExpiresAt int64  `json:"expiresAt"` // 过期时间
+}
+
+// newQuestion 生成算术题，返回题目文本与答案
+// 加法的两个数均不超过20，减法结果不为负
+func newQuestion() (string, string) {
+	if cmn.RandIntn(2) == 0 {
+		a, b := cmn.RandIntn(20)+1, cmn.RandIntn(20)+1
+		return fmt.Sprintf("%d+%d=?", a, b), strconv.Itoa(a + b)
+	}
+	a := cmn.RandIntn(21) + 10
+	b := cmn.RandIntn(a) + 1
+	return fmt.Sprintf("%d-%d=?", a, b), strconv.Itoa(a - b)
+}
+
+// Generate 生成新的挑战并保存答案，同一IP获取过于频繁时返回 *RateLimitError
+func Generate(ctx context.Context, db *gorm.DB, ip string) (*Challenge, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	question, answer := newQuestion()
+	record := cmn.TCaptcha{
+		Id:        cmn.RandToken(idBytes),
+		Answer:    answer,
+		Status:    StatusPending,
+		Ip:        ip,
+		ExpiresAt: time.Now().Add(settings.Duration(settingTtl)).UnixMilli(),
+	}
+
+	var limited *RateLimitError
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 串行化同一IP的请求，避免并发请求同时通过检查
+		err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "captcha:ip:"+ip).Error
+		if err != nil {
+			return err
+		}
+		limited, err = checkIpLimit(tx, ip, time.Now())
+		if err != nil || limited != nil {
+			return err
+		}
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		e := fmt.Errorf("failed to save captcha challenge: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error(), zap.String("ip", ip))
+		return nil, e
+	}
+	if limited != nil {
+		cmn.LoggerFrom(ctx).Warn("captcha request rate limited", zap.String("ip", ip), zap.Duration("retryAfter", limited.RetryAfter))
+		return nil, limited
+	}
+
+	image, err := renderImage(question)
+	if err != nil {
+		e := fmt.Errorf("failed to render captcha image: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return nil, e
+	}
+
+	return &Challenge{Id: record.Id, Image: image, ExpiresAt: record.ExpiresAt}, nil
+}
+
+// checkIpLimit 检查IP在各窗口内获取挑战的次数，超出限制时返回 *RateLimitError
+func checkIpLimit(tx *gorm.DB, ip string, now time.Time) (*RateLimitError, error) {
+	rules := []struct {
+		window time.Duration
+		limit  int64
+	}{
+		{window: time.Minute, limit: settings.Int(settingIpPerMinute)},
+		{window: limitRetention, limit: settings.Int(settingIpPerHour)},
+	}
+	for _, rule := range rules {
+		if rule.limit <= 0 {
+			continue
+		}
+		var createdAt []int64
+		err := tx.Model(&cmn.TCaptcha{}).
+			Where("ip = ? AND created_at > ?", ip, now.Add(-rule.window).UnixMilli()).
+			Order("created_at DESC").
+			Limit(int(rule.limit)).
+			Pluck("created_at", &createdAt).Error
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(createdAt)) < rule.limit {
+			continue
+		}
+		// 窗口内最早的一条记录移出窗口后才能再次获取
+		retryAfter := time.UnixMilli(createdAt[len(createdAt)-1]).Add(rule.window).Sub(now)
+		if retryAfter < time.Second {
+			retryAfter = time.Second
+		}
+		return &RateLimitError{RetryAfter: retryAfter}, nil
+	}
+	return nil, nil
+}
+
+// Verify 校验挑战答案，通过后签发与 ip 绑定的一次性票据；无论对错挑战都随之失效
+func Verify(ctx context.Context, db *gorm.DB, id, answer, ip string) (*Ticket, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	var (
+		ticket *Ticket
+		result error
+	)
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var record cmn.TCaptcha
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ? AND expires_at > ?", id, StatusPending, time.Now().UnixMilli()).
+			First(&record).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result = ErrChallengeNotFound
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if subtle.ConstantTimeCompare([]byte(record.Answer), []byte(strings.TrimSpace(answer))) != 1 {
+			result = ErrWrongAnswer
+			return tx.Model(&record).Update("status", StatusFailed).Error
+		}
+
+		ticket = &Ticket{
+			Ticket:    cmn.RandToken(ticketBytes),
+			ExpiresAt: time.Now().Add(settings.Duration(settingTicketTtl)).UnixMilli(),
+		}
+		return tx.Model(&record).Updates(map[string]any{
+			"status":            StatusPassed,
+			"ticket":            ticket.Ticket,
+			"ticket_expires_at": ticket.ExpiresAt,
+			"ticket_ip":         ip,
+		}).Error
+	})
+	if err != nil {
+		e := fmt.Errorf("failed to verify captcha: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error(), zap.String("id", id), zap.String("ip", ip))
+		return nil, e
+	}
+
+	switch {
+	case errors.Is(result, ErrChallengeNotFound):
+		metrics.ObserveCaptchaVerify(resultNotFound)
+	case errors.Is(result, ErrWrongAnswer):
+		metrics.ObserveCaptchaVerify(resultWrong)
+	default:
+		metrics.ObserveCaptchaVerify(resultPassed)
+	}
+	if result != nil {
+		cmn.LoggerFrom(ctx).Warn("captcha verification failed", zap.String("id", id), zap.String("ip", ip), zap.Error(result))
+		return nil, result
+	}
+	return ticket, nil
+}
+
+// ConsumeTicket 使用票据，票据不存在、已使用、已过期或不是由 ip 验证通过时返回 ErrTicketInvalid
+func ConsumeTicket(ctx context.Context, db *gorm.DB, ticket, ip string) error {
+	if db == nil {
+		db = cmn.GormDB
+	}
+	if ticket == "" {
+		return ErrTicketInvalid
+	}
+
+	result := db.WithContext(ctx).Model(&cmn.TCaptcha{}).
+		Where("ticket = ? AND ticket_ip = ? AND status = ? AND ticket_expires_at > ?", ticket, ip, StatusPassed, time.Now().UnixMilli()).
+		Update("status", StatusConsumed)
+	if result.Error != nil {
+		e := fmt.Errorf("failed to consume captcha ticket: %w", result.Error)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
+	}
+	if result.RowsAffected == 0 {
+		cmn.LoggerFrom(ctx).Warn("captcha ticket rejected", zap.String("ip", ip))
+		return ErrTicketInvalid
+	}
+	return nil
+}
+
+// PurgeExpired 分批删除挑战与票据均已过期的记录，返回删除的记录数
+func PurgeExpired(ctx context.Context, db *gorm.DB) (int64, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	var total int64
+	now := time.Now()
+	for ctx.Err() == nil {
+		// 限流窗口内创建的记录仍用于限流统计，到期后再清理
+		result := db.WithContext(ctx).Exec(
+			`DELETE FROM "`+cmn.TCaptchaName+`" WHERE id IN (SELECT id FROM "`+cmn.TCaptchaName+`" WHERE expires_at <= ? AND COALESCE(ticket_expires_at, 0) <= ? AND created_at <= ? LIMIT ?)`,
+			now.UnixMilli(), now.UnixMilli(), now.Add(-limitRetention).UnixMilli(), purgeBatchSize)
+		if result.Error != nil {
+			e := fmt.Errorf("failed to purge expired captcha: %w", result.Error)
+			z.Error(e.Error(), zap.Int64("deleted", total))
+			return total, e
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < purgeBatchSize {
+			break
+		}
+	}
+	return total, ctx.Err()
+}
+
+// purger 清理过期挑战的定时任务
+func purger(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		deleted, err := PurgeExpired(ctx, db)
+		if err != nil {
+			return err
+		}
+		z.Info("expired captcha purged", zap.Int64("deleted", deleted))
+		return nil
+	}
+}
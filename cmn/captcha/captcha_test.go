@@ -0,0 +1,109 @@
+package captcha
+
+import (
+	"WudangMeta/cmn/settings"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestNewQuestion(t *testing.T) {
+	pattern := regexp.MustCompile(`^(\d+)([+-])(\d+)=\?$`)
+	for i := 0; i < 1000; i++ {
+		question, answer := newQuestion()
+		m := pattern.FindStringSubmatch(question)
+		if m == nil {
+			t.Fatalf("newQuestion() = %q, unexpected format", question)
+		}
+		a, _ := strconv.Atoi(m[1])
+		b, _ := strconv.Atoi(m[3])
+		want := a + b
+		if m[2] == "-" {
+			want = a - b
+		}
+		if want < 0 || answer != strconv.Itoa(want) {
+			t.Fatalf("newQuestion() = %q, answer %q", question, answer)
+		}
+	}
+}
+
+func TestRenderImage(t *testing.T) {
+	// 最长的题目也需要完整绘制在图片内
+	for _, question := range []string{"1+1=?", "30-29=?", "20+20=?"} {
+		dataUrl, err := renderImage(question)
+		if err != nil {
+			t.Fatalf("renderImage(%q) error = %v", question, err)
+		}
+
+		const prefix = "data:image/png;base64,"
+		if !strings.HasPrefix(dataUrl, prefix) {
+			t.Fatalf("renderImage(%q) = %q, want png data url", question, dataUrl[:min(len(dataUrl), 40)])
+		}
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(dataUrl, prefix))
+		if err != nil {
+			t.Fatalf("renderImage(%q) invalid base64: %v", question, err)
+		}
+		img, err := png.Decode(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("renderImage(%q) invalid png: %v", question, err)
+		}
+		if b := img.Bounds(); b.Dx() != imageWidth || b.Dy() != imageHeight {
+			t.Errorf("renderImage(%q) size = %dx%d, want %dx%d", question, b.Dx(), b.Dy(), imageWidth, imageHeight)
+		}
+	}
+
+	if _, err := renderImage("1*2=?"); err == nil {
+		t.Errorf("renderImage with unsupported character succeeded, want error")
+	}
+}
+
+func TestGlyphs(t *testing.T) {
+	for r, glyph := range glyphs {
+		for _, line := range glyph {
+			if len(line) != glyphWidth || strings.Trim(line, ".#") != "" {
+				t.Errorf("glyph %q has malformed row %q", r, line)
+			}
+		}
+	}
+}
+
+func TestRegisterSettings(t *testing.T) {
+	z = zap.NewNop()
+	err := registerSettings(context.Background())
+	if err != nil {
+		t.Fatalf("registerSettings() error = %v", err)
+	}
+	if got := settings.Duration(settingTtl); got != 2*time.Minute {
+		t.Errorf("captcha ttl = %v, want 2m", got)
+	}
+	if got := settings.Duration(settingTicketTtl); got != 5*time.Minute {
+		t.Errorf("captcha ticket ttl = %v, want 5m", got)
+	}
+	if got := settings.Int(settingIpPerMinute); got != 10 {
+		t.Errorf("captcha ip per minute = %d, want 10", got)
+	}
+	if got := settings.Int(settingIpPerHour); got != 100 {
+		t.Errorf("captcha ip per hour = %d, want 100", got)
+	}
+}
+
+func TestDrawLine(t *testing.T) {
+	black := color.RGBA{A: 255}
+	for _, l := range [][4]int{{0, 0, 10, 4}, {10, 4, 0, 0}, {3, 0, 3, 9}, {0, 9, 9, 0}, {5, 5, 5, 5}} {
+		img := image.NewRGBA(image.Rect(0, 0, 12, 12))
+		drawLine(img, l[0], l[1], l[2], l[3], black)
+		if img.RGBAAt(l[0], l[1]) != black || img.RGBAAt(l[2], l[3]) != black {
+			t.Errorf("drawLine(%v) did not draw both endpoints", l)
+		}
+	}
+}
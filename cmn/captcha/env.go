@@ -0,0 +1,73 @@
+package captcha
+
+import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/scheduler"
+	"WudangMeta/cmn/settings"
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 图形验证配置键
+const (
+	settingTtl         = "captcha.ttl"         // 挑战有效期
+	settingTicketTtl   = "captcha.ticketTtl"   // 票据有效期
+	settingIpPerMinute = "captcha.ipPerMinute" // 单个IP每分钟获取挑战次数
+	settingIpPerHour   = "captcha.ipPerHour"   // 单个IP每小时获取挑战次数
+)
+
+const (
+	JobPurgeCaptcha = "captcha_purge" // 清理过期挑战的定时任务
+
+	purgeBatchSize = 5000 // 清理过期挑战时每批删除的记录数
+)
+
+var z *zap.Logger
+
+func Init(ctx context.Context) {
+	z = cmn.GetLogger()
+
+	err := registerSettings(ctx)
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register captcha settings", zap.Error(err))
+	}
+
+	err = scheduler.Register(scheduler.Job{
+		Name:      JobPurgeCaptcha,
+		Spec:      "15 * * * *",
+		Enable:    true,
+		Singleton: true,
+		Fn:        purger(cmn.GormDB),
+	})
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register captcha purge job", zap.Error(err))
+	}
+
+	cmn.MiniLogger.Info("[ OK ] captcha module initialized")
+}
+
+func registerSettings(ctx context.Context) error {
+	atLeast := func(minTtl time.Duration) func(ctx context.Context, value any) error {
+		return func(ctx context.Context, value any) error {
+			if value.(time.Duration) < minTtl {
+				return fmt.Errorf("ttl %v < %v", value, minTtl)
+			}
+			return nil
+		}
+	}
+	nonNegative := func(ctx context.Context, value any) error {
+		if value.(int64) < 0 {
+			return fmt.Errorf("limit %d < 0", value.(int64))
+		}
+		return nil
+	}
+	return settings.Register(ctx,
+		settings.Definition{Key: settingTtl, Kind: settings.KindDuration, Default: "2m", Description: "图形验证挑战有效期", Validate: atLeast(30 * time.Second)},
+		settings.Definition{Key: settingTicketTtl, Kind: settings.KindDuration, Default: "5m", Description: "图形验证通过后票据有效期", Validate: atLeast(30 * time.Second)},
+		settings.Definition{Key: settingIpPerMinute, Kind: settings.KindInt, Default: "10", Description: "单个IP每分钟可获取的图形验证挑战数，0表示不限制", Validate: nonNegative},
+		settings.Definition{Key: settingIpPerHour, Kind: settings.KindInt, Default: "100", Description: "单个IP每小时可获取的图形验证挑战数，0表示不限制", Validate: nonNegative},
+	)
+}
@@ -0,0 +1,145 @@
+package captcha
+
+import (
+	"WudangMeta/cmn"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// 挑战图片参数
+const (
+	imageWidth  = 200
+	imageHeight = 60
+
+	glyphWidth  = 5 // 点阵字宽
+	glyphHeight = 7 // 点阵字高
+	glyphScale  = 4 // 每个点阵像素放大倍数
+	glyphGap    = 6 // 字符间距
+
+	noiseLines = 4   // 干扰线数量
+	noiseDots  = 300 // 干扰点数量
+)
+
+// glyphs 内置5x7点阵字体，仅包含算术题需要的字符，避免依赖外部字体文件
+var glyphs = map[rune][glyphHeight]string{
+	'0': {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9': {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	'+': {".....", "..#..", "..#..", "#####", "..#..", "..#..", "....."},
+	'-': {".....", ".....", ".....", "#####", ".....", ".....", "....."},
+	'=': {".....", ".....", "#####", ".....", "#####", ".....", "....."},
+	'?': {".###.", "#...#", "....#", "...#.", "..#..", ".....", "..#.."},
+}
+
+// renderImage 将题目绘制为带干扰的PNG图片，返回 data URL
+// 每个字符使用随机颜色与垂直偏移，并叠加干扰线与干扰点
+func renderImage(text string) (string, error) {
+	img := image.NewRGBA(image.Rect(0, 0, imageWidth, imageHeight))
+	bg := color.RGBA{R: uint8(230 + cmn.RandIntn(26)), G: uint8(230 + cmn.RandIntn(26)), B: uint8(230 + cmn.RandIntn(26)), A: 255}
+	for y := 0; y < imageHeight; y++ {
+		for x := 0; x < imageWidth; x++ {
+			img.SetRGBA(x, y, bg)
+		}
+	}
+
+	runes := []rune(text)
+	textWidth := len(runes)*(glyphWidth*glyphScale+glyphGap) - glyphGap
+	if textWidth > imageWidth {
+		return "", fmt.Errorf("captcha text %q too long", text)
+	}
+
+	x := (imageWidth-textWidth)/2 + cmn.RandIntn(7) - 3
+	maxOffset := imageHeight - glyphHeight*glyphScale
+	for _, r := range runes {
+		glyph, ok := glyphs[r]
+		if !ok {
+			return "", fmt.Errorf("unsupported captcha character %q", r)
+		}
+		drawGlyph(img, glyph, x, cmn.RandIntn(maxOffset+1), randColor())
+		x += glyphWidth*glyphScale + glyphGap
+	}
+
+	for i := 0; i < noiseLines; i++ {
+		drawLine(img,
+			cmn.RandIntn(imageWidth), cmn.RandIntn(imageHeight),
+			cmn.RandIntn(imageWidth), cmn.RandIntn(imageHeight),
+			randColor())
+	}
+	for i := 0; i < noiseDots; i++ {
+		img.SetRGBA(cmn.RandIntn(imageWidth), cmn.RandIntn(imageHeight), randColor())
+	}
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// drawGlyph 以 (x, y) 为左上角绘制放大后的点阵字符
+func drawGlyph(img *image.RGBA, glyph [glyphHeight]string, x, y int, c color.RGBA) {
+	for row, line := range glyph {
+		for col, cell := range line {
+			if cell != '#' {
+				continue
+			}
+			for dy := 0; dy < glyphScale; dy++ {
+				for dx := 0; dx < glyphScale; dx++ {
+					img.SetRGBA(x+col*glyphScale+dx, y+row*glyphScale+dy, c)
+				}
+			}
+		}
+	}
+}
+
+// drawLine 使用 Bresenham 算法绘制直线
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		img.SetRGBA(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+// randColor 返回与浅色背景对比明显的随机深色
+func randColor() color.RGBA {
+	return color.RGBA{R: uint8(cmn.RandIntn(140)), G: uint8(cmn.RandIntn(140)), B: uint8(cmn.RandIntn(140)), A: 255}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
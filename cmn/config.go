@@ -1,33 +1,606 @@
 package cmn
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 配置加载顺序（后者覆盖前者）：
+//   - 配置文件：--config 指定的文件，未指定时在当前目录及上级目录查找 .config.json
+//   - 环境变量：WUDANG_ 前缀，节点以下划线连接，驼峰转为下划线分隔，如 dbms.pwd 对应 WUDANG_DBMS_PWD，
+//     sms.data.apiUrl 对应 WUDANG_SMS_DATA_API_URL
+//   - 文件环境变量：在环境变量名后加 _FILE，值为文件路径，读取文件内容作为配置值，用于 Docker secrets
+//
+// scheduler.jobs、task.llmPrompt 等映射类型的节点只能通过配置文件设置。
+
+const envPrefix = "WUDANG"
+
+var (
+	configFile string                 // 命令行指定的配置文件路径
+	config     atomic.Pointer[Config] // 当前生效的配置
+
+	logSubscribeOnce sync.Once
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Config 应用配置，对应配置文件的根节点
+type Config struct {
+	Env       string          `mapstructure:"env"` // 运行环境 dev/test/prod，默认 prod
+	Server    ServerConfig    `mapstructure:"server"`
+	Admin     AdminConfig     `mapstructure:"admin"`
+	Session   SessionConfig   `mapstructure:"session"`
+	Dbms      DbmsConfig      `mapstructure:"dbms"`
+	Log       LogConfig       `mapstructure:"log"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Tracing   TracingConfig   `mapstructure:"tracing"`
+	Scheduler SchedulerConfig `mapstructure:"scheduler"`
+	Sms       SmsConfig       `mapstructure:"sms"`
+	Ubanquan  UbanquanConfig  `mapstructure:"ubanquan"`
+	Llm       LlmConfig       `mapstructure:"llm"`
+	Task      TaskConfig      `mapstructure:"task"`
+}
+
+// 运行环境
+const (
+	EnvDev  = "dev"  // 开发环境
+	EnvTest = "test" // 测试环境
+	EnvProd = "prod" // 生产环境
 )
 
+// ServerConfig HTTP 服务配置
+type ServerConfig struct {
+	Host            string        `mapstructure:"host"`            // 监听地址，为空时监听所有地址
+	Port            string        `mapstructure:"port"`            // 监听端口
+	ShutdownTimeout time.Duration `mapstructure:"shutdownTimeout"` // 优雅关闭超时时间
+	// 可信代理的IP或CIDR，仅信任来自这些地址的 X-Forwarded-For 等头部，为空时不信任任何代理，直接使用连接地址作为客户端IP
+	TrustedProxies []string `mapstructure:"trustedProxies"`
+}
+
+// AdminConfig 管理端配置
+type AdminConfig struct {
+	SuperAdmin SuperAdminConfig `mapstructure:"superAdmin"` // 管理员表为空时创建的初始超级管理员
+}
+
+// SuperAdminConfig 初始超级管理员
+type SuperAdminConfig struct {
+	UserName string `mapstructure:"userName"`
+	Password string `mapstructure:"password"` // 不写入配置文件，通过 WUDANG_ADMIN_SUPER_ADMIN_PASSWORD 或 _FILE 提供
+}
+
+// SessionConfig cookie session 密钥
+type SessionConfig struct {
+	AuthKey       string `mapstructure:"authKey"`       // 签名密钥
+	EncryptionKey string `mapstructure:"encryptionKey"` // 加密密钥，长度须为 16/24/32 字节
+}
+
+// DbmsConfig 数据库连接配置
+type DbmsConfig struct {
+	Host string `mapstructure:"host"`
+	Port string `mapstructure:"port"`
+	User string `mapstructure:"user"`
+	Pwd  string `mapstructure:"pwd"`
+	Db   string `mapstructure:"db"`
+}
+
+// MetricsConfig 监控指标配置
+type MetricsConfig struct {
+	Enable bool   `mapstructure:"enable"` // 是否暴露 /metrics
+	Listen string `mapstructure:"listen"` // /metrics 的监听地址，与业务端口分开，应只在内网可达，如 127.0.0.1:9090
+}
+
+// TracingConfig 链路追踪配置
+type TracingConfig struct {
+	Enable       bool    `mapstructure:"enable"`       // 是否启用
+	ServiceName  string  `mapstructure:"serviceName"`  // 服务名称
+	Exporter     string  `mapstructure:"exporter"`     // 导出方式：otlp/file
+	OtlpEndpoint string  `mapstructure:"otlpEndpoint"` // OTLP/HTTP 采集器地址，如 localhost:4318
+	OtlpInsecure bool    `mapstructure:"otlpInsecure"` // 是否使用 HTTP 明文连接采集器
+	FilePath     string  `mapstructure:"filePath"`     // 文件导出路径
+	SampleRatio  float64 `mapstructure:"sampleRatio"`  // 采样比例，0~1
+}
+
+// SchedulerConfig 定时任务配置
+type SchedulerConfig struct {
+	Location string                        `mapstructure:"location"` // 调度时区
+	LeaseTTL time.Duration                 `mapstructure:"leaseTTL"` // 单例任务租约时长
+	Jobs     map[string]SchedulerJobConfig `mapstructure:"jobs"`     // 按任务名覆盖调度表达式与启用状态
+}
+
+// SchedulerJobConfig 单个任务的配置，未设置的项使用任务注册时的默认值
+type SchedulerJobConfig struct {
+	Spec   string `mapstructure:"spec"`
+	Enable *bool  `mapstructure:"enable"`
+}
+
+// SmsConfig 短信服务配置
+// 配置 providers 时按优先级依次尝试各服务商，否则只使用 platform 与 data 指定的单一服务商
+type SmsConfig struct {
+	Enable    bool                `mapstructure:"enable"`
+	Platform  string              `mapstructure:"platform"`  // 短信平台：juhe/tecent/shx/mock
+	Data      SmsDataConfig       `mapstructure:"data"`      // 平台参数，不同平台使用不同的字段
+	Providers []SmsProviderConfig `mapstructure:"providers"` // 多服务商配置，设置后忽略 platform 与 data
+	Timeout   time.Duration       `mapstructure:"timeout"`   // 单个服务商的发送超时，超时后尝试下一个服务商
+	Breaker   SmsBreakerConfig    `mapstructure:"breaker"`   // 服务商熔断配置
+}
+
+// SmsProviderConfig 单个短信服务商配置
+type SmsProviderConfig struct {
+	Name     string        `mapstructure:"name"`     // 服务商名称，唯一，用于指标、每日额度与健康状态，为空时使用平台名
+	Platform string        `mapstructure:"platform"` // 短信平台：juhe/tecent/shx/mock
+	Priority int           `mapstructure:"priority"` // 优先级，数值越小越先尝试
+	Data     SmsDataConfig `mapstructure:"data"`     // 平台参数
+}
+
+// SmsBreakerConfig 短信服务商熔断配置
+type SmsBreakerConfig struct {
+	FailureThreshold int           `mapstructure:"failureThreshold"` // 连续失败次数达到后熔断
+	OpenTimeout      time.Duration `mapstructure:"openTimeout"`      // 熔断持续时间，到期后放行一次试探请求
+}
+
+// ProviderConfigs 返回按优先级排序的服务商配置，未配置 providers 时由 platform 与 data 构造单一服务商
+func (c SmsConfig) ProviderConfigs() []SmsProviderConfig {
+	providers := c.Providers
+	if len(providers) == 0 {
+		providers = []SmsProviderConfig{{Platform: c.Platform, Data: c.Data}}
+	}
+
+	result := make([]SmsProviderConfig, len(providers))
+	copy(result, providers)
+	for i := range result {
+		if result[i].Name == "" {
+			result[i].Name = result[i].Platform
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Priority < result[j].Priority
+	})
+	return result
+}
+
+// SmsDataConfig 短信平台参数
+type SmsDataConfig struct {
+	ApiUrl     string `mapstructure:"apiUrl"`     // juhe/shx
+	Key        string `mapstructure:"key"`        // juhe
+	AppId      string `mapstructure:"appId"`      // tecent
+	AppKey     string `mapstructure:"appKey"`     // tecent
+	TemplateId string `mapstructure:"templateId"` // tecent/juhe
+	SignName   string `mapstructure:"signName"`   // tecent
+	SecretId   string `mapstructure:"secretId"`   // tecent
+	SecretKey  string `mapstructure:"secretKey"`  // tecent
+	UserName   string `mapstructure:"userName"`   // shx
+	Password   string `mapstructure:"password"`   // shx
+	Template   string `mapstructure:"template"`   // shx
+	MockFile   string `mapstructure:"mockFile"`   // mock：验证码逐行追加写入的本地文件
+	MockUrl    string `mapstructure:"mockUrl"`    // mock：以 JSON 接收验证码的地址
+}
+
+// UbanquanConfig 优版权开放平台配置
+type UbanquanConfig struct {
+	BaseApiUrl string `mapstructure:"baseApiUrl"`
+	AppId      string `mapstructure:"appId"`
+	AppSecret  string `mapstructure:"appSecret"`
+}
+
+// LlmConfig 大模型服务配置
+type LlmConfig struct {
+	Enable   bool          `mapstructure:"enable"`
+	Platform string        `mapstructure:"platform"` // 大模型平台：deepseek
+	Data     LlmDataConfig `mapstructure:"data"`
+}
+
+// LlmDataConfig 大模型平台参数
+type LlmDataConfig struct {
+	Model   string `mapstructure:"model"`
+	BaseUrl string `mapstructure:"baseUrl"`
+	ApiKey  string `mapstructure:"apiKey"`
+}
+
+// TaskConfig 任务模块配置
+type TaskConfig struct {
+	Enable    bool             `mapstructure:"enable"`
+	Reward    TaskRewardConfig `mapstructure:"reward"`
+	LlmPrompt map[string]any   `mapstructure:"llmPrompt"` // 运势分析提示词，由任务模块解析
+}
+
+// TaskRewardConfig 任务奖励积分
+type TaskRewardConfig struct {
+	DailyCheckInPoints        float64 `mapstructure:"dailyCheckInPoints"`
+	DailyCheckInPointsType    string  `mapstructure:"dailyCheckInPointsType"`
+	FortuneAnalysisPoints     float64 `mapstructure:"fortuneAnalysisPoints"`
+	FortuneAnalysisPointsType string  `mapstructure:"fortuneAnalysisPointsType"`
+}
+
+// ConfigErrors 配置检查发现的全部问题
+type ConfigErrors []error
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e ConfigErrors) Unwrap() []error {
+	return e
+}
+
+// SetConfigFile 指定配置文件路径，需在 InitConfig 之前调用，为空时按默认路径查找
+func SetConfigFile(path string) {
+	configFile = path
+}
+
+// ConfigFileUsed 返回实际读取的配置文件路径
+func ConfigFileUsed() string {
+	return viper.ConfigFileUsed()
+}
+
+// GetConfig 返回当前生效的配置，InitConfig 之前返回空配置
+func GetConfig() *Config {
+	cfg := config.Load()
+	if cfg == nil {
+		return &Config{}
+	}
+	return cfg
+}
+
 func InitConfig() {
-	err := initViper()
+	cfg, err := LoadConfig()
 	if err != nil {
-		logger.Fatal("[ FAIL ] failed to init viper", zap.Error(err))
+		logger.Fatal("[ FAIL ] failed to load config", zap.Error(err))
+	}
+
+	problems := cfg.Validate()
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			logger.Error("invalid config", zap.Error(problem))
+		}
+		logger.Fatal("[ FAIL ] config is invalid, run `config validate` for details", zap.Int("problems", len(problems)))
 	}
+	config.Store(cfg)
+
+	err = applyLogConfig(cfg.Log)
+	if err != nil {
+		logger.Fatal("[ FAIL ] failed to apply log config", zap.Error(err))
+	}
+	logSubscribeOnce.Do(func() {
+		SubscribeConfig("log", func(ctx context.Context, old, new *Config) error {
+			if reflect.DeepEqual(old.Log, new.Log) {
+				return nil
+			}
+			return applyLogConfig(new.Log)
+		})
+	})
 
 	MiniLogger.Info("[ OK ] config module initialed", zap.String("path", viper.ConfigFileUsed()))
 }
 
+// LoadConfig 读取配置文件与环境变量并解析为 Config，不检查配置项是否完整
+// 环境变量读取失败、类型不匹配等问题会全部收集后以 ConfigErrors 返回，此时仍返回尽力解析的配置，便于继续检查其他问题
+// 配置文件无法读取时返回 nil
+func LoadConfig() (*Config, error) {
+	err := initViper()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems ConfigErrors
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		err = bindEnv(key)
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	cfg := defaultConfig()
+	err = viper.Unmarshal(&cfg)
+	if err != nil {
+		problems = append(problems, decodeProblems(err)...)
+	}
+
+	if len(problems) > 0 {
+		return &cfg, problems
+	}
+	return &cfg, nil
+}
+
+// decodeProblems 将解析错误拆分为逐项的问题，mapstructure 会把所有字段的错误合并为一个
+func decodeProblems(err error) ConfigErrors {
+	joined, ok := errors.Unwrap(err).(interface{ Unwrap() []error })
+	if !ok {
+		return ConfigErrors{fmt.Errorf("%w: %v", ErrInvalidConfig, err)}
+	}
+
+	var problems ConfigErrors
+	for _, e := range joined.Unwrap() {
+		problems = append(problems, fmt.Errorf("%w: %v", ErrInvalidConfig, e))
+	}
+	return problems
+}
+
 func initViper() error {
 	// 读取配置文件
-	viper.SetConfigName(".config")
-	viper.AddConfigPath(".")
-	viper.AddConfigPath("..")
-	viper.AddConfigPath("../..")
-	viper.AddConfigPath("../../..")
+	if configFile != "" {
+		viper.SetConfigFile(configFile)
+	} else {
+		viper.SetConfigName(".config")
+		viper.AddConfigPath(".")
+		viper.AddConfigPath("..")
+		viper.AddConfigPath("../..")
+		viper.AddConfigPath("../../..")
+	}
 	viper.SetConfigType("json")
 
 	err := viper.ReadInConfig()
 	if err != nil {
-		logger.Error("init config failed", zap.Error(err))
-		return err
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	return nil
+}
+
+// defaultConfig 配置文件与环境变量均未设置时使用的默认值
+func defaultConfig() Config {
+	return Config{
+		Env:    EnvProd,
+		Server: ServerConfig{ShutdownTimeout: 15 * time.Second},
+		Log:    defaultLogConfig(),
+		Sms: SmsConfig{
+			Timeout: 10 * time.Second,
+			Breaker: SmsBreakerConfig{FailureThreshold: 5, OpenTimeout: 30 * time.Second},
+		},
+	}
+}
+
+// configKeys 根据 mapstructure 标签列出结构体的所有叶子配置项，如 dbms.pwd
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, configKeys(field.Type, key+".")...)
+			continue
+		}
+		// 映射与结构体切片无法通过单个环境变量覆盖
+		if field.Type.Kind() == reflect.Map || (field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// envName 配置项对应的环境变量名，如 sms.data.apiUrl 对应 WUDANG_SMS_DATA_API_URL
+func envName(key string) string {
+	var b strings.Builder
+	b.WriteString(envPrefix)
+	for _, part := range strings.Split(key, ".") {
+		b.WriteByte('_')
+		for i, r := range part {
+			if i > 0 && unicode.IsUpper(r) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// bindEnv 绑定配置项的环境变量，<NAME>_FILE 存在时读取文件内容作为配置值
+func bindEnv(key string) error {
+	name := envName(key)
+	err := viper.BindEnv(key, name)
+	if err != nil {
+		return fmt.Errorf("failed to bind %s: %w", name, err)
 	}
 
+	path, ok := os.LookupEnv(name + "_FILE")
+	if !ok {
+		return nil
+	}
+	if _, ok = os.LookupEnv(name); ok {
+		return fmt.Errorf("%w: both %s and %s_FILE are set", ErrInvalidConfig, name, name)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read %s_FILE: %v", ErrInvalidConfig, name, err)
+	}
+	viper.Set(key, strings.TrimRight(string(content), "\r\n"))
 	return nil
 }
+
+// Validate 检查配置项是否完整有效，返回发现的全部问题
+// 需要访问数据库的检查（如积分类型是否存在）由各模块初始化时完成
+func (cfg *Config) Validate() ConfigErrors {
+	var problems ConfigErrors
+	require := func(key, value string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, fmt.Errorf("%w: %s is required", ErrInvalidConfig, key))
+		}
+	}
+	invalid := func(key, format string, args ...any) {
+		problems = append(problems, fmt.Errorf("%w: %s %s", ErrInvalidConfig, key, fmt.Sprintf(format, args...)))
+	}
+
+	switch cfg.Env {
+	case EnvDev, EnvTest, EnvProd:
+	default:
+		invalid("env", "%q is not supported, use dev, test or prod", cfg.Env)
+	}
+
+	require("server.port", cfg.Server.Port)
+	if cfg.Server.ShutdownTimeout < 0 {
+		invalid("server.shutdownTimeout", "must not be negative")
+	}
+	for i, proxy := range cfg.Server.TrustedProxies {
+		if !validIPOrCIDR(proxy) {
+			invalid(fmt.Sprintf("server.trustedProxies[%d]", i), "%q is not an ip or cidr", proxy)
+		}
+	}
+
+	require("session.authKey", cfg.Session.AuthKey)
+	if n := len(cfg.Session.EncryptionKey); n == 0 {
+		require("session.encryptionKey", "")
+	} else if n != 16 && n != 24 && n != 32 {
+		invalid("session.encryptionKey", "must be 16, 24 or 32 bytes, got %d", n)
+	}
+
+	require("dbms.host", cfg.Dbms.Host)
+	require("dbms.port", cfg.Dbms.Port)
+	require("dbms.user", cfg.Dbms.User)
+	require("dbms.pwd", cfg.Dbms.Pwd)
+	require("dbms.db", cfg.Dbms.Db)
+
+	if _, err := zapcore.ParseLevel(cfg.Log.Level); err != nil {
+		invalid("log.level", "%q is not a valid level", cfg.Log.Level)
+	}
+	require("log.file", cfg.Log.File)
+	if cfg.Log.MaxSize < 0 || cfg.Log.MaxBackups < 0 || cfg.Log.MaxAge < 0 {
+		invalid("log", "maxSize, maxBackups and maxAge must not be negative")
+	}
+
+	if cfg.Metrics.Enable {
+		require("metrics.listen", cfg.Metrics.Listen)
+	}
+
+	if cfg.Tracing.Enable {
+		switch cfg.Tracing.Exporter {
+		case "otlp":
+			require("tracing.otlpEndpoint", cfg.Tracing.OtlpEndpoint)
+		case "file":
+			require("tracing.filePath", cfg.Tracing.FilePath)
+		default:
+			invalid("tracing.exporter", "%q is not supported, use otlp or file", cfg.Tracing.Exporter)
+		}
+		if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+			invalid("tracing.sampleRatio", "must be between 0 and 1")
+		}
+	}
+
+	if cfg.Scheduler.Location != "" {
+		if _, err := time.LoadLocation(cfg.Scheduler.Location); err != nil {
+			invalid("scheduler.location", "%q is not a valid time zone", cfg.Scheduler.Location)
+		}
+	}
+	if cfg.Scheduler.LeaseTTL < 0 {
+		invalid("scheduler.leaseTTL", "must not be negative")
+	}
+	for name, job := range cfg.Scheduler.Jobs {
+		if job.Spec == "" {
+			continue
+		}
+		if _, err := cron.ParseStandard(job.Spec); err != nil {
+			invalid("scheduler.jobs."+name+".spec", "%q is invalid: %v", job.Spec, err)
+		}
+	}
+
+	if cfg.Sms.Enable {
+		validateSmsProvider := func(prefix, platform string, data SmsDataConfig) {
+			switch platform {
+			case "juhe":
+				require(prefix+"data.apiUrl", data.ApiUrl)
+				require(prefix+"data.key", data.Key)
+				require(prefix+"data.templateId", data.TemplateId)
+			case "tecent":
+				require(prefix+"data.appId", data.AppId)
+				require(prefix+"data.appKey", data.AppKey)
+				require(prefix+"data.templateId", data.TemplateId)
+				require(prefix+"data.signName", data.SignName)
+				require(prefix+"data.secretId", data.SecretId)
+				require(prefix+"data.secretKey", data.SecretKey)
+			case "shx":
+				require(prefix+"data.apiUrl", data.ApiUrl)
+				require(prefix+"data.userName", data.UserName)
+				require(prefix+"data.password", data.Password)
+				require(prefix+"data.template", data.Template)
+			case "mock":
+				// 模拟服务商不发送短信，验证码写入文件或本地地址，仅允许在开发与测试环境使用
+				if cfg.Env != EnvDev && cfg.Env != EnvTest {
+					invalid(prefix+"platform", "mock is only allowed when env is dev or test")
+				}
+				if data.MockFile == "" && data.MockUrl == "" {
+					invalid(prefix+"data", "mockFile or mockUrl is required for mock platform")
+				}
+			default:
+				invalid(prefix+"platform", "%q is not supported, use juhe, tecent, shx or mock", platform)
+			}
+		}
+
+		if len(cfg.Sms.Providers) == 0 {
+			validateSmsProvider("sms.", cfg.Sms.Platform, cfg.Sms.Data)
+		}
+		names := map[string]bool{}
+		for i, p := range cfg.Sms.Providers {
+			prefix := fmt.Sprintf("sms.providers[%d].", i)
+			validateSmsProvider(prefix, p.Platform, p.Data)
+			name := p.Name
+			if name == "" {
+				name = p.Platform
+			}
+			if names[name] {
+				invalid(prefix+"name", "%q is duplicated, set a unique name for each provider", name)
+			}
+			names[name] = true
+		}
+		if cfg.Sms.Timeout < 0 {
+			invalid("sms.timeout", "must not be negative")
+		}
+		if cfg.Sms.Breaker.FailureThreshold < 0 || cfg.Sms.Breaker.OpenTimeout < 0 {
+			invalid("sms.breaker", "failureThreshold and openTimeout must not be negative")
+		}
+	}
+
+	require("ubanquan.baseApiUrl", cfg.Ubanquan.BaseApiUrl)
+	require("ubanquan.appId", cfg.Ubanquan.AppId)
+	require("ubanquan.appSecret", cfg.Ubanquan.AppSecret)
+
+	if cfg.Llm.Enable {
+		switch cfg.Llm.Platform {
+		case "deepseek":
+			require("llm.data.model", cfg.Llm.Data.Model)
+			require("llm.data.baseUrl", cfg.Llm.Data.BaseUrl)
+			require("llm.data.apiKey", cfg.Llm.Data.ApiKey)
+		default:
+			invalid("llm.platform", "%q is not supported, use deepseek", cfg.Llm.Platform)
+		}
+	}
+
+	if cfg.Task.Enable {
+		if cfg.Task.Reward.DailyCheckInPoints <= 0 {
+			invalid("task.reward.dailyCheckInPoints", "must be greater than 0")
+		}
+		if cfg.Task.Reward.FortuneAnalysisPoints <= 0 {
+			invalid("task.reward.fortuneAnalysisPoints", "must be greater than 0")
+		}
+		prompt, _ := cfg.Task.LlmPrompt["prompt"].(string)
+		require("task.llmPrompt.prompt", prompt)
+	}
+
+	return problems
+}
+
+// validIPOrCIDR 返回是否为合法的IP或CIDR
+func validIPOrCIDR(s string) bool {
+	if _, err := netip.ParsePrefix(s); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
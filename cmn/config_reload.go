@@ -0,0 +1,278 @@
+package cmn
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// 配置热更新
+//
+// 配置文件变化后重新加载配置并校验，校验通过后按注册顺序通知订阅者，全部成功后新配置生效。
+// 校验失败或任一订阅者返回错误时，已通知的订阅者按相反顺序收到回滚通知，继续使用原配置。
+// 只有 reloadableKeys 中的配置项支持热更新，其余配置项修改后记录警告并保持原值，重启后生效。
+// 每次变更的结果都会写入 t_config_change_log。
+
+// 配置变更来源
+const (
+	ConfigSourceFile = "file" // 配置文件
+)
+
+// 配置变更结果
+const (
+	ConfigChangeApplied    = "applied"     // 已生效
+	ConfigChangeRejected   = "rejected"    // 校验未通过
+	ConfigChangeRolledBack = "rolled_back" // 订阅者应用失败，已回滚
+	ConfigChangeRestart    = "restart"     // 仅修改了不支持热更新的配置项，重启后生效
+)
+
+// secretConfigKeys 字段名无法识别为敏感字段的密码与密钥配置项，审计记录中脱敏
+var secretConfigKeys = map[string]bool{
+	"dbms.pwd":        true,
+	"sms.data.key":    true,
+	"sms.data.appKey": true,
+	"sms.providers":   true, // 各服务商的平台参数中含有密钥
+}
+
+// reloadableKeys 支持热更新的配置项前缀
+var reloadableKeys = []string{
+	"log.",
+	"task.reward.",
+	"task.llmPrompt",
+	"sms.data.",
+	"sms.providers",
+	"sms.timeout",
+	"sms.breaker.",
+	"llm.data.",
+}
+
+// ConfigSubscriber 配置变更订阅函数，返回错误时本次变更整体回滚
+// 回滚时以相反的参数再次调用，此时返回的错误只记录日志
+type ConfigSubscriber func(ctx context.Context, old, new *Config) error
+
+type configSubscription struct {
+	name string
+	fn   ConfigSubscriber
+}
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   []configSubscription
+	reloadMu      sync.Mutex // 保证同一时间只有一次热更新
+	watchOnce     sync.Once
+)
+
+// ConfigChange 单个配置项的变更
+type ConfigChange struct {
+	Key     string `json:"key"`     // 配置项，如 task.reward.dailyCheckInPoints
+	Old     any    `json:"old"`     // 原值
+	New     any    `json:"new"`     // 新值
+	Restart bool   `json:"restart"` // 是否需要重启才能生效
+}
+
+// SubscribeConfig 注册配置变更订阅，模块在 Init 中调用
+func SubscribeConfig(name string, fn ConfigSubscriber) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers = append(subscribers, configSubscription{name: name, fn: fn})
+}
+
+// WatchConfig 监听配置文件变化并热更新，需在所有模块初始化完成后调用
+func WatchConfig(ctx context.Context) {
+	watchOnce.Do(func() {
+		viper.OnConfigChange(func(e fsnotify.Event) {
+			if ctx.Err() != nil {
+				return
+			}
+			_, err := ReloadConfig(ctx, ConfigSourceFile)
+			if err != nil {
+				logger.Error("config reload failed", zap.String("file", e.Name), zap.Error(err))
+			}
+		})
+		viper.WatchConfig()
+		MiniLogger.Info("[ OK ] config watcher started", zap.String("path", viper.ConfigFileUsed()))
+	})
+}
+
+// ReloadConfig 重新加载配置并通知订阅者，返回本次生效的变更
+func ReloadConfig(ctx context.Context, source string) ([]ConfigChange, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old := GetConfig()
+	next, err := LoadConfig()
+	if err == nil {
+		if problems := next.Validate(); len(problems) > 0 {
+			err = problems
+		}
+	}
+	if err != nil {
+		e := fmt.Errorf("new config is invalid, keep current config: %w", err)
+		logger.Error(e.Error())
+		saveConfigChangeLog(ctx, source, ConfigChangeRejected, nil, e)
+		return nil, e
+	}
+
+	changes := diffConfig(old, next)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	// 不支持热更新的配置项保持原值
+	var applied []ConfigChange
+	for _, change := range changes {
+		if change.Restart {
+			logger.Warn("config change requires restart, ignored until then", zap.String("key", change.Key))
+			err = restoreConfigKey(next, old, change.Key)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		applied = append(applied, change)
+	}
+	if len(applied) == 0 {
+		saveConfigChangeLog(ctx, source, ConfigChangeRestart, changes, nil)
+		return nil, nil
+	}
+
+	err = notifySubscribers(ctx, old, next)
+	if err != nil {
+		logger.Error("config change rolled back", zap.Error(err))
+		saveConfigChangeLog(ctx, source, ConfigChangeRolledBack, changes, err)
+		return nil, err
+	}
+	config.Store(next)
+
+	for _, change := range applied {
+		logger.Info("config changed", zap.String("key", change.Key))
+	}
+	saveConfigChangeLog(ctx, source, ConfigChangeApplied, changes, nil)
+	return applied, nil
+}
+
+// notifySubscribers 按注册顺序通知订阅者，失败时按相反顺序回滚已通知的订阅者
+func notifySubscribers(ctx context.Context, old, next *Config) error {
+	subscribersMu.Lock()
+	subs := append([]configSubscription(nil), subscribers...)
+	subscribersMu.Unlock()
+
+	for i, sub := range subs {
+		err := sub.fn(ctx, old, next)
+		if err == nil {
+			continue
+		}
+
+		for j := i - 1; j >= 0; j-- {
+			rollbackErr := subs[j].fn(ctx, next, old)
+			if rollbackErr != nil {
+				logger.Error("config subscriber rollback failed", zap.String("subscriber", subs[j].name), zap.Error(rollbackErr))
+			}
+		}
+		return fmt.Errorf("subscriber %s rejected config change: %w", sub.name, err)
+	}
+	return nil
+}
+
+// diffConfig 比较两份配置，返回值不同的配置项
+func diffConfig(old, next *Config) []ConfigChange {
+	oldValues := configValues(reflect.ValueOf(old).Elem(), "")
+	nextValues := configValues(reflect.ValueOf(next).Elem(), "")
+
+	var changes []ConfigChange
+	for _, key := range configValueKeys(reflect.TypeOf(Config{}), "") {
+		o, n := oldValues[key].Interface(), nextValues[key].Interface()
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		change := ConfigChange{Key: key, Old: o, New: n, Restart: !isReloadableKey(key)}
+		if secretConfigKeys[key] || classifyField(key[strings.LastIndex(key, ".")+1:]) != fieldPlain {
+			change.Old, change.New = maskedSecret, maskedSecret
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// configValueKeys 列出所有可比较的配置项，与 configKeys 不同的是映射类型的节点整体作为一项
+func configValueKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, configValueKeys(field.Type, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// configValues 返回配置项到字段值的映射，字段值可直接修改
+func configValues(v reflect.Value, prefix string) map[string]reflect.Value {
+	values := map[string]reflect.Value{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := prefix + t.Field(i).Tag.Get("mapstructure")
+		if t.Field(i).Type.Kind() == reflect.Struct {
+			for k, fv := range configValues(v.Field(i), key+".") {
+				values[k] = fv
+			}
+			continue
+		}
+		values[key] = v.Field(i)
+	}
+	return values
+}
+
+// restoreConfigKey 将 next 中的配置项恢复为 old 中的值
+func restoreConfigKey(next, old *Config, key string) error {
+	nextValue, ok := configValues(reflect.ValueOf(next).Elem(), "")[key]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %s", ErrInvalidConfig, key)
+	}
+	nextValue.Set(configValues(reflect.ValueOf(old).Elem(), "")[key])
+	return nil
+}
+
+func isReloadableKey(key string) bool {
+	for _, prefix := range reloadableKeys {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// saveConfigChangeLog 记录配置变更审计，写入失败只记录日志
+func saveConfigChangeLog(ctx context.Context, source, status string, changes []ConfigChange, cause error) {
+	if GormDB == nil {
+		return
+	}
+
+	record := TConfigChangeLog{Source: source, Status: status}
+	if changes != nil {
+		data, err := json.Marshal(changes)
+		if err != nil {
+			logger.Error("failed to marshal config changes", zap.Error(err))
+			return
+		}
+		record.Changes = data
+	}
+	if cause != nil {
+		record.Error = cause.Error()
+	}
+
+	err := GormDB.WithContext(ctx).Create(&record).Error
+	if err != nil {
+		logger.Error("failed to save config change log", zap.String("status", status), zap.Error(err))
+	}
+}
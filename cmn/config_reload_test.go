@@ -0,0 +1,184 @@
+package cmn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// setupReloadTest 以仓库的 .config.json 为基础写入临时配置文件并加载为当前配置，返回修改配置文件的函数
+func setupReloadTest(t *testing.T) func(modify func(root map[string]any)) {
+	t.Helper()
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	content, err := os.ReadFile("../.config.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(modify func(root map[string]any)) {
+		var root map[string]any
+		if err := json.Unmarshal(content, &root); err != nil {
+			t.Fatal(err)
+		}
+		modify(root)
+		data, _ := json.Marshal(root)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(func(map[string]any) {})
+
+	SetConfigFile(path)
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := config.Load()
+	config.Store(cfg)
+
+	subscribersMu.Lock()
+	saved := subscribers
+	subscribers = nil
+	subscribersMu.Unlock()
+
+	t.Cleanup(func() {
+		SetConfigFile("")
+		viper.Reset()
+		config.Store(previous)
+		subscribersMu.Lock()
+		subscribers = saved
+		subscribersMu.Unlock()
+	})
+	return write
+}
+
+func section(root map[string]any, keys ...string) map[string]any {
+	for _, key := range keys {
+		root = root[key].(map[string]any)
+	}
+	return root
+}
+
+func TestReloadConfigApplies(t *testing.T) {
+	write := setupReloadTest(t)
+
+	var notified *Config
+	SubscribeConfig("test", func(ctx context.Context, old, new *Config) error {
+		notified = new
+		return nil
+	})
+
+	write(func(root map[string]any) {
+		section(root, "task", "reward")["dailyCheckInPoints"] = 20
+		section(root, "server")["port"] = "9999"
+		section(root, "ubanquan")["appSecret"] = "new-secret"
+	})
+	changes, err := ReloadConfig(context.Background(), ConfigSourceFile)
+	if err != nil {
+		t.Fatalf("ReloadConfig failed: %v", err)
+	}
+
+	if len(changes) != 1 || changes[0].Key != "task.reward.dailyCheckInPoints" {
+		t.Fatalf("expected only the reward change to be applied, got %+v", changes)
+	}
+	if notified == nil || notified.Task.Reward.DailyCheckInPoints != 20 {
+		t.Errorf("subscriber should receive the new config")
+	}
+	cfg := GetConfig()
+	if cfg.Task.Reward.DailyCheckInPoints != 20 {
+		t.Errorf("new config should take effect")
+	}
+	// 不支持热更新的配置项保持原值
+	if cfg.Server.Port == "9999" || cfg.Ubanquan.AppSecret == "new-secret" {
+		t.Errorf("restart-only keys should keep their old values")
+	}
+}
+
+func TestReloadConfigRollback(t *testing.T) {
+	write := setupReloadTest(t)
+	before := GetConfig()
+
+	var calls []float64
+	SubscribeConfig("first", func(ctx context.Context, old, new *Config) error {
+		calls = append(calls, new.Task.Reward.DailyCheckInPoints)
+		return nil
+	})
+	SubscribeConfig("second", func(ctx context.Context, old, new *Config) error {
+		return errors.New("points type not found")
+	})
+
+	write(func(root map[string]any) {
+		section(root, "task", "reward")["dailyCheckInPoints"] = 30
+	})
+	_, err := ReloadConfig(context.Background(), ConfigSourceFile)
+	if err == nil {
+		t.Fatal("expected the change to be rolled back")
+	}
+
+	// 第一个订阅者先收到新配置，回滚时再收到原配置
+	if len(calls) != 2 || calls[0] != 30 || calls[1] != before.Task.Reward.DailyCheckInPoints {
+		t.Errorf("unexpected subscriber calls: %v", calls)
+	}
+	if GetConfig() != before {
+		t.Errorf("config should not change after rollback")
+	}
+}
+
+func TestReloadConfigRejectsInvalid(t *testing.T) {
+	write := setupReloadTest(t)
+	before := GetConfig()
+
+	called := false
+	SubscribeConfig("test", func(ctx context.Context, old, new *Config) error {
+		called = true
+		return nil
+	})
+
+	write(func(root map[string]any) {
+		section(root, "task", "reward")["dailyCheckInPoints"] = 0
+		section(root, "log")["level"] = "verbose"
+	})
+	_, err := ReloadConfig(context.Background(), ConfigSourceFile)
+
+	var problems ConfigErrors
+	if !errors.As(err, &problems) || len(problems) != 2 {
+		t.Fatalf("expected 2 validation problems, got %v", err)
+	}
+	if called || GetConfig() != before {
+		t.Errorf("invalid config should not be applied")
+	}
+}
+
+func TestDiffConfigMasksSecrets(t *testing.T) {
+	old := defaultConfig()
+	next := defaultConfig()
+	next.Dbms.Pwd = "new-pwd"
+	next.Llm.Data.ApiKey = "sk-new"
+	next.Llm.Data.Model = "deepseek-reasoner"
+
+	changes := diffConfig(&old, &next)
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	for _, change := range changes {
+		switch change.Key {
+		case "dbms.pwd", "llm.data.apiKey":
+			if change.New != maskedSecret {
+				t.Errorf("%s should be masked, got %v", change.Key, change.New)
+			}
+		case "llm.data.model":
+			if change.New != "deepseek-reasoner" || change.Restart {
+				t.Errorf("unexpected change %+v", change)
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package cmn
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// loadTestConfig 读取仓库根目录的 .config.json，测试结束后清理 viper 的全局状态
+func loadTestConfig(t *testing.T) (*Config, error) {
+	t.Helper()
+	t.Cleanup(viper.Reset)
+	return LoadConfig()
+}
+
+func TestEnvName(t *testing.T) {
+	cases := map[string]string{
+		"dbms.pwd":               "WUDANG_DBMS_PWD",
+		"sms.data.apiUrl":        "WUDANG_SMS_DATA_API_URL",
+		"server.shutdownTimeout": "WUDANG_SERVER_SHUTDOWN_TIMEOUT",
+		"log.maskAllowlist":      "WUDANG_LOG_MASK_ALLOWLIST",
+	}
+	for key, want := range cases {
+		if got := envName(key); got != want {
+			t.Errorf("envName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestLoadConfigEnvOverride(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "appSecret")
+	err := os.WriteFile(secretFile, []byte("secret-from-file\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("WUDANG_DBMS_PWD", "pwd-from-env")
+	t.Setenv("WUDANG_SMS_ENABLE", "false")
+	t.Setenv("WUDANG_SERVER_SHUTDOWN_TIMEOUT", "3s")
+	t.Setenv("WUDANG_LOG_MASK_ALLOWLIST", "smsCode,phone")
+	t.Setenv("WUDANG_UBANQUAN_APP_SECRET_FILE", secretFile)
+
+	cfg, err := loadTestConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Dbms.Pwd != "pwd-from-env" {
+		t.Errorf("dbms.pwd = %q, want value from env", cfg.Dbms.Pwd)
+	}
+	if cfg.Sms.Enable {
+		t.Errorf("sms.enable should be overridden to false")
+	}
+	if cfg.Server.ShutdownTimeout.String() != "3s" {
+		t.Errorf("server.shutdownTimeout = %v, want 3s", cfg.Server.ShutdownTimeout)
+	}
+	if strings.Join(cfg.Log.MaskAllowlist, ",") != "smsCode,phone" {
+		t.Errorf("log.maskAllowlist = %v", cfg.Log.MaskAllowlist)
+	}
+	if cfg.Ubanquan.AppSecret != "secret-from-file" {
+		t.Errorf("ubanquan.appSecret = %q, want trimmed file content", cfg.Ubanquan.AppSecret)
+	}
+	// 配置文件中未被覆盖的值保持不变
+	if cfg.Dbms.User == "" || cfg.Task.LlmPrompt["prompt"] == nil {
+		t.Errorf("values from config file should be kept")
+	}
+}
+
+func TestLoadConfigCollectsProblems(t *testing.T) {
+	t.Setenv("WUDANG_DBMS_PWD", "pwd-from-env")
+	t.Setenv("WUDANG_DBMS_PWD_FILE", "/nonexistent")
+	t.Setenv("WUDANG_UBANQUAN_APP_ID_FILE", "/nonexistent")
+	t.Setenv("WUDANG_SERVER_SHUTDOWN_TIMEOUT", "soon")
+	t.Setenv("WUDANG_LOG_MAX_SIZE", "big")
+
+	cfg, err := loadTestConfig(t)
+	if cfg == nil {
+		t.Fatalf("LoadConfig should return the partially parsed config: %v", err)
+	}
+	var problems ConfigErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected ConfigErrors, got %v", err)
+	}
+	if len(problems) != 4 {
+		t.Errorf("expected 4 problems, got %d: %v", len(problems), problems)
+	}
+	for _, problem := range problems {
+		if !errors.Is(problem, ErrInvalidConfig) {
+			t.Errorf("problem should wrap ErrInvalidConfig: %v", problem)
+		}
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Session.EncryptionKey = "short"
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8", "proxy.local"}
+	cfg.Sms = SmsConfig{Enable: true, Platform: "shx"}
+	cfg.Task.Enable = true
+	cfg.Scheduler.Jobs = map[string]SchedulerJobConfig{"points_expiry": {Spec: "not a spec"}}
+
+	problems := cfg.Validate()
+	messages := problems.Error()
+	for _, key := range []string{
+		"server.port", "session.authKey", "session.encryptionKey", "dbms.host", "dbms.pwd",
+		"sms.data.apiUrl", "sms.data.template", "ubanquan.appId", "task.reward.dailyCheckInPoints",
+		"task.llmPrompt.prompt", "scheduler.jobs.points_expiry.spec", "server.trustedProxies[1]",
+	} {
+		if !strings.Contains(messages, key) {
+			t.Errorf("expected a problem for %s: %s", key, messages)
+		}
+	}
+	// 未启用的模块不检查
+	if strings.Contains(messages, "server.trustedProxies[0]") {
+		t.Errorf("cidr should be a valid trusted proxy: %s", messages)
+	}
+	if strings.Contains(messages, "llm.") || strings.Contains(messages, "tracing.") {
+		t.Errorf("disabled modules should not be validated: %s", messages)
+	}
+}
+
+func TestValidateSmsProviders(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Env = EnvTest
+	cfg.Sms = SmsConfig{
+		Enable: true,
+		Providers: []SmsProviderConfig{
+			{Platform: "mock", Data: SmsDataConfig{MockFile: "sms.jsonl"}},
+			{Platform: "mock"},
+			{Name: "backup", Platform: "aliyun"},
+		},
+		Timeout: -time.Second,
+	}
+
+	messages := cfg.Validate().Error()
+	for _, key := range []string{"sms.providers[1].data", "sms.providers[1].name", "sms.providers[2].platform", "sms.timeout"} {
+		if !strings.Contains(messages, key) {
+			t.Errorf("expected a problem for %s: %s", key, messages)
+		}
+	}
+	// 配置 providers 时不检查单一平台配置
+	if strings.Contains(messages, "sms.platform") || strings.Contains(messages, "sms.providers[0]") {
+		t.Errorf("unexpected sms problems: %s", messages)
+	}
+
+	// 生产环境不允许使用模拟服务商
+	cfg.Env = EnvProd
+	messages = cfg.Validate().Error()
+	if !strings.Contains(messages, "sms.providers[0].platform") {
+		t.Errorf("expected mock provider to be rejected in prod: %s", messages)
+	}
+}
+
+func TestSmsProviderConfigs(t *testing.T) {
+	legacy := SmsConfig{Platform: "shx", Data: SmsDataConfig{ApiUrl: "http://sms"}}
+	got := legacy.ProviderConfigs()
+	if len(got) != 1 || got[0].Name != "shx" || got[0].Data.ApiUrl != "http://sms" {
+		t.Errorf("legacy ProviderConfigs() = %+v", got)
+	}
+
+	cfg := SmsConfig{Providers: []SmsProviderConfig{
+		{Name: "c", Priority: 3},
+		{Platform: "mock", Priority: 1},
+		{Name: "b", Priority: 3},
+	}}
+	var names []string
+	for _, p := range cfg.ProviderConfigs() {
+		names = append(names, p.Name)
+	}
+	if strings.Join(names, ",") != "mock,c,b" {
+		t.Errorf("ProviderConfigs() order = %v, want mock,c,b", names)
+	}
+}
+
+func TestRepositoryConfigIsValid(t *testing.T) {
+	cfg, err := loadTestConfig(t)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if problems := cfg.Validate(); len(problems) > 0 {
+		t.Errorf(".config.json is invalid: %v", problems)
+	}
+}
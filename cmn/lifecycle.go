@@ -0,0 +1,44 @@
+package cmn
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// 受管理的后台协程，服务关闭时需等待其退出后再关闭数据库连接池
+var backgroundWg sync.WaitGroup
+
+// GoBackground 启动受管理的后台协程，协程内的 panic 会被记录而不会导致进程退出
+// fn 应在 ctx 取消后尽快返回
+func GoBackground(name string, fn func()) {
+	backgroundWg.Add(1)
+	go func() {
+		defer backgroundWg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("background goroutine panic", zap.String("name", name), zap.Any("panic", r))
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// WaitBackground 等待所有受管理的后台协程退出，等待时间受 ctx 控制
+func WaitBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		backgroundWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait background goroutines: %w", ctx.Err())
+	}
+}
@@ -2,9 +2,10 @@ package llm
 
 import (
 	"WudangMeta/cmn"
+	"context"
 	"fmt"
+	"sync"
 
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -13,52 +14,76 @@ var (
 	enable   bool
 	platform string
 
+	configMu       sync.RWMutex // 平台配置支持热更新
 	deepSeekConfig DeepSeekConfig
 )
 
-func Init() {
+func Init(ctx context.Context) {
 	logger = cmn.GetLogger()
 
-	enable = viper.GetBool("llm.enable")
+	cfg := cmn.GetConfig().Llm
+	enable = cfg.Enable
 	if !enable {
 		cmn.MiniLogger.Info("[ -- ] llm module disabled")
 		return
 	}
 
-	platform = viper.GetString("llm.platform")
+	platform = cfg.Platform
 	if platform == "" {
 		logger.Fatal("[ FAIL ] llm platform not set")
 	}
 
 	switch platform {
 	case "deepseek":
-		err := initDeepSeek()
+		err := initDeepSeek(cfg.Data)
 		if err != nil {
 			logger.Fatal("[ FAIL ] failed to init deepseek", zap.Error(err))
 		}
 	}
 
+	// 模型与密钥修改后热更新，切换平台需要重启
+	cmn.SubscribeConfig("llm", func(ctx context.Context, old, new *cmn.Config) error {
+		if old.Llm.Data == new.Llm.Data || platform != "deepseek" {
+			return nil
+		}
+		return initDeepSeek(new.Llm.Data)
+	})
+
 	cmn.MiniLogger.Info("[ OK ] llm module initialed", zap.String("platform", platform))
 }
 
-func initDeepSeek() error {
-	deepSeekConfig.ApiKey = viper.GetString("llm.data.apiKey")
-	if deepSeekConfig.ApiKey == "" {
+func initDeepSeek(data cmn.LlmDataConfig) error {
+	config := DeepSeekConfig{
+		ApiKey:  data.ApiKey,
+		Model:   data.Model,
+		BaseUrl: data.BaseUrl,
+	}
+	if config.ApiKey == "" {
 		logger.Error("api key not set")
 		return fmt.Errorf("llm module api key not set")
 	}
-
-	deepSeekConfig.Model = viper.GetString("llm.data.model")
-	if deepSeekConfig.Model == "" {
+	if config.Model == "" {
 		logger.Error("model not set")
 		return fmt.Errorf("llm module model not set")
 	}
-
-	deepSeekConfig.BaseUrl = viper.GetString("llm.data.baseUrl")
-	if deepSeekConfig.BaseUrl == "" {
+	if config.BaseUrl == "" {
 		logger.Error("base url not set")
 		return fmt.Errorf("llm module base url not set")
 	}
 
+	configMu.Lock()
+	deepSeekConfig = config
+	configMu.Unlock()
 	return nil
 }
+
+func currentDeepSeekConfig() DeepSeekConfig {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return deepSeekConfig
+}
+
+// Configured 返回大模型服务是否启用以及启用时使用的平台，未启用或未初始化时平台为空
+func Configured() (bool, string) {
+	return enable, platform
+}
@@ -1,15 +1,19 @@
 package llm
 
 import (
+	"WudangMeta/cmn/metrics"
+	"WudangMeta/cmn/tracing"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
 type Service interface {
-	Chat(msg string) (string, error)
+	Chat(ctx context.Context, msg string) (string, error)
 }
 
 type deepSeekImpl struct {
@@ -24,7 +28,7 @@ func NewService() Service {
 	return &deepSeekImpl{}
 }
 
-func (*deepSeekImpl) Chat(msg string) (string, error) {
+func (*deepSeekImpl) Chat(ctx context.Context, msg string) (string, error) {
 	if msg == "" {
 		return "", nil
 	}
@@ -50,6 +54,7 @@ func (*deepSeekImpl) Chat(msg string) (string, error) {
 	}
 
 	url := "https://api.deepseek.com/chat/completions"
+	deepSeekConfig := currentDeepSeekConfig()
 
 	// 构造请求体
 	requestBody := ChatRequest{
@@ -69,7 +74,7 @@ func (*deepSeekImpl) Chat(msg string) (string, error) {
 	}
 
 	// 构造 HTTP 请求
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		logger.Error("new request fail")
 		return "", err
@@ -78,9 +83,16 @@ func (*deepSeekImpl) Chat(msg string) (string, error) {
 	req.Header.Set("Authorization", "Bearer "+deepSeekConfig.ApiKey)
 
 	// 执行请求
-	client := &http.Client{}
+	start := time.Now()
+	errKind := ""
+	defer func() {
+		metrics.ObserveExternalRequest(metrics.ServiceLlm, "chat", start, errKind)
+	}()
+
+	client := &http.Client{Transport: tracing.Transport(nil)}
 	resp, err := client.Do(req)
 	if err != nil {
+		errKind = metrics.ErrKindNetwork
 		return "", err
 	}
 	defer func(Body io.ReadCloser) {
@@ -94,6 +106,7 @@ func (*deepSeekImpl) Chat(msg string) (string, error) {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logger.Error("read response body fail")
+		errKind = metrics.ErrKindNetwork
 		return "", err
 	}
 
@@ -102,6 +115,8 @@ func (*deepSeekImpl) Chat(msg string) (string, error) {
 	err = json.Unmarshal(body, &chatResp)
 	if err != nil {
 		logger.Error("json unmarshal fail")
+		errKind = metrics.ErrKindDecode
+		metrics.ObserveLlmParseFailure("response")
 		return "", err
 	}
 
@@ -110,5 +125,6 @@ func (*deepSeekImpl) Chat(msg string) (string, error) {
 	}
 
 	logger.Warn("no response message found")
+	errKind = metrics.ErrKindApi
 	return "", fmt.Errorf("no response message found")
 }
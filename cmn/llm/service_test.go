@@ -1,15 +1,29 @@
 package llm
 
-import "testing"
+import (
+	"WudangMeta/cmn"
+	"context"
+	"os"
+	"testing"
+)
 
+// TestChat 调用真实的大模型接口，仅在通过环境变量提供密钥时执行
 func TestChat(t *testing.T) {
-	Init()
+	if os.Getenv("WUDANG_LLM_DATA_API_KEY") == "" {
+		t.Skip("WUDANG_LLM_DATA_API_KEY is not set, skip calling the llm api")
+	}
+	cmn.InitLogger(true)
+	cmn.InitConfig()
+	Init(context.Background())
+	if enabled, _ := Configured(); !enabled {
+		t.Skip("llm is not enabled")
+	}
 
 	service := NewService()
 
 	msg := "Hello, how are you?"
 
-	response, err := service.Chat(msg)
+	response, err := service.Chat(context.Background(), msg)
 	if err != nil {
 		t.Fatalf("Chat failed: %v", err)
 	}
@@ -1,13 +1,17 @@
 package cmn
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 const (
@@ -20,30 +24,70 @@ var (
 	once       sync.Once = sync.Once{}
 )
 
+var (
+	logLevel      = zap.NewAtomicLevelAt(zapcore.InfoLevel) // 运行时可调整的日志级别，控制台与文件输出共用
+	logFile       *logFileWriter                            // 生产环境日志文件，调试模式下为 nil
+	rotateDaily   atomic.Bool                               // 是否每天零点切割日志文件
+	levelMu       sync.Mutex
+	configLevel   = zapcore.InfoLevel // 配置的日志级别，临时调整到期后恢复为该级别
+	levelRevertAt time.Time           // 临时调整的到期时间，零值表示没有临时调整
+	levelRevert   *time.Timer
+	debugMode     bool // 是否以调试模式启动
+)
+
+var ErrInvalidLogLevel = errors.New("invalid log level")
+
+// LogConfig 日志配置，对应配置文件中的 log 节点
+type LogConfig struct {
+	Level         string   `mapstructure:"level"`         // 日志级别：debug/info/warn/error
+	File          string   `mapstructure:"file"`          // 日志文件路径
+	MaxSize       int      `mapstructure:"maxSize"`       // 单个文件最大尺寸（MB），超过后切割
+	MaxBackups    int      `mapstructure:"maxBackups"`    // 保留的历史文件个数，0 表示不限制
+	MaxAge        int      `mapstructure:"maxAge"`        // 历史文件保留天数，0 表示不限制
+	Compress      bool     `mapstructure:"compress"`      // 是否 gzip 压缩历史文件
+	RotateDaily   bool     `mapstructure:"rotateDaily"`   // 是否每天零点切割
+	MaskAllowlist []string `mapstructure:"maskAllowlist"` // 不脱敏的字段名，仅调试模式生效
+}
+
+// defaultLogConfig 未配置 log 节点时使用的默认值
+func defaultLogConfig() LogConfig {
+	return LogConfig{
+		Level:       "info",
+		File:        logDir + "/wudang.log",
+		MaxSize:     100,
+		MaxBackups:  30,
+		MaxAge:      30,
+		Compress:    true,
+		RotateDaily: true,
+	}
+}
+
+// LogLevelStatus 日志级别状态
+type LogLevelStatus struct {
+	Level       string `json:"level"`       // 当前级别
+	ConfigLevel string `json:"configLevel"` // 配置的级别
+	RevertAt    int64  `json:"revertAt"`    // 临时调整恢复为配置级别的时间，0 表示没有临时调整
+}
+
 func InitLogger(debug bool) {
 	once = sync.Once{}
 	once.Do(func() {
+		debugMode = debug
+
 		// 初始化日志文件目录
 		err := initDir()
 		if err != nil {
 			logger.Fatal("init dir failed", zap.Error(err))
 		}
 
-		// 生成当前时间戳
-		now := time.Now()
-		timestamp := now.Format("2006-01-02T15-04-05")
-
-		// 将时间戳插入到文件名中
-		logFileName := fmt.Sprintf("%s/%s.log", logDir, timestamp)
-
-		// 初始化日志
+		// 初始化日志，生产环境先使用默认配置，读取配置文件后由 applyLogConfig 调整
 		if debug {
 			err = initDevLogger()
 			if err != nil {
 				logger.Fatal("init dev logger failed" + err.Error())
 			}
 		} else {
-			err = initProdLogger(logFileName)
+			err = initProdLogger(defaultLogConfig())
 			if err != nil {
 				logger.Fatal("init prod logger failed" + err.Error())
 			}
@@ -61,6 +105,176 @@ func InitLogger(debug bool) {
 	MiniLogger.Info("[ OK ] log module initialized")
 }
 
+// applyLogConfig 读取配置文件或配置热更新后应用日志配置：日志级别、文件路径与切割策略
+// 调试模式固定输出 debug 级别到控制台，不受配置影响
+func applyLogConfig(cfg LogConfig) error {
+	// 脱敏白名单仅在调试模式下生效，避免生产环境误配导致敏感信息落盘
+	if debugMode {
+		setMaskAllowlist(cfg.MaskAllowlist)
+	} else if len(cfg.MaskAllowlist) > 0 {
+		logger.Warn("log.maskAllowlist is ignored outside debug mode", zap.Strings("keys", cfg.MaskAllowlist))
+	}
+
+	if logFile == nil {
+		return nil
+	}
+
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidLogLevel, cfg.Level)
+	}
+	levelMu.Lock()
+	configLevel = level
+	logLevel.SetLevel(level)
+	levelMu.Unlock()
+
+	// 按新的配置创建文件写入器并整体替换，旧的写入器不再被写入后关闭
+	old := logFile.swap(newLumberjack(cfg))
+	err = old.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close previous log file: %w", err)
+	}
+	rotateDaily.Store(cfg.RotateDaily)
+
+	MiniLogger.Info("[ OK ] log config applied",
+		zap.String("level", cfg.Level),
+		zap.String("file", cfg.File),
+		zap.Int("maxSize", cfg.MaxSize),
+		zap.Int("maxBackups", cfg.MaxBackups),
+		zap.Int("maxAge", cfg.MaxAge))
+	return nil
+}
+
+// StartLogRotation 启动每天零点切割日志文件的后台任务，ctx 取消后退出
+// 是否切割由 log.rotateDaily 决定，支持热更新；调试模式下不写文件，不启动
+func StartLogRotation(ctx context.Context) {
+	if logFile == nil {
+		return
+	}
+	go func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			if !rotateDaily.Load() {
+				continue
+			}
+			err := logFile.rotate()
+			if err != nil {
+				logger.Error("failed to rotate log file", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// logFileWriter 日志文件写入器，配置变更时整体替换内部的 lumberjack.Logger，
+// 避免在其他 goroutine 写入时修改 lumberjack.Logger 的字段
+type logFileWriter struct {
+	mu      sync.RWMutex
+	current *lumberjack.Logger
+}
+
+func (w *logFileWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current.Write(p)
+}
+
+// Sync lumberjack 直接写入文件，无需刷新
+func (w *logFileWriter) Sync() error {
+	return nil
+}
+
+// swap 替换为新的 lumberjack.Logger 并返回旧的实例，返回后旧实例不会再被写入
+func (w *logFileWriter) swap(next *lumberjack.Logger) *lumberjack.Logger {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	old := w.current
+	w.current = next
+	return old
+}
+
+// rotate 切割当前日志文件
+func (w *logFileWriter) rotate() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current.Rotate()
+}
+
+// newLumberjack 按配置创建日志文件，文件在首次写入时创建，超过 MaxSize 后切割，并按 MaxBackups 与 MaxAge 清理历史文件
+func newLumberjack(cfg LogConfig) *lumberjack.Logger {
+	return &lumberjack.Logger{
+		Filename:   cfg.File,
+		MaxSize:    cfg.MaxSize,
+		MaxBackups: cfg.MaxBackups,
+		MaxAge:     cfg.MaxAge,
+		Compress:   cfg.Compress,
+		LocalTime:  true,
+	}
+}
+
+// GetLogLevel 获取当前日志级别状态
+func GetLogLevel() LogLevelStatus {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	status := LogLevelStatus{
+		Level:       logLevel.Level().String(),
+		ConfigLevel: configLevel.String(),
+	}
+	if !levelRevertAt.IsZero() {
+		status.RevertAt = levelRevertAt.UnixMilli()
+	}
+	return status
+}
+
+// SetLogLevel 运行时调整日志级别，仅对当前实例生效，重启后恢复为配置的级别
+// duration 大于 0 时到期后自动恢复为配置的级别，用于线上临时开启 debug 日志
+func SetLogLevel(level string, duration time.Duration) error {
+	l, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidLogLevel, level)
+	}
+
+	levelMu.Lock()
+	defer levelMu.Unlock()
+
+	if levelRevert != nil {
+		levelRevert.Stop()
+		levelRevert = nil
+	}
+	levelRevertAt = time.Time{}
+	logLevel.SetLevel(l)
+
+	if duration > 0 {
+		levelRevertAt = time.Now().Add(duration)
+		var timer *time.Timer
+		timer = time.AfterFunc(duration, func() {
+			levelMu.Lock()
+			defer levelMu.Unlock()
+			// 到期前已被再次调整时不做处理
+			if levelRevert != timer {
+				return
+			}
+			logLevel.SetLevel(configLevel)
+			levelRevert = nil
+			levelRevertAt = time.Time{}
+			logger.Info("log level reverted", zap.String("level", configLevel.String()))
+		})
+		levelRevert = timer
+	}
+
+	logger.Info("log level changed", zap.String("level", l.String()), zap.Duration("duration", duration))
+	return nil
+}
+
 // GetLogger 获取全局的logger
 func GetLogger() *zap.Logger {
 	return logger
@@ -83,29 +297,34 @@ func initDevLogger() error {
 	consoleEncoder := zapcore.NewConsoleEncoder(encoderConfig)
 
 	// 控制台输出级别写 Debug，文件输出级别写 Error
-	consoleCore := zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), zapcore.DebugLevel)
+	configLevel = zapcore.DebugLevel
+	logLevel.SetLevel(zapcore.DebugLevel)
+	consoleCore := zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), logLevel)
 
-	core := zapcore.NewTee(consoleCore)
+	core := newMaskingCore(zapcore.NewTee(consoleCore))
 	logger := zap.New(core, zap.AddCaller())
 	zap.ReplaceGlobals(logger)
 
 	return nil
 }
 
-// initProdLogger 初始化生产环境日志
-func initProdLogger(logFilePath string) error {
+// initProdLogger 初始化生产环境日志，文件按大小与时间切割
+func initProdLogger(cfg LogConfig) error {
 	// 参数校验
-	if logFilePath == "" {
+	if cfg.File == "" {
 		fmt.Println("log file path is empty, init log failed")
 		return nil
 	}
 
-	// 创建日志文件（如果文件已经存在，会覆盖；在实际项目中常配合日志切割工具一起使用）
-	file, err := os.Create(logFilePath)
+	level, err := zapcore.ParseLevel(cfg.Level)
 	if err != nil {
-		fmt.Printf("create log file failed: %v\n", err)
-		return err
+		return fmt.Errorf("%w: %q", ErrInvalidLogLevel, cfg.Level)
 	}
+	configLevel = level
+	logLevel.SetLevel(level)
+
+	logFile = &logFileWriter{current: newLumberjack(cfg)}
+	rotateDaily.Store(cfg.RotateDaily)
 
 	// 使用生产环境的 EncoderConfig
 	encoderConfig := zap.NewProductionEncoderConfig()
@@ -123,19 +342,19 @@ func initProdLogger(logFilePath string) error {
 	consoleCore := zapcore.NewCore(
 		consoleEncoder,
 		zapcore.AddSync(os.Stdout),
-		zapcore.InfoLevel, // 仅在控制台打印 WARN 及以上
+		logLevel,
 	)
 
 	// 对文件输出，使用 JSONEncoder，记录 Info 及以上
 	fileEncoder := zapcore.NewJSONEncoder(encoderConfig)
 	fileCore := zapcore.NewCore(
 		fileEncoder,
-		zapcore.AddSync(file),
-		zapcore.InfoLevel, // 写入文件的最小级别
+		logFile,
+		logLevel,
 	)
 
-	// 将控制台和文件两个 Core 合并
-	core := zapcore.NewTee(consoleCore, fileCore)
+	// 将控制台和文件两个 Core 合并，写入前统一脱敏
+	core := newMaskingCore(zapcore.NewTee(consoleCore, fileCore))
 
 	// 生产环境一般去掉开发模式下的 Stacktrace 太多信息，直接启用 caller 和时间戳即可
 	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(0))
@@ -159,7 +378,7 @@ func initMiniLogger() error {
 		zapcore.InfoLevel, // 只输出 info 及以上
 	)
 
-	MiniLogger = zap.New(core)
+	MiniLogger = zap.New(newMaskingCore(core))
 
 	return nil
 }
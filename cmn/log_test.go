@@ -0,0 +1,37 @@
+package cmn
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogFileWriterSwap(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.log")
+	second := filepath.Join(dir, "second.log")
+
+	w := &logFileWriter{current: newLumberjack(LogConfig{File: first, MaxSize: 1})}
+	if _, err := w.Write([]byte("a\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	old := w.swap(newLumberjack(LogConfig{File: second, MaxSize: 1}))
+	if err := old.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := w.Write([]byte("b\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	t.Cleanup(func() { _ = w.current.Close() })
+
+	for file, want := range map[string]string{first: "a\n", second: "b\n"} {
+		got, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", filepath.Base(file), got, want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package cmn
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 日志字段脱敏策略
+//
+// 按字段名识别敏感字段，整体替换为掩码：
+//   - 手机号：字段名包含 phone、mobile，保留前3位与后4位
+//   - 验证码：smsCode、verifyCode、captcha、otp 等，全部替换
+//   - 令牌与密钥：字段名包含 token、secret、password、apiKey 等，全部替换
+//
+// 其他字符串、错误与日志消息中出现的手机号同样会被脱敏。
+// zap.Any 记录的结构体无法逐字段检查，敏感数据不应以结构体形式写入日志。
+// 调试模式下可通过 log.maskAllowlist 配置不脱敏的字段名，便于本地联调，生产环境该配置不生效。
+
+const (
+	maskedCode   = "****"   // 验证码掩码
+	maskedSecret = "******" // 令牌与密钥掩码，不暴露原值长度
+)
+
+// 敏感字段类型
+const (
+	fieldPlain = iota
+	fieldPhone
+	fieldCode
+	fieldSecret
+)
+
+// codeFieldNames 验证码字段名（规范化后）
+var codeFieldNames = map[string]bool{
+	"smscode":          true,
+	"verifycode":       true,
+	"verificationcode": true,
+	"captcha":          true,
+	"captchacode":      true,
+	"otp":              true,
+}
+
+// secretFieldKeywords 令牌与密钥字段名包含的关键字（规范化后）
+var secretFieldKeywords = []string{
+	"token", "secret", "password", "passwd", "apikey", "authkey",
+	"encryptionkey", "privatekey", "credential", "authorization", "cookie",
+}
+
+// maskAllowlist 调试模式下不脱敏的字段名（规范化后）
+var maskAllowlist atomic.Pointer[map[string]bool]
+
+// setMaskAllowlist 设置不脱敏的字段名
+func setMaskAllowlist(keys []string) {
+	allowlist := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		allowlist[normalizeFieldKey(key)] = true
+	}
+	maskAllowlist.Store(&allowlist)
+}
+
+// normalizeFieldKey 字段名转小写并去掉分隔符，使 mobile_phone、mobilePhone 等写法一致
+func normalizeFieldKey(key string) string {
+	key = strings.ToLower(key)
+	return strings.NewReplacer("_", "", "-", "", ".", "").Replace(key)
+}
+
+// classifyField 按字段名判断敏感字段类型
+func classifyField(key string) int {
+	k := normalizeFieldKey(key)
+	if codeFieldNames[k] {
+		return fieldCode
+	}
+	if strings.Contains(k, "phone") || strings.Contains(k, "mobile") {
+		return fieldPhone
+	}
+	for _, keyword := range secretFieldKeywords {
+		if strings.Contains(k, keyword) {
+			return fieldSecret
+		}
+	}
+	return fieldPlain
+}
+
+// MaskPhone 手机号脱敏，保留前3位与后4位
+func MaskPhone(phone string) string {
+	if len(phone) < 8 {
+		return maskedCode
+	}
+	return phone[:3] + "****" + phone[len(phone)-4:]
+}
+
+// MaskPhonesIn 将文本中出现的大陆手机号脱敏
+func MaskPhonesIn(s string) string {
+	var b strings.Builder
+	last := 0
+	for i := 0; i+11 <= len(s); {
+		if isPhoneAt(s, i) {
+			b.WriteString(s[last:i])
+			b.WriteString(MaskPhone(s[i : i+11]))
+			i += 11
+			last = i
+			continue
+		}
+		i++
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// isPhoneAt 判断 s[i:i+11] 是否为前后不紧邻数字的手机号
+func isPhoneAt(s string, i int) bool {
+	if s[i] != '1' || s[i+1] < '3' || s[i+1] > '9' {
+		return false
+	}
+	for j := i + 2; j < i+11; j++ {
+		if !isDigit(s[j]) {
+			return false
+		}
+	}
+	if i > 0 && isDigit(s[i-1]) {
+		return false
+	}
+	if i+11 < len(s) && isDigit(s[i+11]) {
+		return false
+	}
+	return true
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+// maskFields 返回脱敏后的字段，不修改传入的切片
+func maskFields(fields []zapcore.Field) []zapcore.Field {
+	var allowlist map[string]bool
+	if p := maskAllowlist.Load(); p != nil {
+		allowlist = *p
+	}
+
+	var masked []zapcore.Field
+	for i, f := range fields {
+		if allowlist[normalizeFieldKey(f.Key)] {
+			continue
+		}
+		mf, changed := maskField(f)
+		if !changed {
+			continue
+		}
+		if masked == nil {
+			masked = make([]zapcore.Field, len(fields))
+			copy(masked, fields)
+		}
+		masked[i] = mf
+	}
+
+	if masked == nil {
+		return fields
+	}
+	return masked
+}
+
+// maskField 对单个字段脱敏，返回脱敏后的字段以及是否有改动
+func maskField(f zapcore.Field) (zapcore.Field, bool) {
+	switch classifyField(f.Key) {
+	case fieldPhone:
+		if f.Type == zapcore.BoolType || f.Type == zapcore.SkipType {
+			return f, false
+		}
+		return maskedField(f.Key, MaskPhone(fieldString(f))), true
+	case fieldCode:
+		if f.Type == zapcore.SkipType {
+			return f, false
+		}
+		return maskedField(f.Key, maskedCode), true
+	case fieldSecret:
+		if f.Type == zapcore.BoolType || f.Type == zapcore.SkipType {
+			return f, false
+		}
+		return maskedField(f.Key, maskedSecret), true
+	}
+
+	switch f.Type {
+	case zapcore.StringType, zapcore.ByteStringType, zapcore.StringerType, zapcore.ErrorType:
+		s := fieldString(f)
+		masked := MaskPhonesIn(s)
+		if masked == s {
+			return f, false
+		}
+		return maskedField(f.Key, masked), true
+	}
+	return f, false
+}
+
+func maskedField(key, value string) zapcore.Field {
+	return zapcore.Field{Key: key, Type: zapcore.StringType, String: value}
+}
+
+// fieldString 获取字段的字符串形式
+func fieldString(f zapcore.Field) string {
+	switch f.Type {
+	case zapcore.StringType:
+		return f.String
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			return string(b)
+		}
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			return s.String()
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return err.Error()
+		}
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		return fmt.Sprint(f.Integer)
+	case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type:
+		return fmt.Sprint(uint64(f.Integer))
+	}
+	if f.Interface != nil {
+		return fmt.Sprint(f.Interface)
+	}
+	return f.String
+}
+
+// maskingCore 在写入编码器前对日志消息与字段脱敏
+type maskingCore struct {
+	zapcore.Core
+}
+
+// newMaskingCore 包装 core，使其输出经过脱敏
+func newMaskingCore(core zapcore.Core) zapcore.Core {
+	return &maskingCore{Core: core}
+}
+
+func (c *maskingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &maskingCore{Core: c.Core.With(maskFields(fields))}
+}
+
+func (c *maskingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *maskingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = MaskPhonesIn(ent.Message)
+
+	// 通过内层 Check 写入，保证组合的多个 core 各自的级别过滤仍然生效
+	inner := c.Core.Check(ent, nil)
+	if inner == nil {
+		return nil
+	}
+	inner.Write(maskFields(fields)...)
+	return nil
+}
@@ -0,0 +1,145 @@
+package cmn
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	rawPhone  = "13812345678"
+	rawCode   = "864213"
+	rawToken  = "eyJhbGciOiJIUzI1NiJ9.payload.signature"
+	rawSecret = "ubq-app-secret-value"
+)
+
+type phoneStringer struct{}
+
+func (phoneStringer) String() string {
+	return "user " + rawPhone
+}
+
+// newTestLogger 创建输出到缓冲区的 JSON 日志，与生产环境一样经过脱敏 core
+func newTestLogger(buf *bytes.Buffer) *zap.Logger {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	core := zapcore.NewCore(encoder, zapcore.AddSync(buf), zapcore.DebugLevel)
+	return zap.New(newMaskingCore(core))
+}
+
+// assertNoRaw 检查编码后的输出中不包含任何原始敏感值
+func assertNoRaw(t *testing.T, output string, raws ...string) {
+	t.Helper()
+	for _, raw := range raws {
+		if strings.Contains(output, raw) {
+			t.Errorf("raw value %q reached the encoder: %s", raw, output)
+		}
+	}
+}
+
+func TestMaskSensitiveFields(t *testing.T) {
+	setMaskAllowlist(nil)
+	var buf bytes.Buffer
+	l := newTestLogger(&buf)
+
+	l.Info("sent sms code",
+		zap.String("phone", rawPhone),
+		zap.String("mobile_phone", rawPhone),
+		zap.String("mobilePhone", rawPhone),
+		zap.Int64("phoneNumber", 13812345678),
+		zap.String("smsCode", rawCode),
+		zap.String("verify_code", rawCode),
+		zap.String("accessToken", rawToken),
+		zap.String("refresh_token", rawToken),
+		zap.String("appSecret", rawSecret),
+		zap.ByteString("password", []byte(rawSecret)),
+	)
+	output := buf.String()
+
+	assertNoRaw(t, output, rawPhone, rawCode, rawToken, rawSecret)
+	if !strings.Contains(output, `"phone":"138****5678"`) {
+		t.Errorf("phone should keep prefix and suffix: %s", output)
+	}
+	if !strings.Contains(output, `"smsCode":"****"`) {
+		t.Errorf("sms code should be masked: %s", output)
+	}
+	if !strings.Contains(output, `"appSecret":"******"`) {
+		t.Errorf("secret should be masked: %s", output)
+	}
+}
+
+func TestMaskPhonesInValues(t *testing.T) {
+	setMaskAllowlist(nil)
+	var buf bytes.Buffer
+	l := newTestLogger(&buf)
+
+	l.Error("user "+rawPhone+" not found",
+		zap.String("detail", "mobile="+rawPhone+";"),
+		zap.Error(errors.New("duplicate key phone "+rawPhone)),
+		zap.Stringer("user", phoneStringer{}),
+		zap.String("orderNo", "2024"+rawPhone+"01"),
+	)
+	output := buf.String()
+
+	assertNoRaw(t, output, "user "+rawPhone, "="+rawPhone, "phone "+rawPhone)
+	if strings.Count(output, "138****5678") != 4 {
+		t.Errorf("expected 4 masked phones in message and values: %s", output)
+	}
+	// 紧邻其他数字的数字串不是手机号，保持原样
+	if !strings.Contains(output, "2024"+rawPhone+"01") {
+		t.Errorf("digits embedded in a longer number should not be masked: %s", output)
+	}
+}
+
+func TestMaskWithFields(t *testing.T) {
+	setMaskAllowlist(nil)
+	var buf bytes.Buffer
+	l := newTestLogger(&buf).With(zap.String("mobilePhone", rawPhone), zap.String("token", rawToken))
+
+	l.Info("request")
+	assertNoRaw(t, buf.String(), rawPhone, rawToken)
+}
+
+func TestMaskAllowlist(t *testing.T) {
+	setMaskAllowlist([]string{"sms_code"})
+	defer setMaskAllowlist(nil)
+
+	var buf bytes.Buffer
+	l := newTestLogger(&buf)
+	l.Info("sent sms code", zap.String("smsCode", rawCode), zap.String("phone", rawPhone))
+	output := buf.String()
+
+	if !strings.Contains(output, rawCode) {
+		t.Errorf("allowlisted field should not be masked: %s", output)
+	}
+	assertNoRaw(t, output, rawPhone)
+}
+
+func TestMaskKeepsOtherFields(t *testing.T) {
+	setMaskAllowlist(nil)
+	fields := []zapcore.Field{
+		zap.String("userId", "5f1c0c5e-7d7e-4f0f-9b1e-0a6c2b1e8f11"),
+		zap.Int("count", 3),
+		zap.Bool("tokenValid", true),
+	}
+	masked := maskFields(fields)
+	if &masked[0] != &fields[0] {
+		t.Errorf("fields without sensitive data should not be copied")
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	cases := map[string]string{
+		rawPhone:         "138****5678",
+		"+86" + rawPhone: "+86****5678",
+		"123":            "****",
+	}
+	for in, want := range cases {
+		if got := MaskPhone(in); got != want {
+			t.Errorf("MaskPhone(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace 所有指标的统一前缀
+const namespace = "wudang"
+
+// 外部服务名称
+const (
+	ServiceUbanquan = "ubanquan"
+	ServiceLlm      = "llm"
+)
+
+var (
+	// httpRequestDuration 按路由统计的HTTP请求耗时
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// raffleDrawsTotal 抽奖次数
+	raffleDrawsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "raffle",
+		Name:      "draws_total",
+		Help:      "Number of raffle draws by result.",
+	}, []string{"result"})
+
+	// raffleWinsTotal 按奖品统计的中奖次数
+	raffleWinsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "raffle",
+		Name:      "wins_total",
+		Help:      "Number of raffle wins by prize.",
+	}, []string{"prize"})
+
+	// pointsChangesTotal 按原因统计的积分变动次数
+	pointsChangesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "points",
+		Name:      "changes_total",
+		Help:      "Number of points changes by direction, reason and points type.",
+	}, []string{"direction", "reason", "points_type"})
+
+	// pointsAmountTotal 按原因统计的积分变动数量
+	pointsAmountTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "points",
+		Name:      "amount_total",
+		Help:      "Amount of points credited or debited by reason and points type.",
+	}, []string{"direction", "reason", "points_type"})
+
+	// smsSendsTotal 按服务商与结果码统计的短信发送次数
+	smsSendsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sms",
+		Name:      "sends_total",
+		Help:      "Number of SMS sends by provider and result code.",
+	}, []string{"provider", "result", "code"})
+
+	// smsBlockedTotal 按规则统计的被限流拒绝的短信发送请求数
+	smsBlockedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sms",
+		Name:      "blocked_total",
+		Help:      "Number of SMS send requests blocked by rate limit rule.",
+	}, []string{"rule"})
+
+	// smsBreakerState 短信服务商熔断状态
+	smsBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sms",
+		Name:      "breaker_state",
+		Help:      "Circuit breaker state of SMS providers: 0 closed, 1 half-open, 2 open.",
+	}, []string{"provider"})
+
+	// smsFailoversTotal 首选服务商失败后切换到下一个服务商的次数
+	smsFailoversTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sms",
+		Name:      "failovers_total",
+		Help:      "Number of SMS sends failed over from a provider to the next one.",
+	}, []string{"from"})
+
+	// captchaVerifiesTotal 按结果统计的图形验证次数
+	captchaVerifiesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "captcha",
+		Name:      "verifies_total",
+		Help:      "Number of CAPTCHA verifications by result.",
+	}, []string{"result"})
+
+	// externalRequestDuration 外部服务调用耗时
+	externalRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "external",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of calls to external services.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"service", "operation"})
+
+	// externalRequestErrorsTotal 外部服务调用失败次数
+	externalRequestErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "external",
+		Name:      "request_errors_total",
+		Help:      "Number of failed calls to external services by failure kind.",
+	}, []string{"service", "operation", "kind"})
+
+	// llmParseFailuresTotal 大模型响应解析失败次数
+	llmParseFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "parse_failures_total",
+		Help:      "Number of LLM responses that failed to parse by stage.",
+	}, []string{"stage"})
+)
+
+// 外部服务调用失败类型
+const (
+	ErrKindNetwork = "network" // 网络错误或超时
+	ErrKindDecode  = "decode"  // 响应解析失败
+	ErrKindApi     = "api"     // 服务端返回业务错误
+)
+
+// ObserveHttpRequest 记录一次HTTP请求
+func ObserveHttpRequest(method, route, status string, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, status).Observe(duration.Seconds())
+}
+
+// ObserveRaffle 记录一次抽奖请求的结果，成功时按奖品记录中奖次数
+func ObserveRaffle(draws int64, prizesWon []string, err error) {
+	if err != nil {
+		raffleDrawsTotal.WithLabelValues("failed").Add(float64(draws))
+		return
+	}
+
+	raffleDrawsTotal.WithLabelValues("succeeded").Add(float64(draws))
+	for _, prize := range prizesWon {
+		raffleWinsTotal.WithLabelValues(prize).Inc()
+	}
+}
+
+// ObservePointsChange 记录一次积分变动，delta 为正表示增加，为负表示扣减
+func ObservePointsChange(pointsType, reason string, delta float64) {
+	direction := "credit"
+	amount := delta
+	if delta < 0 {
+		direction = "debit"
+		amount = -delta
+	}
+	pointsChangesTotal.WithLabelValues(direction, reason, pointsType).Inc()
+	pointsAmountTotal.WithLabelValues(direction, reason, pointsType).Add(amount)
+}
+
+// ObserveSmsSend 记录一次短信发送，code 为服务商返回的结果码或本地错误类型
+func ObserveSmsSend(provider string, succeeded bool, code string) {
+	result := "succeeded"
+	if !succeeded {
+		result = "failed"
+	}
+	smsSendsTotal.WithLabelValues(provider, result, code).Inc()
+}
+
+// ObserveSmsBlocked 记录一次被限流拒绝的短信发送请求
+func ObserveSmsBlocked(rule string) {
+	smsBlockedTotal.WithLabelValues(rule).Inc()
+}
+
+// 短信服务商熔断状态取值
+const (
+	SmsBreakerClosed   = 0
+	SmsBreakerHalfOpen = 1
+	SmsBreakerOpen     = 2
+)
+
+// SetSmsBreakerState 记录短信服务商当前的熔断状态
+func SetSmsBreakerState(provider string, state int) {
+	smsBreakerState.WithLabelValues(provider).Set(float64(state))
+}
+
+// ObserveSmsFailover 记录一次从服务商 from 切换到下一个服务商的发送
+func ObserveSmsFailover(from string) {
+	smsFailoversTotal.WithLabelValues(from).Inc()
+}
+
+// ObserveCaptchaVerify 记录一次图形验证的结果
+func ObserveCaptchaVerify(result string) {
+	captchaVerifiesTotal.WithLabelValues(result).Inc()
+}
+
+// ObserveExternalRequest 记录一次外部服务调用，errKind 为空表示调用成功
+func ObserveExternalRequest(service, operation string, start time.Time, errKind string) {
+	externalRequestDuration.WithLabelValues(service, operation).Observe(time.Since(start).Seconds())
+	if errKind != "" {
+		externalRequestErrorsTotal.WithLabelValues(service, operation, errKind).Inc()
+	}
+}
+
+// ObserveLlmParseFailure 记录一次大模型响应解析失败
+func ObserveLlmParseFailure(stage string) {
+	llmParseFailuresTotal.WithLabelValues(stage).Inc()
+}
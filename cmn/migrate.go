@@ -0,0 +1,325 @@
+package cmn
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 数据库结构通过 migrations 目录下编号的 SQL 文件管理，文件名格式为 <版本号>_<名称>.up.sql / .down.sql，
+// 版本号从1开始连续递增，每个版本必须同时提供 up 与 down 文件。
+// 修改表结构、视图、索引或回填数据时新增迁移文件，不要修改已发布的迁移文件。
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// migrationLockKey 执行迁移时持有的事务级咨询锁，避免多个实例同时执行同一迁移
+const migrationLockKey = 7283741016
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var (
+	ErrSchemaOutdated   = errors.New("database schema is outdated")
+	ErrInvalidMigration = errors.New("invalid migration")
+)
+
+// Migration 一个版本的数据库迁移
+type Migration struct {
+	Version int64  // 版本号
+	Name    string // 名称
+	Up      string // 升级 SQL
+	Down    string // 回滚 SQL
+}
+
+// MigrationStatus 迁移版本的执行状态
+type MigrationStatus struct {
+	Version   int64  `json:"version"`   // 版本号
+	Name      string `json:"name"`      // 名称
+	Applied   bool   `json:"applied"`   // 是否已执行
+	AppliedAt int64  `json:"appliedAt"` // 执行时间，未执行时为0
+	Unknown   bool   `json:"unknown"`   // 数据库中有记录但当前程序中不存在，通常是更高版本的程序执行过的迁移
+}
+
+// LoadMigrations 读取内置的迁移文件，按版本号升序返回
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		m := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("%w: unexpected file name %s", ErrInvalidMigration, entry.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		content, err := migrationFS.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("%w: version %d has different names %s and %s", ErrInvalidMigration, version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("%w: version %d requires both up and down files", ErrInvalidMigration, migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, migration := range migrations {
+		if migration.Version != int64(i+1) {
+			return nil, fmt.Errorf("%w: versions must start at 1 and be contiguous, missing version %d", ErrInvalidMigration, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// ensureMigrationTable 创建迁移记录表
+func ensureMigrationTable(ctx context.Context, db *gorm.DB) error {
+	err := db.WithContext(ctx).Exec(`CREATE TABLE IF NOT EXISTS "schema_migrations" (
+    "version" bigint PRIMARY KEY,
+    "name" varchar(100) NOT NULL,
+    "applied_at" bigint NOT NULL
+)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations 查询已执行的迁移记录
+func appliedMigrations(ctx context.Context, db *gorm.DB) (map[int64]TSchemaMigration, error) {
+	var records []TSchemaMigration
+	err := db.WithContext(ctx).Order("version").Find(&records).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+
+	applied := make(map[int64]TSchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// MigrateUp 按版本号顺序执行未执行的迁移，target 大于0时只执行到该版本
+// 每个迁移与其记录在同一事务中提交，失败时该迁移整体回滚，已成功的迁移保留
+func MigrateUp(ctx context.Context, db *gorm.DB, target int64) ([]Migration, error) {
+	if db == nil {
+		db = GormDB
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if target > int64(len(migrations)) {
+		return nil, fmt.Errorf("%w: target version %d does not exist, latest is %d", ErrInvalidMigration, target, len(migrations))
+	}
+	err = ensureMigrationTable(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range migrations {
+		if target > 0 && migration.Version > target {
+			break
+		}
+
+		var ran bool
+		err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error
+			if err != nil {
+				return err
+			}
+			// 持有锁后再检查，其他实例可能已经执行过
+			var count int64
+			err = tx.Model(&TSchemaMigration{}).Where("version = ?", migration.Version).Count(&count).Error
+			if err != nil || count > 0 {
+				return err
+			}
+
+			err = tx.Exec(migration.Up).Error
+			if err != nil {
+				return err
+			}
+			ran = true
+			return tx.Create(&TSchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now().UnixMilli(),
+			}).Error
+		})
+		if err != nil {
+			e := fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			logger.Error(e.Error())
+			return done, e
+		}
+		if ran {
+			logger.Info("migration applied", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+			done = append(done, migration)
+		}
+	}
+
+	return done, nil
+}
+
+// MigrateDown 按版本号倒序回滚最近执行的 steps 个迁移
+func MigrateDown(ctx context.Context, db *gorm.DB, steps int) ([]Migration, error) {
+	if db == nil {
+		db = GormDB
+	}
+	if steps <= 0 {
+		return nil, fmt.Errorf("%w: steps must be positive", ErrInvalidMigration)
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	err = ensureMigrationTable(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockKey).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Exec(migration.Down).Error
+			if err != nil {
+				return err
+			}
+			return tx.Where("version = ?", migration.Version).Delete(&TSchemaMigration{}).Error
+		})
+		if err != nil {
+			e := fmt.Errorf("failed to roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+			logger.Error(e.Error())
+			return done, e
+		}
+		logger.Info("migration rolled back", zap.Int64("version", migration.Version), zap.String("name", migration.Name))
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// QueryMigrationStatus 查询所有迁移版本的执行状态，按版本号升序返回
+func QueryMigrationStatus(ctx context.Context, db *gorm.DB) ([]MigrationStatus, error) {
+	if db == nil {
+		db = GormDB
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	err = ensureMigrationTable(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		result = append(result, status)
+	}
+	for _, record := range applied {
+		result = append(result, MigrationStatus{
+			Version:   record.Version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: record.AppliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// VerifySchema 检查数据库结构是否已迁移到当前程序需要的版本，不修改数据库
+// 存在未执行的迁移时返回 ErrSchemaOutdated；数据库版本高于程序版本时仅记录警告，便于滚动发布时旧版本实例继续运行
+func VerifySchema(ctx context.Context, db *gorm.DB) error {
+	if db == nil {
+		db = GormDB
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return err
+	}
+
+	if !db.Migrator().HasTable(&TSchemaMigration{}) {
+		return fmt.Errorf("%w: schema_migrations not found, %d migrations pending", ErrSchemaOutdated, len(migrations))
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	var pending []int64
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration.Version)
+		}
+		delete(applied, migration.Version)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: pending migrations %v", ErrSchemaOutdated, pending)
+	}
+	if len(applied) > 0 {
+		logger.Warn("database has migrations unknown to this build, it may have been migrated by a newer version",
+			zap.Int("unknownCount", len(applied)), zap.Int("latest", len(migrations)))
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package cmn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("LoadMigrations failed: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d has version %d, want %d", i, m.Version, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s has empty up or down sql", m.Version, m.Name)
+		}
+	}
+}
+
+// 基线迁移需要能在 AutoMigrate 创建的已有数据库上直接执行
+func TestBaselineMigrationIsIdempotent(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("LoadMigrations failed: %v", err)
+	}
+
+	for _, line := range strings.Split(migrations[0].Up, "\n") {
+		if strings.HasPrefix(line, "CREATE ") && !strings.Contains(line, "IF NOT EXISTS") {
+			t.Errorf("baseline statement is not idempotent: %s", line)
+		}
+	}
+}
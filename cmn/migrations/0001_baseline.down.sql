@@ -0,0 +1,24 @@
+-- 删除全部业务表，会丢失所有数据
+
+DROP TABLE IF EXISTS "t_scheduler_job";
+DROP TABLE IF EXISTS "t_job_lease";
+DROP TABLE IF EXISTS "t_job_run_item";
+DROP TABLE IF EXISTS "t_job_run";
+DROP TABLE IF EXISTS "t_admin_user";
+DROP TABLE IF EXISTS "t_user_check_in";
+DROP TABLE IF EXISTS "t_user_fortune";
+DROP TABLE IF EXISTS "t_user_asset";
+DROP TABLE IF EXISTS "t_meta_asset";
+DROP TABLE IF EXISTS "t_raffle_designated_user";
+DROP TABLE IF EXISTS "t_raffle_prize";
+DROP TABLE IF EXISTS "t_raffle_log";
+DROP TABLE IF EXISTS "t_raffle_winner";
+DROP TABLE IF EXISTS "t_sms_code";
+DROP TABLE IF EXISTS "t_points_ledger";
+DROP TABLE IF EXISTS "t_points_lot";
+DROP TABLE IF EXISTS "t_user_points_balance";
+DROP TABLE IF EXISTS "t_points_type";
+DROP TABLE IF EXISTS "t_user_points";
+DROP TABLE IF EXISTS "t_user_external";
+DROP TABLE IF EXISTS "t_user";
+DROP TABLE IF EXISTS "t_cfg_common";
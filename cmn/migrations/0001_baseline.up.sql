@@ -0,0 +1,308 @@
+-- 基线结构：与引入版本化迁移前 AutoMigrate 创建的表结构一致
+-- 已有数据库中的表与索引已存在，IF NOT EXISTS 保证可直接执行
+
+CREATE TABLE IF NOT EXISTS "t_cfg_common" (
+    "id" bigserial,
+    "key" varchar(50) NOT NULL,
+    "value" text,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_t_cfg_common_key" UNIQUE ("key")
+);
+CREATE INDEX IF NOT EXISTS "idx_t_cfg_common_key" ON "t_cfg_common" ("key");
+
+CREATE TABLE IF NOT EXISTS "t_user" (
+    "id" uuid NOT NULL,
+    "official_name" varchar(50),
+    "nick_name" varchar(50),
+    "email" varchar(30),
+    "mobile_phone" varchar(11),
+    "login_time" bigint,
+    "created_at" bigint,
+    "updated_at" bigint,
+    "status" varchar(2) DEFAULT '00',
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_t_user_id" UNIQUE ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_t_user_status" ON "t_user" ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_t_user_mobile_phone" ON "t_user" ("mobile_phone");
+CREATE INDEX IF NOT EXISTS "idx_t_user_id" ON "t_user" ("id");
+
+CREATE TABLE IF NOT EXISTS "t_user_external" (
+    "id" bigserial,
+    "user_id" uuid NOT NULL,
+    "platform" varchar(30) NOT NULL,
+    "access_token" text,
+    "refresh_token" text,
+    "token_expire_time" bigint,
+    "open_id" text,
+    "nick_name" text,
+    "avatar" text,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_t_user_external_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_t_user_external_open_id" ON "t_user_external" ("open_id");
+CREATE INDEX IF NOT EXISTS "idx_t_user_external_platform" ON "t_user_external" ("platform");
+CREATE INDEX IF NOT EXISTS "idx_t_user_external_user_id" ON "t_user_external" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "t_user_points" (
+    "id" bigserial,
+    "user_id" uuid NOT NULL,
+    "default_points" decimal,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_t_user_points_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT "uni_t_user_points_user_id" UNIQUE ("user_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_t_user_points_user_id" ON "t_user_points" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "t_points_type" (
+    "id" bigserial,
+    "code" varchar(30) NOT NULL,
+    "name" varchar(50) NOT NULL,
+    "description" text,
+    "expire_days" bigint NOT NULL DEFAULT 0,
+    "status" varchar(2) DEFAULT '00',
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_t_points_type_status" ON "t_points_type" ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_t_points_type_code" ON "t_points_type" ("code");
+
+CREATE TABLE IF NOT EXISTS "t_user_points_balance" (
+    "id" bigserial,
+    "user_id" uuid NOT NULL,
+    "points_type" varchar(30) NOT NULL,
+    "balance" double precision NOT NULL DEFAULT 0,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_t_user_points_balance_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_t_user_points_balance_points_type" ON "t_user_points_balance" ("points_type");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_points_balance_user_type" ON "t_user_points_balance" ("user_id","points_type");
+
+CREATE TABLE IF NOT EXISTS "t_points_lot" (
+    "id" bigserial,
+    "user_id" uuid NOT NULL,
+    "points_type" varchar(30) NOT NULL,
+    "amount" double precision NOT NULL,
+    "remaining" double precision NOT NULL,
+    "reason" varchar(30) NOT NULL,
+    "ref_id" varchar(64),
+    "expire_at" bigint NOT NULL,
+    "status" varchar(2) DEFAULT '00',
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_t_points_lot_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_t_points_lot_status" ON "t_points_lot" ("status");
+CREATE INDEX IF NOT EXISTS "idx_t_points_lot_expire_at" ON "t_points_lot" ("expire_at");
+CREATE INDEX IF NOT EXISTS "idx_points_lot_user_type" ON "t_points_lot" ("user_id","points_type");
+
+CREATE TABLE IF NOT EXISTS "t_points_ledger" (
+    "id" bigserial,
+    "user_id" uuid NOT NULL,
+    "points_type" varchar(30) NOT NULL DEFAULT 'default',
+    "delta" double precision NOT NULL,
+    "reason" varchar(30) NOT NULL,
+    "ref_id" varchar(64),
+    "balance_after" double precision,
+    "created_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_t_points_ledger_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_t_points_ledger_reason" ON "t_points_ledger" ("reason");
+CREATE INDEX IF NOT EXISTS "idx_t_points_ledger_points_type" ON "t_points_ledger" ("points_type");
+CREATE INDEX IF NOT EXISTS "idx_points_ledger_user_created" ON "t_points_ledger" ("user_id","created_at");
+
+CREATE TABLE IF NOT EXISTS "t_sms_code" (
+    "id" bigserial,
+    "mobile_phone" varchar(11) NOT NULL,
+    "code" varchar(10) NOT NULL,
+    "expires_at" bigint NOT NULL,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE IF NOT EXISTS "t_raffle_winner" (
+    "id" bigserial,
+    "user_id" uuid NOT NULL,
+    "prize_name" varchar(100) NOT NULL,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_t_raffle_winner_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_t_raffle_winner_prize_name" ON "t_raffle_winner" ("prize_name");
+CREATE INDEX IF NOT EXISTS "idx_t_raffle_winner_user_id" ON "t_raffle_winner" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "t_raffle_log" (
+    "id" bigserial,
+    "user_id" uuid NOT NULL,
+    "count" bigint DEFAULT 0,
+    "prizes" JSONB,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_t_raffle_log_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "t_raffle_prize" (
+    "id" bigserial,
+    "name" varchar(100) NOT NULL,
+    "probability" decimal NOT NULL,
+    "total_count" bigint NOT NULL,
+    "remain_count" bigint NOT NULL,
+    "cost" decimal NOT NULL,
+    "status" varchar(5),
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_t_raffle_prize_name" ON "t_raffle_prize" ("name");
+
+CREATE TABLE IF NOT EXISTS "t_raffle_designated_user" (
+    "id" bigserial,
+    "user_id" uuid NOT NULL,
+    "prize_id" bigint NOT NULL,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_t_raffle_designated_user_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT "fk_t_raffle_designated_user_prize_info" FOREIGN KEY ("prize_id") REFERENCES "t_raffle_prize"("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "t_meta_asset" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "cover_img" text,
+    "external_no" text,
+    "value" decimal,
+    "platform" text,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "uniq_platform_name" ON "t_meta_asset" ("name","platform");
+
+CREATE TABLE IF NOT EXISTS "t_user_asset" (
+    "id" bigserial,
+    "user_id" uuid NOT NULL,
+    "meta_asset_id" bigint NOT NULL,
+    "name" text NOT NULL,
+    "theme_name" text,
+    "external_no" text,
+    "cover_img" text,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_t_user_asset_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE,
+    CONSTRAINT "fk_t_user_asset_meta_asset" FOREIGN KEY ("meta_asset_id") REFERENCES "t_meta_asset"("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_t_user_asset_name" ON "t_user_asset" ("name");
+CREATE INDEX IF NOT EXISTS "idx_t_user_asset_meta_asset_id" ON "t_user_asset" ("meta_asset_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "uniq_user_meta_ext" ON "t_user_asset" ("user_id","meta_asset_id","external_no");
+CREATE INDEX IF NOT EXISTS "idx_t_user_asset_user_id" ON "t_user_asset" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "t_user_fortune" (
+    "user_id" uuid NOT NULL,
+    "name" varchar(50),
+    "gender" varchar(50),
+    "birth" varchar(50),
+    "data" JSONB,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("user_id"),
+    CONSTRAINT "fk_t_user_fortune_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_t_user_fortune_user_id" ON "t_user_fortune" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "t_user_check_in" (
+    "id" bigserial,
+    "user_id" uuid NOT NULL,
+    "points" double precision DEFAULT 0,
+    "created_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_t_user_check_in_user_info" FOREIGN KEY ("user_id") REFERENCES "t_user"("id") ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_t_user_check_in_user_id" ON "t_user_check_in" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "t_admin_user" (
+    "id" uuid NOT NULL,
+    "user_name" varchar(50) NOT NULL,
+    "password_hash" text NOT NULL,
+    "nick_name" varchar(50),
+    "role" varchar(20) NOT NULL,
+    "status" varchar(2) DEFAULT '00',
+    "login_time" bigint,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_t_admin_user_id" UNIQUE ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_t_admin_user_status" ON "t_admin_user" ("status");
+CREATE INDEX IF NOT EXISTS "idx_t_admin_user_role" ON "t_admin_user" ("role");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_t_admin_user_user_name" ON "t_admin_user" ("user_name");
+CREATE INDEX IF NOT EXISTS "idx_t_admin_user_id" ON "t_admin_user" ("id");
+
+CREATE TABLE IF NOT EXISTS "t_job_run" (
+    "id" bigserial,
+    "job_name" varchar(50) NOT NULL,
+    "biz_date" varchar(10) NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "total_count" bigint DEFAULT 0,
+    "success_count" bigint DEFAULT 0,
+    "skip_count" bigint DEFAULT 0,
+    "fail_count" bigint DEFAULT 0,
+    "last_error" text,
+    "started_at" bigint,
+    "finished_at" bigint,
+    "created_at" bigint,
+    "updated_at" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_t_job_run_status" ON "t_job_run" ("status");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_job_run_job_date" ON "t_job_run" ("job_name","biz_date");
+
+CREATE TABLE IF NOT EXISTS "t_job_run_item" (
+    "id" bigserial,
+    "job_name" varchar(50) NOT NULL,
+    "biz_date" varchar(10) NOT NULL,
+    "item_key" varchar(64) NOT NULL,
+    "created_at" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_job_run_item_key" ON "t_job_run_item" ("job_name","biz_date","item_key");
+
+CREATE TABLE IF NOT EXISTS "t_job_lease" (
+    "job_name" varchar(50),
+    "holder" varchar(100),
+    "lease_until" bigint DEFAULT 0,
+    "last_fire_at" bigint DEFAULT 0,
+    "updated_at" bigint,
+    PRIMARY KEY ("job_name")
+);
+
+CREATE TABLE IF NOT EXISTS "t_scheduler_job" (
+    "job_name" varchar(50),
+    "spec" varchar(100),
+    "enable" boolean DEFAULT true,
+    "singleton" boolean DEFAULT true,
+    "paused" boolean DEFAULT false,
+    "last_run_at" bigint DEFAULT 0,
+    "last_duration" bigint DEFAULT 0,
+    "last_status" varchar(20),
+    "last_error" text,
+    "last_run_by" varchar(100),
+    "last_trigger" varchar(20),
+    "run_count" bigint DEFAULT 0,
+    "fail_count" bigint DEFAULT 0,
+    "updated_at" bigint,
+    PRIMARY KEY ("job_name")
+);
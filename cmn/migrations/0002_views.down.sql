@@ -0,0 +1,4 @@
+DROP VIEW IF EXISTS "v_raffle_designated_user_prize_info";
+DROP VIEW IF EXISTS "v_raffle_winner_info";
+DROP VIEW IF EXISTS "v_user_info";
+DROP VIEW IF EXISTS "v_user_asset_meta";
@@ -0,0 +1,90 @@
+-- 查询视图，修改视图定义时新增迁移并使用 CREATE OR REPLACE VIEW 或先删除再创建
+
+CREATE OR REPLACE VIEW "v_user_asset_meta" AS
+SELECT
+    ua.id,
+    ua.user_id,
+    u.mobile_phone,
+    u.email,
+    u.nick_name,
+    ua.meta_asset_id,
+    ma.name AS meta_asset_name,
+    ma.value AS meta_asset_value,
+    ma.cover_img AS meta_cover_img,
+    ua.name,
+    ua.theme_name,
+    ua.external_no,
+    ua.cover_img,
+    ua.created_at,
+    ua.updated_at
+FROM t_user_asset AS ua
+    LEFT JOIN t_meta_asset AS ma ON ua.meta_asset_id = ma.id
+    LEFT JOIN t_user AS u ON ua.user_id = u.id;
+
+CREATE OR REPLACE VIEW "v_user_info" AS
+SELECT
+    u.id,
+    u.official_name,
+    u.nick_name,
+    u.email,
+    u.mobile_phone,
+    u.login_time,
+    u.created_at,
+    u.updated_at,
+    u.status,
+    ue.platform AS external_platform,
+    ue.nick_name AS external_nick_name,
+    ue.avatar AS external_avatar,
+    COALESCE(up.balance, 0) AS default_points,
+    COALESCE(ua_count.asset_count, 0) AS asset_count,
+    COALESCE(rw_count.raffle_prize_count, 0) AS raffle_prize_count
+FROM t_user AS u
+    LEFT JOIN t_user_external AS ue ON u.id = ue.user_id
+    LEFT JOIN t_user_points_balance AS up ON u.id = up.user_id AND up.points_type = 'default'
+    LEFT JOIN (SELECT user_id, COUNT(*) as asset_count FROM t_user_asset GROUP BY user_id) AS ua_count ON u.id = ua_count.user_id
+    LEFT JOIN (SELECT user_id, COUNT(*) as raffle_prize_count FROM t_raffle_winner GROUP BY user_id) AS rw_count ON u.id = rw_count.user_id;
+
+CREATE OR REPLACE VIEW "v_raffle_winner_info" AS
+SELECT
+    rw.user_id,
+    rw.prize_name,
+    rw.created_at,
+    rw.updated_at,
+    u.official_name,
+    u.nick_name,
+    u.email,
+    u.mobile_phone,
+    u.login_time,
+    u.status,
+    ue.platform AS external_platform,
+    ue.nick_name AS external_nick_name,
+    ue.avatar AS external_avatar,
+    COALESCE(up.balance, 0) AS default_points
+FROM t_raffle_winner AS rw
+    LEFT JOIN t_user AS u ON rw.user_id = u.id
+    LEFT JOIN t_user_external AS ue ON u.id = ue.user_id
+    LEFT JOIN t_user_points_balance AS up ON u.id = up.user_id AND up.points_type = 'default';
+
+CREATE OR REPLACE VIEW "v_raffle_designated_user_prize_info" AS
+SELECT
+    rdu.id,
+    rdu.user_id,
+    rdu.prize_id,
+    rdu.created_at,
+    rdu.updated_at,
+    rp.name AS prize_name,
+    rp.probability AS prize_probability,
+    rp.total_count AS prize_total_count,
+    rp.remain_count AS prize_remain_count,
+    rp.cost AS prize_cost,
+    rp.status AS prize_status,
+    rp.created_at AS prize_created_at,
+    rp.updated_at AS prize_updated_at,
+    u.official_name AS user_official_name,
+    u.nick_name AS user_nick_name,
+    u.email AS user_email,
+    u.mobile_phone AS user_mobile_phone,
+    u.login_time AS user_login_time
+FROM t_raffle_designated_user AS rdu
+    LEFT JOIN t_raffle_prize AS rp ON rdu.prize_id = rp.id
+    LEFT JOIN t_user AS u ON rdu.user_id = u.id;
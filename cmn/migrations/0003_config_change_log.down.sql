@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "t_config_change_log";
@@ -0,0 +1,12 @@
+-- 配置热更新审计记录
+CREATE TABLE "t_config_change_log" (
+    "id" bigserial,
+    "source" varchar(20) NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "changes" jsonb,
+    "error" text,
+    "created_at" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_t_config_change_log_status" ON "t_config_change_log" ("status");
+CREATE INDEX "idx_t_config_change_log_created_at" ON "t_config_change_log" ("created_at");
@@ -0,0 +1,21 @@
+-- 管理操作审计记录
+CREATE TABLE "t_admin_audit_log" (
+    "id" bigserial,
+    "actor_id" uuid,
+    "actor_name" varchar(50),
+    "action" varchar(50) NOT NULL,
+    "target" varchar(200),
+    "before" jsonb,
+    "after" jsonb,
+    "diff" jsonb,
+    "status" integer NOT NULL,
+    "msg" text,
+    "ip" varchar(64),
+    "request_id" varchar(64),
+    "created_at" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_t_admin_audit_log_actor_id" ON "t_admin_audit_log" ("actor_id");
+CREATE INDEX "idx_t_admin_audit_log_action" ON "t_admin_audit_log" ("action");
+CREATE INDEX "idx_t_admin_audit_log_target" ON "t_admin_audit_log" ("target");
+CREATE INDEX "idx_t_admin_audit_log_created_at" ON "t_admin_audit_log" ("created_at");
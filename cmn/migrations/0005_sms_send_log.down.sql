@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "t_sms_send_log";
@@ -0,0 +1,19 @@
+-- 短信发送限流决策记录
+CREATE TABLE "t_sms_send_log" (
+    "id" bigserial,
+    "mobile_phone" varchar(11) NOT NULL,
+    "ip" varchar(64),
+    "provider" varchar(20),
+    "decision" varchar(20) NOT NULL,
+    "rule" varchar(50),
+    "retry_after" bigint,
+    "result" varchar(20),
+    "error" text,
+    "created_at" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_t_sms_send_log_phone_created_at" ON "t_sms_send_log" ("mobile_phone", "created_at");
+CREATE INDEX "idx_t_sms_send_log_ip_created_at" ON "t_sms_send_log" ("ip", "created_at");
+CREATE INDEX "idx_t_sms_send_log_provider_created_at" ON "t_sms_send_log" ("provider", "created_at");
+CREATE INDEX "idx_t_sms_send_log_decision" ON "t_sms_send_log" ("decision");
+CREATE INDEX "idx_t_sms_send_log_created_at" ON "t_sms_send_log" ("created_at");
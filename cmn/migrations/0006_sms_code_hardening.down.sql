@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "idx_t_sms_code_expires_at";
+DROP INDEX IF EXISTS "idx_t_sms_code_phone_purpose_status";
+ALTER TABLE "t_sms_code" DROP COLUMN IF EXISTS "status";
+ALTER TABLE "t_sms_code" DROP COLUMN IF EXISTS "attempts";
+ALTER TABLE "t_sms_code" DROP COLUMN IF EXISTS "purpose";
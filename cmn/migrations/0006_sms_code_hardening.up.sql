@@ -0,0 +1,6 @@
+-- 验证码用途、错误次数与状态，旧验证码视为登录验证码
+ALTER TABLE "t_sms_code" ADD COLUMN "purpose" varchar(20) NOT NULL DEFAULT 'login';
+ALTER TABLE "t_sms_code" ADD COLUMN "attempts" integer NOT NULL DEFAULT 0;
+ALTER TABLE "t_sms_code" ADD COLUMN "status" varchar(2) NOT NULL DEFAULT '00';
+CREATE INDEX "idx_t_sms_code_phone_purpose_status" ON "t_sms_code" ("mobile_phone", "purpose", "status");
+CREATE INDEX "idx_t_sms_code_expires_at" ON "t_sms_code" ("expires_at");
@@ -0,0 +1,14 @@
+-- 图形验证挑战，答案仅保存在服务端，验证通过后签发一次性票据
+CREATE TABLE "t_captcha" (
+    "id" varchar(64),
+    "answer" varchar(20) NOT NULL,
+    "status" varchar(2) NOT NULL DEFAULT '00',
+    "ticket" varchar(64),
+    "ip" varchar(64),
+    "expires_at" bigint NOT NULL,
+    "ticket_expires_at" bigint,
+    "created_at" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_t_captcha_ticket" ON "t_captcha" ("ticket");
+CREATE INDEX "idx_t_captcha_expires_at" ON "t_captcha" ("expires_at");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "t_admin_login_failure";
+ALTER TABLE "t_admin_user" DROP COLUMN IF EXISTS "must_change_password";
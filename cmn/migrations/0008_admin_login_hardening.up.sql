@@ -0,0 +1,14 @@
+-- 初始超级管理员首次登录后须修改密码
+ALTER TABLE "t_admin_user" ADD COLUMN "must_change_password" boolean NOT NULL DEFAULT false;
+
+-- 管理员登录失败记录，按用户名与IP限制暴力破解
+CREATE TABLE "t_admin_login_failure" (
+    "id" bigserial,
+    "user_name" varchar(50) NOT NULL,
+    "ip" varchar(64),
+    "created_at" bigint,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_t_admin_login_failure_user_name_created_at" ON "t_admin_login_failure" ("user_name", "created_at");
+CREATE INDEX "idx_t_admin_login_failure_ip_created_at" ON "t_admin_login_failure" ("ip", "created_at");
+CREATE INDEX "idx_t_admin_login_failure_created_at" ON "t_admin_login_failure" ("created_at");
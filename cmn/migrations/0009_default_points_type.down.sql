@@ -0,0 +1,5 @@
+-- 迁移后的积分余额可能已发生变动，回滚时保留；默认积分类型仅在未被使用时删除
+DELETE FROM "t_points_type" WHERE "code" = 'default'
+    AND NOT EXISTS (SELECT 1 FROM "t_user_points_balance" WHERE "points_type" = 'default')
+    AND NOT EXISTS (SELECT 1 FROM "t_points_lot" WHERE "points_type" = 'default')
+    AND NOT EXISTS (SELECT 1 FROM "t_points_ledger" WHERE "points_type" = 'default');
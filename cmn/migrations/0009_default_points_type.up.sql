@@ -0,0 +1,9 @@
+-- 内置的默认积分类型，已存在时不做修改
+INSERT INTO "t_points_type" ("code", "name", "description", "expire_days", "status", "created_at", "updated_at")
+VALUES ('default', '默认积分', '系统内置积分类型', 0, '00', (EXTRACT(EPOCH FROM now()) * 1000)::bigint, (EXTRACT(EPOCH FROM now()) * 1000)::bigint)
+ON CONFLICT ("code") DO NOTHING;
+
+-- 将 t_user_points 中的默认积分迁移到 t_user_points_balance，已存在余额记录的用户不会被覆盖
+INSERT INTO "t_user_points_balance" ("user_id", "points_type", "balance", "created_at", "updated_at")
+SELECT "user_id", 'default', COALESCE("default_points", 0), "created_at", "updated_at" FROM "t_user_points"
+ON CONFLICT ("user_id", "points_type") DO NOTHING;
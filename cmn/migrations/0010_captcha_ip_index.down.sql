@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "idx_t_captcha_ip_created_at";
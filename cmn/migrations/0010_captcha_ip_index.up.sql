@@ -0,0 +1,2 @@
+-- 按IP统计窗口期内获取图形验证挑战的次数
+CREATE INDEX "idx_t_captcha_ip_created_at" ON "t_captcha" ("ip", "created_at");
@@ -0,0 +1 @@
+ALTER TABLE "t_captcha" DROP COLUMN IF EXISTS "ticket_ip";
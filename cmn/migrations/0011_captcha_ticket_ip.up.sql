@@ -0,0 +1,2 @@
+-- 票据与验证通过的客户端IP绑定
+ALTER TABLE "t_captcha" ADD COLUMN "ticket_ip" varchar(64);
@@ -9,8 +9,14 @@ const (
 	TUserName         = "t_user"          // 用户信息表
 	TUserExternalName = "t_user_external" // 用户外部信息表
 	TSmsCodesName     = "t_sms_code"      // 短信验证码表
+	TSmsSendLogName   = "t_sms_send_log"  // 短信发送限流决策表
+	TCaptchaName      = "t_captcha"       // 图形验证挑战表
 
-	TUserPointsName = "t_user_points" // 用户积分表
+	TUserPointsName        = "t_user_points"         // 用户积分表（已废弃，仅保留历史默认积分）
+	TPointsTypeName        = "t_points_type"         // 积分类型表
+	TUserPointsBalanceName = "t_user_points_balance" // 用户分类积分余额表
+	TPointsLotName         = "t_points_lot"          // 积分批次表
+	TPointsLedgerName      = "t_points_ledger"       // 积分流水表
 
 	TRaffleWinnersName        = "t_raffle_winner"          // 抽奖获奖者表
 	TRaffleDesignatedUserName = "t_raffle_designated_user" // 抽奖指定获奖者表
@@ -26,6 +32,18 @@ const (
 	TUserFortuneName = "t_user_fortune"  // 用户运势表
 	TUserCheckInName = "t_user_check_in" // 用户签到表
 
+	TAdminUserName         = "t_admin_user"          // 管理员表
+	TAdminLoginFailureName = "t_admin_login_failure" // 管理员登录失败记录表
+
+	TJobRunName       = "t_job_run"       // 定时任务运行记录表
+	TJobRunItemName   = "t_job_run_item"  // 定时任务处理明细表
+	TJobLeaseName     = "t_job_lease"     // 定时任务租约表
+	TSchedulerJobName = "t_scheduler_job" // 定时任务状态表
+
+	TSchemaMigrationName = "schema_migrations"   // 数据库迁移记录表
+	TConfigChangeLogName = "t_config_change_log" // 配置变更审计表
+	TAdminAuditLogName   = "t_admin_audit_log"   // 管理操作审计表
+
 	VUserAssetMetaName                 = "v_user_asset_meta"                   // 用户资产视图
 	VUserInfoName                      = "v_user_info"                         // 用户信息视图
 	VRaffleWinnerInfoName              = "v_raffle_winner_info"                // 抽奖获奖者信息视图
@@ -134,18 +152,56 @@ func (TRaffleDesignatedUser) TableName() string {
 
 // TSmsCodes 短信验证码表
 type TSmsCodes struct {
-	Id          int64  `gorm:"column:id;type:bigint;primaryKey;autoIncrement"`     // ID
-	MobilePhone string `gorm:"column:mobile_phone;type:varchar(11);not null"`      // 手机号
-	Code        string `gorm:"column:code;type:varchar(10);not null"`              // 验证码
-	ExpiresAt   int64  `gorm:"column:expires_at;type:bigint;not null"`             // 验证码过期时间
-	CreatedAt   int64  `gorm:"column:created_at;type:bigint;autoCreateTime:milli"` // 创建时间
-	UpdatedAt   int64  `gorm:"column:updated_at;type:bigint;autoUpdateTime:milli"` // 更新时间
+	Id          int64  `gorm:"column:id;type:bigint;primaryKey;autoIncrement"`           // ID
+	MobilePhone string `gorm:"column:mobile_phone;type:varchar(11);not null"`            // 手机号
+	Code        string `gorm:"column:code;type:varchar(10);not null"`                    // 验证码
+	Purpose     string `gorm:"column:purpose;type:varchar(20);not null;default:'login'"` // 用途 login:登录 bind_phone:绑定手机号 change_phone:更换手机号
+	Attempts    int    `gorm:"column:attempts;type:integer;not null;default:0"`          // 验证失败次数
+	Status      string `gorm:"column:status;type:varchar(2);not null;default:'00'"`      // 状态 00:有效 01:已使用 02:已被新验证码取代 03:错误次数过多已锁定 04:发送失败
+	ExpiresAt   int64  `gorm:"column:expires_at;type:bigint;not null"`                   // 验证码过期时间
+	CreatedAt   int64  `gorm:"column:created_at;type:bigint;autoCreateTime:milli"`       // 创建时间
+	UpdatedAt   int64  `gorm:"column:updated_at;type:bigint;autoUpdateTime:milli"`       // 更新时间
 }
 
 func (TSmsCodes) TableName() string {
 	return TSmsCodesName
 }
 
+// TSmsSendLog 短信发送限流决策表，记录每次发送请求是否放行以及发送结果
+type TSmsSendLog struct {
+	Id          int64  `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`                  // ID
+	MobilePhone string `json:"mobilePhone" gorm:"column:mobile_phone;type:varchar(11);not null"`          // 手机号
+	Ip          string `json:"ip" gorm:"column:ip;type:varchar(64)"`                                      // 客户端IP
+	Provider    string `json:"provider" gorm:"column:provider;type:varchar(20)"`                          // 短信服务商
+	Decision    string `json:"decision" gorm:"column:decision;type:varchar(20);not null;index"`           // 决策 allowed:放行 blocked:拒绝 aborted:放行后未发送
+	Rule        string `json:"rule" gorm:"column:rule;type:varchar(50)"`                                  // 触发拒绝的规则，如 phone_per_minute
+	RetryAfter  int64  `json:"retryAfter" gorm:"column:retry_after;type:bigint"`                          // 拒绝时距可再次发送的秒数
+	Result      string `json:"result" gorm:"column:result;type:varchar(20)"`                              // 放行后的发送结果 sent:成功 failed:失败
+	Error       string `json:"error" gorm:"column:error;type:text"`                                       // 发送失败原因
+	CreatedAt   int64  `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli;index"` // 创建时间
+}
+
+func (TSmsSendLog) TableName() string {
+	return TSmsSendLogName
+}
+
+// TCaptcha 图形验证挑战表，答案仅保存在服务端，验证通过后签发一次性票据
+type TCaptcha struct {
+	Id              string `json:"id" gorm:"column:id;type:varchar(64);primaryKey"`                     // 挑战ID
+	Answer          string `json:"-" gorm:"column:answer;type:varchar(20);not null"`                    // 答案
+	Status          string `json:"status" gorm:"column:status;type:varchar(2);not null;default:'00'"`   // 状态 00:待验证 01:已通过 02:票据已使用 03:验证失败
+	Ticket          string `json:"-" gorm:"column:ticket;type:varchar(64);uniqueIndex;default:null"`    // 验证通过后签发的票据
+	Ip              string `json:"ip" gorm:"column:ip;type:varchar(64)"`                                // 请求挑战的客户端IP
+	ExpiresAt       int64  `json:"expiresAt" gorm:"column:expires_at;type:bigint;not null;index"`       // 挑战过期时间
+	TicketExpiresAt int64  `json:"ticketExpiresAt" gorm:"column:ticket_expires_at;type:bigint"`         // 票据过期时间
+	TicketIp        string `json:"ticketIp" gorm:"column:ticket_ip;type:varchar(64)"`                   // 验证通过的客户端IP，票据只能由该IP使用
+	CreatedAt       int64  `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli"` // 创建时间
+}
+
+func (TCaptcha) TableName() string {
+	return TCaptchaName
+}
+
 // TMetaAsset 元资产表
 type TMetaAsset struct {
 	Id         int64   `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`                  // 元资产ID
@@ -183,6 +239,7 @@ func (TUserAsset) TableName() string {
 }
 
 // TUserPoints 用户积分表
+// 已废弃：积分余额迁移至 TUserPointsBalance，该表仅用于启动时迁移历史默认积分
 type TUserPoints struct {
 	Id            int64     `gorm:"column:id;type:bigint;primaryKey;autoIncrement"`     // ID
 	UserId        uuid.UUID `gorm:"column:user_id;type:uuid;not null;unique;index"`     // 用户ID
@@ -197,6 +254,78 @@ func (TUserPoints) TableName() string {
 	return TUserPointsName
 }
 
+// TPointsType 积分类型表
+type TPointsType struct {
+	Id          int64  `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`            // ID
+	Code        string `json:"code" gorm:"column:code;type:varchar(30);not null;uniqueIndex"`       // 积分类型编码，如 default、activity
+	Name        string `json:"name" gorm:"column:name;type:varchar(50);not null"`                   // 积分类型名称
+	Description string `json:"description" gorm:"column:description;type:text"`                     // 描述
+	ExpireDays  int    `json:"expireDays" gorm:"column:expire_days;type:int;not null;default:0"`    // 积分有效天数，0表示永不过期
+	Status      string `json:"status" gorm:"column:status;type:varchar(2);default:'00';index"`      // 状态 00:启用 01:停用
+	CreatedAt   int64  `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli"` // 创建时间
+	UpdatedAt   int64  `json:"updatedAt" gorm:"column:updated_at;type:bigint;autoUpdateTime:milli"` // 更新时间
+}
+
+func (TPointsType) TableName() string {
+	return TPointsTypeName
+}
+
+// TUserPointsBalance 用户分类积分余额表，每个用户每种积分类型一条记录
+type TUserPointsBalance struct {
+	Id         int64     `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`                                                                      // ID
+	UserId     uuid.UUID `json:"userId" gorm:"column:user_id;type:uuid;not null;uniqueIndex:idx_user_points_balance_user_type,priority:1"`                      // 用户ID
+	PointsType string    `json:"pointsType" gorm:"column:points_type;type:varchar(30);not null;uniqueIndex:idx_user_points_balance_user_type,priority:2;index"` // 积分类型编码
+	Balance    float64   `json:"balance" gorm:"column:balance;type:double precision;not null;default:0"`                                                        // 积分余额
+	CreatedAt  int64     `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli"`                                                           // 创建时间
+	UpdatedAt  int64     `json:"updatedAt" gorm:"column:updated_at;type:bigint;autoUpdateTime:milli"`                                                           // 更新时间
+
+	UserInfo TUser `json:"-" gorm:"foreignKey:UserId;references:Id;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+func (TUserPointsBalance) TableName() string {
+	return TUserPointsBalanceName
+}
+
+// TPointsLot 积分批次表
+// 仅为会过期的积分类型记录批次，扣减时按过期时间先后消耗，到期未消耗的部分由定时任务清零
+type TPointsLot struct {
+	Id         int64     `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`                                                 // ID
+	UserId     uuid.UUID `json:"userId" gorm:"column:user_id;type:uuid;not null;index:idx_points_lot_user_type,priority:1"`                // 用户ID
+	PointsType string    `json:"pointsType" gorm:"column:points_type;type:varchar(30);not null;index:idx_points_lot_user_type,priority:2"` // 积分类型编码
+	Amount     float64   `json:"amount" gorm:"column:amount;type:double precision;not null"`                                               // 批次原始积分
+	Remaining  float64   `json:"remaining" gorm:"column:remaining;type:double precision;not null"`                                         // 批次剩余积分
+	Reason     string    `json:"reason" gorm:"column:reason;type:varchar(30);not null"`                                                    // 获得原因
+	RefId      string    `json:"refId" gorm:"column:ref_id;type:varchar(64)"`                                                              // 关联业务ID
+	ExpireAt   int64     `json:"expireAt" gorm:"column:expire_at;type:bigint;not null;index"`                                              // 过期时间
+	Status     string    `json:"status" gorm:"column:status;type:varchar(2);default:'00';index"`                                           // 状态 00:有效 01:已过期
+	CreatedAt  int64     `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli"`                                      // 创建时间
+	UpdatedAt  int64     `json:"updatedAt" gorm:"column:updated_at;type:bigint;autoUpdateTime:milli"`                                      // 更新时间
+
+	UserInfo TUser `json:"-" gorm:"foreignKey:UserId;references:Id;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+func (TPointsLot) TableName() string {
+	return TPointsLotName
+}
+
+// TPointsLedger 积分流水表（只追加，不修改）
+type TPointsLedger struct {
+	Id           int64     `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`                                                            // ID
+	UserId       uuid.UUID `json:"userId" gorm:"column:user_id;type:uuid;not null;index:idx_points_ledger_user_created,priority:1"`                     // 用户ID
+	PointsType   string    `json:"pointsType" gorm:"column:points_type;type:varchar(30);not null;default:'default';index"`                              // 积分类型编码
+	Delta        float64   `json:"delta" gorm:"column:delta;type:double precision;not null"`                                                            // 积分变动值，正数为增加，负数为扣减
+	Reason       string    `json:"reason" gorm:"column:reason;type:varchar(30);not null;index"`                                                         // 变动原因
+	RefId        string    `json:"refId" gorm:"column:ref_id;type:varchar(64)"`                                                                         // 关联业务ID
+	BalanceAfter float64   `json:"balanceAfter" gorm:"column:balance_after;type:double precision"`                                                      // 变动后余额
+	CreatedAt    int64     `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli;index:idx_points_ledger_user_created,priority:2"` // 创建时间
+
+	UserInfo TUser `json:"-" gorm:"foreignKey:UserId;references:Id;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+func (TPointsLedger) TableName() string {
+	return TPointsLedgerName
+}
+
 // TUserFortune 用户运势表
 type TUserFortune struct {
 	UserId    uuid.UUID      `json:"userId" gorm:"column:user_id;type:uuid;primaryKey;not null;index"`    // 用户ID
@@ -228,6 +357,105 @@ func (TUserCheckIn) TableName() string {
 	return TUserCheckInName
 }
 
+// TAdminUser 管理员表
+type TAdminUser struct {
+	Id                 uuid.UUID `json:"id" gorm:"column:id;type:uuid;primaryKey;not null;unique;index"`                            // 管理员ID
+	UserName           string    `json:"userName" gorm:"column:user_name;type:varchar(50);not null;uniqueIndex"`                    // 登录用户名
+	PasswordHash       string    `json:"-" gorm:"column:password_hash;type:text;not null"`                                          // 密码哈希
+	NickName           string    `json:"nickName" gorm:"column:nick_name;type:varchar(50)"`                                         // 昵称
+	Role               string    `json:"role" gorm:"column:role;type:varchar(20);not null;index"`                                   // 角色 superadmin/operator/auditor
+	Status             string    `json:"status" gorm:"column:status;type:varchar(2);default:'00';index"`                            // 状态 00:启用 01:禁用
+	LoginTime          int64     `json:"loginTime" gorm:"column:login_time;type:bigint"`                                            // 最近登录时间
+	MustChangePassword bool      `json:"mustChangePassword" gorm:"column:must_change_password;type:boolean;not null;default:false"` // 是否须先修改密码才能访问其它管理接口
+	CreatedAt          int64     `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli"`                       // 创建时间
+	UpdatedAt          int64     `json:"updatedAt" gorm:"column:updated_at;type:bigint;autoUpdateTime:milli"`                       // 更新时间
+}
+
+func (TAdminUser) TableName() string {
+	return TAdminUserName
+}
+
+// TAdminLoginFailure 管理员登录失败记录表，用于限制暴力破解
+type TAdminLoginFailure struct {
+	Id        int64  `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`                  // ID
+	UserName  string `json:"userName" gorm:"column:user_name;type:varchar(50);not null"`                // 登录用户名
+	Ip        string `json:"ip" gorm:"column:ip;type:varchar(64)"`                                      // 客户端IP
+	CreatedAt int64  `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli;index"` // 创建时间
+}
+
+func (TAdminLoginFailure) TableName() string {
+	return TAdminLoginFailureName
+}
+
+// TJobRun 定时任务运行记录表，每个任务每个业务日期一条记录
+type TJobRun struct {
+	Id           int64  `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`                                             // ID
+	JobName      string `json:"jobName" gorm:"column:job_name;type:varchar(50);not null;uniqueIndex:idx_job_run_job_date,priority:1"` // 任务名称
+	BizDate      string `json:"bizDate" gorm:"column:biz_date;type:varchar(10);not null;uniqueIndex:idx_job_run_job_date,priority:2"` // 业务日期 yyyy-mm-dd
+	Status       string `json:"status" gorm:"column:status;type:varchar(20);not null;index"`                                          // 运行状态 running/succeeded/failed
+	TotalCount   int64  `json:"totalCount" gorm:"column:total_count;type:bigint;default:0"`                                           // 待处理数量
+	SuccessCount int64  `json:"successCount" gorm:"column:success_count;type:bigint;default:0"`                                       // 本次运行处理成功数量
+	SkipCount    int64  `json:"skipCount" gorm:"column:skip_count;type:bigint;default:0"`                                             // 本次运行跳过的已处理数量
+	FailCount    int64  `json:"failCount" gorm:"column:fail_count;type:bigint;default:0"`                                             // 本次运行处理失败数量
+	LastError    string `json:"lastError" gorm:"column:last_error;type:text"`                                                         // 最近一次错误
+	StartedAt    int64  `json:"startedAt" gorm:"column:started_at;type:bigint"`                                                       // 最近一次开始时间
+	FinishedAt   int64  `json:"finishedAt" gorm:"column:finished_at;type:bigint"`                                                     // 最近一次结束时间
+	CreatedAt    int64  `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli"`                                  // 创建时间
+	UpdatedAt    int64  `json:"updatedAt" gorm:"column:updated_at;type:bigint;autoUpdateTime:milli"`                                  // 更新时间，运行中作为心跳
+}
+
+func (TJobRun) TableName() string {
+	return TJobRunName
+}
+
+// TJobRunItem 定时任务处理明细表，用于保证同一业务日期内每个处理对象只处理一次
+type TJobRunItem struct {
+	Id        int64  `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`                                             // ID
+	JobName   string `json:"jobName" gorm:"column:job_name;type:varchar(50);not null;uniqueIndex:idx_job_run_item_key,priority:1"` // 任务名称
+	BizDate   string `json:"bizDate" gorm:"column:biz_date;type:varchar(10);not null;uniqueIndex:idx_job_run_item_key,priority:2"` // 业务日期 yyyy-mm-dd
+	ItemKey   string `json:"itemKey" gorm:"column:item_key;type:varchar(64);not null;uniqueIndex:idx_job_run_item_key,priority:3"` // 处理对象标识，如用户ID
+	CreatedAt int64  `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli"`                                  // 创建时间
+}
+
+func (TJobRunItem) TableName() string {
+	return TJobRunItemName
+}
+
+// TJobLease 定时任务租约表，用于多实例部署时保证同一任务的每次调度只由一个实例执行
+type TJobLease struct {
+	JobName    string `json:"jobName" gorm:"column:job_name;type:varchar(50);primaryKey"`          // 任务名称
+	Holder     string `json:"holder" gorm:"column:holder;type:varchar(100)"`                       // 当前持有租约的实例标识
+	LeaseUntil int64  `json:"leaseUntil" gorm:"column:lease_until;type:bigint;default:0"`          // 租约到期时间
+	LastFireAt int64  `json:"lastFireAt" gorm:"column:last_fire_at;type:bigint;default:0"`         // 最近一次被执行的调度时间
+	UpdatedAt  int64  `json:"updatedAt" gorm:"column:updated_at;type:bigint;autoUpdateTime:milli"` // 更新时间
+}
+
+func (TJobLease) TableName() string {
+	return TJobLeaseName
+}
+
+// TSchedulerJob 定时任务状态表，记录任务当前配置、暂停状态与最近一次执行结果
+type TSchedulerJob struct {
+	JobName      string `json:"jobName" gorm:"column:job_name;type:varchar(50);primaryKey"`          // 任务名称
+	Spec         string `json:"spec" gorm:"column:spec;type:varchar(100)"`                           // 生效的调度表达式
+	Enable       bool   `json:"enable" gorm:"column:enable;type:boolean;default:true"`               // 配置中是否启用
+	Singleton    bool   `json:"singleton" gorm:"column:singleton;type:boolean;default:true"`         // 是否集群内单实例执行
+	Paused       bool   `json:"paused" gorm:"column:paused;type:boolean;default:false"`              // 是否被管理员暂停，暂停后不再按计划执行
+	LastRunAt    int64  `json:"lastRunAt" gorm:"column:last_run_at;type:bigint;default:0"`           // 最近一次开始执行时间
+	LastDuration int64  `json:"lastDuration" gorm:"column:last_duration;type:bigint;default:0"`      // 最近一次执行耗时（毫秒）
+	LastStatus   string `json:"lastStatus" gorm:"column:last_status;type:varchar(20)"`               // 最近一次执行状态：running/succeeded/failed
+	LastError    string `json:"lastError" gorm:"column:last_error;type:text"`                        // 最近一次执行的错误信息
+	LastRunBy    string `json:"lastRunBy" gorm:"column:last_run_by;type:varchar(100)"`               // 最近一次执行的实例标识
+	LastTrigger  string `json:"lastTrigger" gorm:"column:last_trigger;type:varchar(20)"`             // 最近一次触发方式：schedule/manual
+	RunCount     int64  `json:"runCount" gorm:"column:run_count;type:bigint;default:0"`              // 累计执行次数
+	FailCount    int64  `json:"failCount" gorm:"column:fail_count;type:bigint;default:0"`            // 累计失败次数
+	UpdatedAt    int64  `json:"updatedAt" gorm:"column:updated_at;type:bigint;autoUpdateTime:milli"` // 更新时间
+}
+
+func (TSchedulerJob) TableName() string {
+	return TSchedulerJobName
+}
+
 // TCfgCommon 通用配置表
 type TCfgCommon struct {
 	Id        int64  `gorm:"column:id;type:bigint;primaryKey;autoIncrement"`     // ID
@@ -241,6 +469,41 @@ func (TCfgCommon) TableName() string {
 	return TCommonConfigName
 }
 
+// TConfigChangeLog 配置变更审计表，记录每次配置热更新的结果
+type TConfigChangeLog struct {
+	Id        int64          `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`                  // ID
+	Source    string         `json:"source" gorm:"column:source;type:varchar(20);not null"`                     // 变更来源 file:配置文件
+	Status    string         `json:"status" gorm:"column:status;type:varchar(20);not null;index"`               // 结果 applied:已生效 rejected:校验未通过 rolled_back:已回滚
+	Changes   datatypes.JSON `json:"changes" gorm:"column:changes;type:jsonb"`                                  // 变更的配置项，敏感配置的值已脱敏
+	Error     string         `json:"error" gorm:"column:error;type:text"`                                       // 未生效的原因
+	CreatedAt int64          `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli;index"` // 创建时间
+}
+
+func (TConfigChangeLog) TableName() string {
+	return TConfigChangeLogName
+}
+
+// TAdminAuditLog 管理操作审计表，记录管理员的每次修改操作，包括失败与被拒绝的请求
+type TAdminAuditLog struct {
+	Id        int64          `json:"id" gorm:"column:id;type:bigint;primaryKey;autoIncrement"`                  // ID
+	ActorId   uuid.UUID      `json:"actorId" gorm:"column:actor_id;type:uuid;index"`                            // 操作管理员ID
+	ActorName string         `json:"actorName" gorm:"column:actor_name;type:varchar(50)"`                       // 操作管理员用户名
+	Action    string         `json:"action" gorm:"column:action;type:varchar(50);not null;index"`               // 操作，如 prize.update
+	Target    string         `json:"target" gorm:"column:target;type:varchar(200);index"`                       // 操作对象，如 prize:12
+	Before    datatypes.JSON `json:"before" gorm:"column:before;type:jsonb"`                                    // 修改前的数据
+	After     datatypes.JSON `json:"after" gorm:"column:after;type:jsonb"`                                      // 修改后的数据
+	Diff      datatypes.JSON `json:"diff" gorm:"column:diff;type:jsonb"`                                        // 修改前后不同的字段
+	Status    int            `json:"status" gorm:"column:status;type:integer;not null"`                         // 响应状态 0:成功
+	Msg       string         `json:"msg" gorm:"column:msg;type:text"`                                           // 响应信息
+	Ip        string         `json:"ip" gorm:"column:ip;type:varchar(64)"`                                      // 客户端IP
+	RequestId string         `json:"requestId" gorm:"column:request_id;type:varchar(64)"`                       // 请求ID
+	CreatedAt int64          `json:"createdAt" gorm:"column:created_at;type:bigint;autoCreateTime:milli;index"` // 创建时间
+}
+
+func (TAdminAuditLog) TableName() string {
+	return TAdminAuditLogName
+}
+
 // VUserAssetMeta 用户资产视图
 type VUserAssetMeta struct {
 	Id             int64     `json:"id" gorm:"column:id"`
@@ -334,3 +597,14 @@ type VRaffleDesignatedUserPrizeInfo struct {
 func (VRaffleDesignatedUserPrizeInfo) TableName() string {
 	return VRaffleDesignatedUserPrizeInfoName
 }
+
+// TSchemaMigration 数据库迁移记录表，每条记录表示一个已执行的迁移版本
+type TSchemaMigration struct {
+	Version   int64  `json:"version" gorm:"column:version;type:bigint;primaryKey"`    // 迁移版本号
+	Name      string `json:"name" gorm:"column:name;type:varchar(100);not null"`      // 迁移名称
+	AppliedAt int64  `json:"appliedAt" gorm:"column:applied_at;type:bigint;not null"` // 执行时间
+}
+
+func (TSchemaMigration) TableName() string {
+	return TSchemaMigrationName
+}
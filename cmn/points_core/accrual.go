@@ -0,0 +1,418 @@
+package points_core
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobAssetAccrual 每日资产积分累加任务名称
+const JobAssetAccrual = "asset_accrual"
+
+// 任务运行状态
+const (
+	JobRunStatusRunning   = "running"   // 运行中
+	JobRunStatusSucceeded = "succeeded" // 全部处理成功
+	JobRunStatusFailed    = "failed"    // 部分处理失败或被中断
+)
+
+const (
+	jobRunStaleTimeout     = 10 * time.Minute // 运行中的任务超过该时间没有心跳视为已中断，可被接管
+	jobRunHeartbeatBatch   = 50               // 每处理多少个对象更新一次进度与心跳
+	bizDateLayout          = "2006-01-02"     // 业务日期格式
+	bizDateLocation        = "Asia/Shanghai"  // 业务日期所在时区
+	resumeAccrualLookbackN = 1                // 启动时向前回溯的天数，恢复该范围内（含今天）未完成的累加任务
+	rerunAccrualLookbackN  = 7                // 允许手动补跑的最早业务日期距今天的天数
+)
+
+var (
+	ErrJobRunInProgress = errors.New("job run in progress")                     // 同一任务同一业务日期已有实例在运行
+	ErrJobRunTooOld     = errors.New("job run biz date is out of rerun window") // 业务日期早于允许补跑的范围
+	ErrJobRunNotFound   = errors.New("job run not found")                       // 业务日期没有运行记录
+	ErrJobRunSucceeded  = errors.New("job run already succeeded")               // 业务日期的运行已全部成功
+)
+
+// BizDateOf 返回指定时间所在的业务日期
+func BizDateOf(t time.Time) string {
+	loc, err := time.LoadLocation(bizDateLocation)
+	if err != nil {
+		loc = time.Local
+	}
+	return t.In(loc).Format(bizDateLayout)
+}
+
+// ParseBizDate 解析业务日期，不允许晚于今天
+func ParseBizDate(bizDate string) (time.Time, error) {
+	loc, err := time.LoadLocation(bizDateLocation)
+	if err != nil {
+		loc = time.Local
+	}
+	t, err := time.ParseInLocation(bizDateLayout, bizDate, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid bizDate %s: %w", bizDate, err)
+	}
+	if bizDate > BizDateOf(time.Now()) {
+		return time.Time{}, fmt.Errorf("bizDate %s is in the future", bizDate)
+	}
+	return t, nil
+}
+
+// RunDailyAssetAccrual 执行指定业务日期的资产积分累加
+// 同一业务日期内每个用户最多累加一次：处理明细与积分变动在同一事务中写入，
+// 因此任务中途崩溃后重新执行只会处理尚未成功的用户，多实例并发执行也不会重复累加
+func RunDailyAssetAccrual(ctx context.Context, db *gorm.DB, bizDate string) (cmn.TJobRun, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+	if _, err := ParseBizDate(bizDate); err != nil {
+		cmn.LoggerFrom(ctx).Error(err.Error())
+		return cmn.TJobRun{}, err
+	}
+
+	jobRun, err := claimJobRun(db, JobAssetAccrual, bizDate)
+	if err != nil {
+		return cmn.TJobRun{}, err
+	}
+
+	// 查询所有已存在默认积分记录的用户
+	var userIds []uuid.UUID
+	err = db.Model(&cmn.TUserPointsBalance{}).
+		Where("points_type = ?", PointsTypeDefault).
+		Order("user_id").
+		Pluck("user_id", &userIds).Error
+	if err != nil {
+		e := fmt.Errorf("failed to query all user points: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		jobRun.LastError = e.Error()
+		finishJobRun(db, &jobRun)
+		return jobRun, e
+	}
+	jobRun.TotalCount = int64(len(userIds))
+
+	for i, userId := range userIds {
+		if ctx.Err() != nil {
+			jobRun.LastError = ctx.Err().Error()
+			jobRun.FailCount += int64(len(userIds) - i)
+			break
+		}
+
+		processed, err := accrueUserAssetPoints(ctx, db, bizDate, userId)
+		switch {
+		case err != nil:
+			jobRun.FailCount++
+			jobRun.LastError = err.Error()
+		case processed:
+			jobRun.SuccessCount++
+		default:
+			jobRun.SkipCount++
+		}
+
+		if (i+1)%jobRunHeartbeatBatch == 0 {
+			heartbeatJobRun(db, &jobRun)
+		}
+	}
+
+	finishJobRun(db, &jobRun)
+
+	cmn.LoggerFrom(ctx).Info("asset accrual finished",
+		zap.String("bizDate", bizDate),
+		zap.String("status", jobRun.Status),
+		zap.Int64("total", jobRun.TotalCount),
+		zap.Int64("success", jobRun.SuccessCount),
+		zap.Int64("skip", jobRun.SkipCount),
+		zap.Int64("fail", jobRun.FailCount))
+
+	if jobRun.Status != JobRunStatusSucceeded {
+		return jobRun, fmt.Errorf("asset accrual %s finished with %d failures: %s", bizDate, jobRun.FailCount, jobRun.LastError)
+	}
+	return jobRun, nil
+}
+
+// ResumeUnfinishedAssetAccrual 恢复最近未完成的资产积分累加任务
+// 用于服务在累加过程中崩溃或重启后继续处理剩余用户
+func ResumeUnfinishedAssetAccrual(ctx context.Context, db *gorm.DB) []error {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	now := time.Now()
+	earliest := BizDateOf(now.AddDate(0, 0, -resumeAccrualLookbackN))
+
+	var jobRuns []cmn.TJobRun
+	err := db.Where("job_name = ? AND biz_date >= ? AND status <> ?", JobAssetAccrual, earliest, JobRunStatusSucceeded).
+		Order("biz_date ASC").
+		Find(&jobRuns).Error
+	if err != nil {
+		e := fmt.Errorf("failed to query unfinished asset accrual runs: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return []error{e}
+	}
+
+	var errs []error
+	for _, jobRun := range jobRuns {
+		cmn.LoggerFrom(ctx).Info("resuming unfinished asset accrual", zap.String("bizDate", jobRun.BizDate), zap.String("status", jobRun.Status))
+		_, err = RunDailyAssetAccrual(ctx, db, jobRun.BizDate)
+		if err != nil && !errors.Is(err, ErrJobRunInProgress) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// CheckAssetAccrualRerunnable 检查指定业务日期是否允许手动补跑资产积分累加
+// 仅允许补跑近期已有运行记录且失败或中断的业务日期：没有运行记录的日期从未执行过，
+// 补跑会按用户当前资产重新累加一整天的积分，因此不允许通过补跑创建
+func CheckAssetAccrualRerunnable(ctx context.Context, db *gorm.DB, bizDate string) error {
+	if db == nil {
+		db = cmn.GormDB
+	}
+	if _, err := ParseBizDate(bizDate); err != nil {
+		return err
+	}
+	if bizDate < BizDateOf(time.Now().AddDate(0, 0, -rerunAccrualLookbackN)) {
+		return fmt.Errorf("%w: %s", ErrJobRunTooOld, bizDate)
+	}
+
+	var jobRun cmn.TJobRun
+	err := db.WithContext(ctx).Where("job_name = ? AND biz_date = ?", JobAssetAccrual, bizDate).First(&jobRun).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: %s %s", ErrJobRunNotFound, JobAssetAccrual, bizDate)
+	}
+	if err != nil {
+		e := fmt.Errorf("failed to query job run: %w, job: %s, bizDate: %s", err, JobAssetAccrual, bizDate)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
+	}
+
+	switch {
+	case jobRun.Status == JobRunStatusSucceeded:
+		return fmt.Errorf("%w: %s %s", ErrJobRunSucceeded, JobAssetAccrual, bizDate)
+	case jobRun.Status == JobRunStatusRunning && jobRun.UpdatedAt >= time.Now().Add(-jobRunStaleTimeout).UnixMilli():
+		return fmt.Errorf("%w: %s %s", ErrJobRunInProgress, JobAssetAccrual, bizDate)
+	}
+	return nil
+}
+
+// accrueUserAssetPoints 在一个事务内写入处理明细并累加单个用户的资产积分
+// 返回: 是否本次处理（false 表示该用户当天已处理过）、错误
+func accrueUserAssetPoints(ctx context.Context, db *gorm.DB, bizDate string, userId uuid.UUID) (bool, error) {
+	var processed bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		item := cmn.TJobRunItem{
+			JobName: JobAssetAccrual,
+			BizDate: bizDate,
+			ItemKey: userId.String(),
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&item)
+		if result.Error != nil {
+			e := fmt.Errorf("failed to create job run item: %w, userId: %s", result.Error, userId.String())
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
+		if result.RowsAffected == 0 {
+			// 当天已处理过该用户
+			return nil
+		}
+		processed = true
+
+		return addSingleUserPointsFromAssets(ctx, tx, userId, bizDate)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return processed, nil
+}
+
+// addSingleUserPointsFromAssets 根据资产计算并累加到单个用户积分
+// 计算用户资产总价值并累加到现有积分上
+func addSingleUserPointsFromAssets(ctx context.Context, db *gorm.DB, userId uuid.UUID, bizDate string) error {
+	if db == nil {
+		db = cmn.GormDB
+	}
+	if userId == uuid.Nil {
+		e := fmt.Errorf("userId is nil")
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
+	}
+
+	// 查询用户所有资产及其对应的元资产信息
+	var userAssets []struct {
+		MetaAssetId    int64   `gorm:"column:meta_asset_id"`
+		MetaAssetValue float64 `gorm:"column:meta_asset_value"`
+		Count          int64   `gorm:"column:asset_count"`
+	}
+
+	// 使用 VUserAssetMeta 视图查询，统计每个元资产的数量
+	err := db.Model(&cmn.VUserAssetMeta{}).
+		Select("meta_asset_id, meta_asset_value, COUNT(id) as asset_count").
+		Where("user_id = ?", userId).
+		Group("meta_asset_id, meta_asset_value").
+		Scan(&userAssets).Error
+
+	if err != nil {
+		e := fmt.Errorf("failed to query user assets: %w, userId: %s", err, userId.String())
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
+	}
+
+	// 计算资产总积分
+	var assetPoints float64
+	for _, asset := range userAssets {
+		assetPoints += asset.MetaAssetValue * float64(asset.Count)
+	}
+
+	// 资产价值为0时无需变动积分
+	if assetPoints <= 0 {
+		return nil
+	}
+
+	// 累加积分到原有积分上，以业务日期作为流水关联ID
+	_, err = Credit(ctx, db, userId, PointsTypeDefault, assetPoints, ReasonAssetDaily, bizDate)
+	if err != nil {
+		e := fmt.Errorf("failed to update user points: %w, userId: %v", err, userId)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
+	}
+
+	return nil
+}
+
+// claimJobRun 抢占指定任务指定业务日期的运行权
+// 记录不存在时创建；已存在时仅当其未在运行或心跳超时才可接管，否则返回 ErrJobRunInProgress
+func claimJobRun(db *gorm.DB, jobName, bizDate string) (cmn.TJobRun, error) {
+	now := time.Now().UnixMilli()
+
+	jobRun := cmn.TJobRun{
+		JobName:   jobName,
+		BizDate:   bizDate,
+		Status:    JobRunStatusRunning,
+		StartedAt: now,
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&jobRun)
+	if result.Error != nil {
+		e := fmt.Errorf("failed to create job run: %w, job: %s, bizDate: %s", result.Error, jobName, bizDate)
+		z.Error(e.Error())
+		return cmn.TJobRun{}, e
+	}
+	if result.RowsAffected > 0 {
+		return jobRun, nil
+	}
+
+	// 已存在运行记录，尝试接管已结束或已中断的运行
+	staleBefore := now - jobRunStaleTimeout.Milliseconds()
+	result = db.Model(&cmn.TJobRun{}).
+		Where("job_name = ? AND biz_date = ?", jobName, bizDate).
+		Where("status <> ? OR updated_at < ?", JobRunStatusRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"status":        JobRunStatusRunning,
+			"total_count":   0,
+			"success_count": 0,
+			"skip_count":    0,
+			"fail_count":    0,
+			"last_error":    "",
+			"started_at":    now,
+			"finished_at":   0,
+			"updated_at":    now,
+		})
+	if result.Error != nil {
+		e := fmt.Errorf("failed to claim job run: %w, job: %s, bizDate: %s", result.Error, jobName, bizDate)
+		z.Error(e.Error())
+		return cmn.TJobRun{}, e
+	}
+	if result.RowsAffected == 0 {
+		z.Info("job run is in progress by another instance", zap.String("job", jobName), zap.String("bizDate", bizDate))
+		return cmn.TJobRun{}, fmt.Errorf("%w: %s %s", ErrJobRunInProgress, jobName, bizDate)
+	}
+
+	err := db.Where("job_name = ? AND biz_date = ?", jobName, bizDate).First(&jobRun).Error
+	if err != nil {
+		e := fmt.Errorf("failed to query job run: %w, job: %s, bizDate: %s", err, jobName, bizDate)
+		z.Error(e.Error())
+		return cmn.TJobRun{}, e
+	}
+
+	return jobRun, nil
+}
+
+// heartbeatJobRun 更新运行进度，同时刷新心跳时间
+func heartbeatJobRun(db *gorm.DB, jobRun *cmn.TJobRun) {
+	err := db.Model(&cmn.TJobRun{}).
+		Where("id = ?", jobRun.Id).
+		Updates(map[string]interface{}{
+			"total_count":   jobRun.TotalCount,
+			"success_count": jobRun.SuccessCount,
+			"skip_count":    jobRun.SkipCount,
+			"fail_count":    jobRun.FailCount,
+			"updated_at":    time.Now().UnixMilli(),
+		}).Error
+	if err != nil {
+		z.Error("failed to heartbeat job run", zap.Error(err), zap.Int64("id", jobRun.Id))
+	}
+}
+
+// finishJobRun 根据处理结果结束运行记录
+func finishJobRun(db *gorm.DB, jobRun *cmn.TJobRun) {
+	jobRun.Status = JobRunStatusSucceeded
+	if jobRun.FailCount > 0 || jobRun.LastError != "" {
+		jobRun.Status = JobRunStatusFailed
+	}
+	jobRun.FinishedAt = time.Now().UnixMilli()
+
+	err := db.Model(&cmn.TJobRun{}).
+		Where("id = ?", jobRun.Id).
+		Updates(map[string]interface{}{
+			"status":        jobRun.Status,
+			"total_count":   jobRun.TotalCount,
+			"success_count": jobRun.SuccessCount,
+			"skip_count":    jobRun.SkipCount,
+			"fail_count":    jobRun.FailCount,
+			"last_error":    jobRun.LastError,
+			"finished_at":   jobRun.FinishedAt,
+			"updated_at":    jobRun.FinishedAt,
+		}).Error
+	if err != nil {
+		z.Error("failed to finish job run", zap.Error(err), zap.Int64("id", jobRun.Id))
+	}
+}
+
+// QueryJobRuns 分页查询任务运行记录，按业务日期倒序
+func QueryJobRuns(ctx context.Context, jobName string, page, pageSize int) ([]cmn.TJobRun, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	query := cmn.GormDB.Model(&cmn.TJobRun{}).Where("job_name = ?", jobName)
+
+	var total int64
+	err := query.Count(&total).Error
+	if err != nil {
+		e := fmt.Errorf("failed to count job runs: %w, job: %s", err, jobName)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return nil, 0, e
+	}
+
+	var jobRuns []cmn.TJobRun
+	err = query.Order("biz_date DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&jobRuns).Error
+	if err != nil {
+		e := fmt.Errorf("failed to query job runs: %w, job: %s", err, jobName)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return nil, 0, e
+	}
+
+	return jobRuns, total, nil
+}
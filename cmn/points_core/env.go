@@ -2,14 +2,30 @@ package points_core
 
 import (
 	"WudangMeta/cmn"
+	"context"
 
 	"go.uber.org/zap"
 )
 
+// 积分变动原因
+const (
+	ReasonInit       = "init"        // 根据资产初始化积分
+	ReasonCheckIn    = "check_in"    // 每日签到
+	ReasonFortune    = "fortune"     // 运势分析
+	ReasonAsset      = "asset"       // 新增资产
+	ReasonAssetDaily = "asset_daily" // 每日资产积分
+	ReasonActivity   = "activity"    // 活动奖励
+	ReasonRaffle     = "raffle"      // 抽奖消耗
+	ReasonExpire     = "expire"      // 积分过期
+)
+
+// PointsTypeDefault 默认积分类型编码，系统内置（由数据库迁移创建）且不可停用
+const PointsTypeDefault = "default"
+
 var z *zap.Logger
 
-func Init() {
+func Init(ctx context.Context) {
 	z = cmn.GetLogger()
 
-	cmn.MiniLogger.Info("[ OK ] ubanquan-core module initialized")
+	cmn.MiniLogger.Info("[ OK ] points-core module initialized")
 }
@@ -0,0 +1,232 @@
+package points_core
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// expireLocation 积分过期按该时区的自然日计算，与每日结算任务保持一致
+const expireLocation = "Asia/Shanghai"
+
+// calcExpireAt 计算积分批次的过期时间
+// 积分在获得当天之后的第 expireDays 天结束时过期，例如有效期1天时，今天获得的积分在后天 00:00 过期
+func calcExpireAt(now time.Time, expireDays int) int64 {
+	loc, err := time.LoadLocation(expireLocation)
+	if err != nil {
+		loc = time.Local
+	}
+	now = now.In(loc)
+	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	return todayStart.AddDate(0, 0, expireDays+1).UnixMilli()
+}
+
+// createPointsLot 记录一个会过期的积分批次
+func createPointsLot(ctx context.Context, db *gorm.DB, userId uuid.UUID, pointsType string, amount float64, reason, refId string, expireAt int64) error {
+	lot := cmn.TPointsLot{
+		UserId:     userId,
+		PointsType: pointsType,
+		Amount:     amount,
+		Remaining:  amount,
+		Reason:     reason,
+		RefId:      refId,
+		ExpireAt:   expireAt,
+		Status:     "00",
+	}
+	err := db.Create(&lot).Error
+	if err != nil {
+		e := fmt.Errorf("failed to create points lot: %w, userId: %s, pointsType: %s", err, userId.String(), pointsType)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
+	}
+
+	return nil
+}
+
+// consumePointsLots 按过期时间先后消耗积分批次
+// 调用方需已在同一事务中锁定用户余额记录；批次总量不足时，剩余部分由不会过期的积分承担
+func consumePointsLots(ctx context.Context, tx *gorm.DB, userId uuid.UUID, pointsType string, amount float64) error {
+	var lots []cmn.TPointsLot
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND points_type = ? AND status = ? AND remaining > 0", userId, pointsType, "00").
+		Order("expire_at ASC, id ASC").
+		Find(&lots).Error
+	if err != nil {
+		e := fmt.Errorf("failed to query points lots: %w, userId: %s, pointsType: %s", err, userId.String(), pointsType)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
+	}
+
+	remaining := amount
+	for _, lot := range lots {
+		if remaining <= 0 {
+			break
+		}
+
+		consumed := lot.Remaining
+		if consumed > remaining {
+			consumed = remaining
+		}
+
+		err = tx.Model(&cmn.TPointsLot{}).
+			Where("id = ?", lot.Id).
+			Update("remaining", gorm.Expr("remaining - ?", consumed)).Error
+		if err != nil {
+			e := fmt.Errorf("failed to consume points lot: %w, lotId: %d", err, lot.Id)
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
+
+		remaining -= consumed
+	}
+
+	return nil
+}
+
+// ExpireDuePointsLots 将所有已到期且仍有剩余的积分批次清零，并写入过期流水
+// 每个批次在独立事务中处理，单个批次失败不影响其他批次，重复执行是安全的
+// 返回: 成功过期的批次数、错误列表
+func ExpireDuePointsLots(ctx context.Context, db *gorm.DB) (int, []error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	var lotIds []int64
+	err := db.Model(&cmn.TPointsLot{}).
+		Where("status = ? AND remaining > 0 AND expire_at <= ?", "00", time.Now().UnixMilli()).
+		Order("expire_at ASC, id ASC").
+		Pluck("id", &lotIds).Error
+	if err != nil {
+		e := fmt.Errorf("failed to query due points lots: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return 0, []error{e}
+	}
+
+	var expiredCount int
+	var errs []error
+	for _, lotId := range lotIds {
+		select {
+		case <-ctx.Done():
+			return expiredCount, append(errs, ctx.Err())
+		default:
+		}
+
+		err = expirePointsLot(ctx, db, lotId)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		expiredCount++
+	}
+
+	return expiredCount, errs
+}
+
+// expirePointsLot 过期单个积分批次
+// 先锁定余额记录再锁定批次，与扣减时的加锁顺序一致
+func expirePointsLot(ctx context.Context, db *gorm.DB, lotId int64) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var lot cmn.TPointsLot
+		err := tx.Where("id = ?", lotId).First(&lot).Error
+		if err != nil {
+			e := fmt.Errorf("failed to query points lot: %w, lotId: %d", err, lotId)
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
+
+		var userBalance cmn.TUserPointsBalance
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND points_type = ?", lot.UserId, lot.PointsType).
+			First(&userBalance).Error
+		if err != nil {
+			e := fmt.Errorf("failed to lock user points balance: %w, lotId: %d", err, lotId)
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
+
+		// 加锁后重新读取批次，期间可能已被扣减或过期
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", lotId).First(&lot).Error
+		if err != nil {
+			e := fmt.Errorf("failed to lock points lot: %w, lotId: %d", err, lotId)
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
+		if lot.Status != "00" || lot.ExpireAt > time.Now().UnixMilli() {
+			return nil
+		}
+
+		expired := lot.Remaining
+		if expired > userBalance.Balance {
+			// 正常情况下不会发生，防止余额被扣为负数
+			cmn.LoggerFrom(ctx).Warn("points lot remaining exceeds balance",
+				zap.Int64("lotId", lotId), zap.Float64("remaining", lot.Remaining), zap.Float64("balance", userBalance.Balance))
+			expired = userBalance.Balance
+		}
+
+		err = tx.Model(&cmn.TPointsLot{}).
+			Where("id = ?", lotId).
+			Updates(map[string]interface{}{
+				"remaining":  0,
+				"status":     "01",
+				"updated_at": time.Now().UnixMilli(),
+			}).Error
+		if err != nil {
+			e := fmt.Errorf("failed to expire points lot: %w, lotId: %d", err, lotId)
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
+
+		if expired <= 0 {
+			return nil
+		}
+
+		var balances []float64
+		err = tx.Raw(`UPDATE t_user_points_balance
+			SET balance = balance - ?, updated_at = ?
+			WHERE id = ?
+			RETURNING balance`, expired, time.Now().UnixMilli(), userBalance.Id).
+			Scan(&balances).Error
+		if err != nil || len(balances) == 0 {
+			e := fmt.Errorf("failed to deduct expired points: %v, lotId: %d", err, lotId)
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
+
+		return RecordPointsLedger(ctx, tx, lot.UserId, lot.PointsType, -expired, ReasonExpire, strconv.FormatInt(lot.Id, 10), balances[0])
+	})
+}
+
+// UpcomingExpiration 即将过期的积分
+type UpcomingExpiration struct {
+	PointsType string  `json:"pointsType" gorm:"column:points_type"` // 积分类型编码
+	Amount     float64 `json:"amount" gorm:"column:amount"`          // 即将过期的积分
+	ExpireAt   int64   `json:"expireAt" gorm:"column:expire_at"`     // 过期时间
+}
+
+// QueryUserUpcomingExpirations 查询用户在指定天数内即将过期的积分，按过期时间与积分类型汇总
+func QueryUserUpcomingExpirations(ctx context.Context, userId uuid.UUID, withinDays int) ([]UpcomingExpiration, error) {
+	now := time.Now()
+	var expirations []UpcomingExpiration
+	err := cmn.GormDB.Model(&cmn.TPointsLot{}).
+		Select("points_type, SUM(remaining) AS amount, expire_at").
+		Where("user_id = ? AND status = ? AND remaining > 0", userId, "00").
+		Where("expire_at > ? AND expire_at <= ?", now.UnixMilli(), now.AddDate(0, 0, withinDays).UnixMilli()).
+		Group("points_type, expire_at").
+		Order("expire_at ASC, points_type ASC").
+		Scan(&expirations).Error
+	if err != nil {
+		e := fmt.Errorf("failed to query upcoming expirations: %w, userId: %s", err, userId.String())
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return nil, e
+	}
+
+	return expirations, nil
+}
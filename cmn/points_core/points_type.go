@@ -0,0 +1,78 @@
+package points_core
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPointsTypeNotFound 积分类型不存在
+	ErrPointsTypeNotFound = errors.New("points type not found")
+	// ErrPointsTypeDisabled 积分类型已停用
+	ErrPointsTypeDisabled = errors.New("points type disabled")
+)
+
+// pointsTypeCodeRegexp 积分类型编码只允许小写字母、数字和下划线，且以字母开头
+var pointsTypeCodeRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]{0,29}$`)
+
+// IsValidPointsTypeCode 检查积分类型编码格式是否合法
+func IsValidPointsTypeCode(code string) bool {
+	return pointsTypeCodeRegexp.MatchString(code)
+}
+
+// GetPointsType 根据编码查询积分类型
+func GetPointsType(ctx context.Context, db *gorm.DB, code string) (cmn.TPointsType, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	var pointsType cmn.TPointsType
+	err := db.Where("code = ?", code).First(&pointsType).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return cmn.TPointsType{}, fmt.Errorf("%w: %s", ErrPointsTypeNotFound, code)
+		}
+		e := fmt.Errorf("failed to query points type: %w, code: %s", err, code)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return cmn.TPointsType{}, e
+	}
+
+	return pointsType, nil
+}
+
+// ValidatePointsType 检查积分类型存在且处于启用状态
+func ValidatePointsType(ctx context.Context, db *gorm.DB, code string) error {
+	_, err := getEnabledPointsType(ctx, db, code)
+	return err
+}
+
+// getEnabledPointsType 查询处于启用状态的积分类型
+func getEnabledPointsType(ctx context.Context, db *gorm.DB, code string) (cmn.TPointsType, error) {
+	pointsType, err := GetPointsType(ctx, db, code)
+	if err != nil {
+		return cmn.TPointsType{}, err
+	}
+	if pointsType.Status != "00" {
+		return cmn.TPointsType{}, fmt.Errorf("%w: %s", ErrPointsTypeDisabled, code)
+	}
+
+	return pointsType, nil
+}
+
+// QueryPointsTypes 查询所有积分类型
+func QueryPointsTypes(ctx context.Context) ([]cmn.TPointsType, error) {
+	var pointsTypes []cmn.TPointsType
+	err := cmn.GormDB.Order("id ASC").Find(&pointsTypes).Error
+	if err != nil {
+		e := fmt.Errorf("failed to query points types: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return nil, e
+	}
+
+	return pointsTypes, nil
+}
@@ -2,30 +2,40 @@ package points_core
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/metrics"
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// InitializeUserPoints 根据资产初始化用户积分
-// 仅为不存在积分记录的用户创建初始积分，不会更新已存在的记录
+// InitializeUserPoints 根据资产初始化用户默认积分
+// 仅为不存在默认积分记录的用户创建初始积分，不会更新已存在的记录
 func InitializeUserPoints(ctx context.Context, db *gorm.DB, userId uuid.UUID) error {
 	if db == nil {
 		db = cmn.GormDB
 	}
 	if userId == uuid.Nil {
 		e := fmt.Errorf("userId is nil")
-		z.Error(e.Error())
+		cmn.LoggerFrom(ctx).Error(e.Error())
 		return e
 	}
 
-	// 检查用户积分记录是否已存在
-	var existingPoints cmn.TUserPoints
-	err := cmn.GormDB.Where("user_id = ?", userId).First(&existingPoints).Error
-	if err == nil {
+	// 检查用户默认积分记录是否已存在
+	var existingCount int64
+	err := db.Model(&cmn.TUserPointsBalance{}).
+		Where("user_id = ? AND points_type = ?", userId, PointsTypeDefault).
+		Count(&existingCount).Error
+	if err != nil {
+		cmn.LoggerFrom(ctx).Error("failed to query user points", zap.Error(err), zap.String("user_id", userId.String()))
+		return err
+	}
+	if existingCount > 0 {
 		// 记录已存在，不进行初始化
 		return nil
 	}
@@ -45,7 +55,7 @@ func InitializeUserPoints(ctx context.Context, db *gorm.DB, userId uuid.UUID) er
 		Scan(&userAssets).Error
 
 	if err != nil {
-		z.Error("failed to query user assets", zap.Error(err), zap.String("user_id", userId.String()))
+		cmn.LoggerFrom(ctx).Error("failed to query user assets", zap.Error(err), zap.String("user_id", userId.String()))
 		return err
 	}
 
@@ -55,161 +65,375 @@ func InitializeUserPoints(ctx context.Context, db *gorm.DB, userId uuid.UUID) er
 		totalPoints += asset.MetaAssetValue * float64(asset.Count)
 	}
 
-	// 创建用户积分记录
-	userPoints := cmn.TUserPoints{
-		UserId:        userId,
-		DefaultPoints: totalPoints,
+	// 创建用户默认积分记录，并发初始化时以先写入者为准
+	rowsAffected, err := createBalanceIfNotExists(db, userId, PointsTypeDefault, totalPoints)
+	if err != nil {
+		cmn.LoggerFrom(ctx).Error("failed to create user points", zap.Error(err), zap.String("user_id", userId.String()))
+		return err
+	}
+
+	// 初始积分不为0且确实由本次写入时记录流水
+	if rowsAffected > 0 && totalPoints != 0 {
+		err = RecordPointsLedger(ctx, db, userId, PointsTypeDefault, totalPoints, ReasonInit, "", totalPoints)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createBalanceIfNotExists 创建用户指定类型的积分余额记录，已存在时不做修改
+// 返回: 实际写入的行数、错误
+func createBalanceIfNotExists(db *gorm.DB, userId uuid.UUID, pointsType string, balance float64) (int64, error) {
+	userBalance := cmn.TUserPointsBalance{
+		UserId:     userId,
+		PointsType: pointsType,
+		Balance:    balance,
+	}
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "points_type"}},
+		DoNothing: true,
+	}).Create(&userBalance)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// ensureUserBalance 确保用户指定类型的积分余额记录存在
+// 默认积分按资产初始化，其余类型初始余额为0
+func ensureUserBalance(ctx context.Context, db *gorm.DB, userId uuid.UUID, pointsType string) error {
+	if pointsType == PointsTypeDefault {
+		return InitializeUserPoints(ctx, db, userId)
 	}
 
-	// 创建新的积分记录
-	err = db.Create(&userPoints).Error
+	_, err := createBalanceIfNotExists(db, userId, pointsType, 0)
 	if err != nil {
-		z.Error("failed to create user points", zap.Error(err), zap.String("user_id", userId.String()))
+		cmn.LoggerFrom(ctx).Error("failed to create user points balance", zap.Error(err), zap.String("user_id", userId.String()), zap.String("points_type", pointsType))
 		return err
 	}
 
 	return nil
 }
 
-// AddUserPoints 增加用户积分
-func AddUserPoints(ctx context.Context, db *gorm.DB, userId uuid.UUID, points float64) error {
+// ErrInsufficientBalance 积分余额不足
+// 可通过 errors.Is 判断，具体余额信息通过 errors.As 获取 *InsufficientBalanceError
+var ErrInsufficientBalance = errors.New("insufficient points balance")
+
+// InsufficientBalanceError 积分余额不足错误
+type InsufficientBalanceError struct {
+	UserId     uuid.UUID // 用户ID
+	PointsType string    // 积分类型编码
+	Balance    float64   // 当前余额
+	Required   float64   // 所需积分
+}
+
+func (e *InsufficientBalanceError) Error() string {
+	return fmt.Sprintf("insufficient points balance, userId: %s, pointsType: %s, balance: %.2f, required: %.2f", e.UserId.String(), e.PointsType, e.Balance, e.Required)
+}
+
+func (e *InsufficientBalanceError) Is(target error) bool {
+	return target == ErrInsufficientBalance
+}
+
+// Credit 原子地增加用户指定类型的积分并写入流水
+// 使用 UPDATE ... SET balance = balance + ? 在数据库侧完成累加，不存在读改写竞争
+// 返回: 变动后余额、错误
+func Credit(ctx context.Context, db *gorm.DB, userId uuid.UUID, pointsType string, amount float64, reason, refId string) (float64, error) {
 	if db == nil {
 		db = cmn.GormDB
 	}
 	if userId == uuid.Nil {
 		e := fmt.Errorf("userId is nil")
-		z.Error(e.Error())
-		return e
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return 0, e
 	}
-	if points <= 0 {
-		e := fmt.Errorf("points must be positive")
-		z.Error(e.Error())
-		return e
+	if amount <= 0 {
+		e := fmt.Errorf("credit amount must be positive, userId: %s, amount: %.2f", userId.String(), amount)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return 0, e
 	}
 
-	// 尝试初始化用户积分记录
-	// 如果记录已存在，则不会重复创建
-	err := InitializeUserPoints(ctx, db, userId)
+	var balance float64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		pt, err := getEnabledPointsType(ctx, tx, pointsType)
+		if err != nil {
+			return err
+		}
+
+		// 尝试初始化用户积分记录
+		// 如果记录已存在，则不会重复创建
+		err = ensureUserBalance(ctx, tx, userId, pointsType)
+		if err != nil {
+			e := fmt.Errorf("failed to initialize user points: %w, userId: %s", err, userId.String())
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
+
+		var balances []float64
+		err = tx.Raw(`UPDATE t_user_points_balance
+			SET balance = balance + ?, updated_at = ?
+			WHERE user_id = ? AND points_type = ?
+			RETURNING balance`, amount, time.Now().UnixMilli(), userId, pointsType).
+			Scan(&balances).Error
+		if err != nil {
+			e := fmt.Errorf("failed to credit user points: %w, userId: %s, pointsType: %s", err, userId.String(), pointsType)
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
+		if len(balances) == 0 {
+			e := fmt.Errorf("user points not found, userId: %s, pointsType: %s", userId.String(), pointsType)
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
+		balance = balances[0]
+
+		// 会过期的积分类型需要记录批次
+		if pt.ExpireDays > 0 {
+			err = createPointsLot(ctx, tx, userId, pointsType, amount, reason, refId, calcExpireAt(time.Now(), pt.ExpireDays))
+			if err != nil {
+				return err
+			}
+		}
+
+		return RecordPointsLedger(ctx, tx, userId, pointsType, amount, reason, refId, balance)
+	})
 	if err != nil {
-		e := fmt.Errorf("failed to initialize user points: %w, userId: %s", err, userId.String())
-		z.Error(e.Error())
-		return e
+		return 0, err
 	}
 
-	// 查询用户现有积分记录
-	var userPoints cmn.TUserPoints
-	err = db.Where("user_id = ?", userId).First(&userPoints).Error
-	if err != nil {
-		e := fmt.Errorf("failed to query user points: %w, userId: %s", err, userId.String())
-		z.Error(e.Error())
-		return e
+	return balance, nil
+}
+
+// Debit 原子地扣减用户指定类型的积分并写入流水
+// 使用带条件的 UPDATE ... WHERE balance >= ? 保证并发扣减时余额不会为负
+// 余额不足时返回 *InsufficientBalanceError
+// 返回: 变动后余额、错误
+func Debit(ctx context.Context, db *gorm.DB, userId uuid.UUID, pointsType string, amount float64, reason, refId string) (float64, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+	if userId == uuid.Nil {
+		e := fmt.Errorf("userId is nil")
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return 0, e
 	}
+	if amount <= 0 {
+		e := fmt.Errorf("debit amount must be positive, userId: %s, amount: %.2f", userId.String(), amount)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return 0, e
+	}
+
+	var balance float64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := ValidatePointsType(ctx, tx, pointsType)
+		if err != nil {
+			return err
+		}
 
-	// 累加积分到原有积分上
-	newTotalPoints := userPoints.DefaultPoints + points
+		var balances []float64
+		err = tx.Raw(`UPDATE t_user_points_balance
+			SET balance = balance - ?, updated_at = ?
+			WHERE user_id = ? AND points_type = ? AND balance >= ?
+			RETURNING balance`, amount, time.Now().UnixMilli(), userId, pointsType, amount).
+			Scan(&balances).Error
+		if err != nil {
+			e := fmt.Errorf("failed to debit user points: %w, userId: %s, pointsType: %s", err, userId.String(), pointsType)
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
 
-	// 更新积分记录
-	err = db.Model(&userPoints).Update("default_points", newTotalPoints).Error
+		if len(balances) == 0 {
+			// 未更新任何行，余额记录不存在视为余额为0
+			var current []float64
+			err = tx.Model(&cmn.TUserPointsBalance{}).
+				Where("user_id = ? AND points_type = ?", userId, pointsType).
+				Pluck("balance", &current).Error
+			if err != nil {
+				e := fmt.Errorf("failed to query user points: %w, userId: %s, pointsType: %s", err, userId.String(), pointsType)
+				cmn.LoggerFrom(ctx).Error(e.Error())
+				return e
+			}
+			insufficient := &InsufficientBalanceError{
+				UserId:     userId,
+				PointsType: pointsType,
+				Required:   amount,
+			}
+			if len(current) > 0 {
+				insufficient.Balance = current[0]
+			}
+			return insufficient
+		}
+		balance = balances[0]
+
+		// 优先消耗即将过期的积分批次
+		err = consumePointsLots(ctx, tx, userId, pointsType, amount)
+		if err != nil {
+			return err
+		}
+
+		return RecordPointsLedger(ctx, tx, userId, pointsType, -amount, reason, refId, balance)
+	})
 	if err != nil {
-		e := fmt.Errorf("failed to update user points: %w, userId: %s", err, userId.String())
-		z.Error(e.Error())
-		return e
+		return 0, err
 	}
 
-	return nil
+	return balance, nil
 }
 
-// AddAllUserPointsFromAssets 根据资产计算并累加到已存在的用户积分
-// 自动遍历积分表中的所有用户，计算其资产总价值并累加到现有积分上
-func AddAllUserPointsFromAssets(ctx context.Context, db *gorm.DB) []error {
+// Transfer 在同一事务内从一个用户向另一个用户转移指定类型的积分
+// 按用户ID顺序使用 SELECT ... FOR UPDATE 锁定双方积分记录，避免并发转账时死锁
+func Transfer(ctx context.Context, db *gorm.DB, fromUserId, toUserId uuid.UUID, pointsType string, amount float64, reason, refId string) error {
 	if db == nil {
 		db = cmn.GormDB
 	}
-
-	// 查询所有已存在积分记录的用户
-	var allUserPoints []cmn.TUserPoints
-	err := cmn.GormDB.Find(&allUserPoints).Error
-	if err != nil {
-		z.Error("failed to query all user points", zap.Error(err))
-		return []error{err}
+	if fromUserId == uuid.Nil || toUserId == uuid.Nil {
+		e := fmt.Errorf("fromUserId or toUserId is nil")
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
+	}
+	if fromUserId == toUserId {
+		e := fmt.Errorf("cannot transfer points to self, userId: %s", fromUserId.String())
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
+	}
+	if amount <= 0 {
+		e := fmt.Errorf("transfer amount must be positive, amount: %.2f", amount)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
 	}
 
-	var successCount, failCount int
-	var errs []error
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 确保收款方积分记录存在后再加锁
+		err := ensureUserBalance(ctx, tx, toUserId, pointsType)
+		if err != nil {
+			e := fmt.Errorf("failed to initialize user points: %w, userId: %s", err, toUserId.String())
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
+		}
 
-	// 遍历每个用户进行积分更新
-	for _, userPoint := range allUserPoints {
-		err = addSingleUserPointsFromAssets(ctx, db, userPoint.UserId)
+		var locked []cmn.TUserPointsBalance
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id IN ? AND points_type = ?", []uuid.UUID{fromUserId, toUserId}, pointsType).
+			Order("user_id").
+			Find(&locked).Error
 		if err != nil {
-			errs = append(errs, err)
-			failCount++
-			continue
+			e := fmt.Errorf("failed to lock user points: %w", err)
+			cmn.LoggerFrom(ctx).Error(e.Error())
+			return e
 		}
-		successCount++
-	}
 
-	if failCount > 0 {
-		return errs
-	}
+		_, err = Debit(ctx, tx, fromUserId, pointsType, amount, reason, refId)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		_, err = Credit(ctx, tx, toUserId, pointsType, amount, reason, refId)
+		return err
+	})
 }
 
-// addSingleUserPointsFromAssets 根据资产计算并累加到单个用户积分
-// 计算用户资产总价值并累加到现有积分上
-func addSingleUserPointsFromAssets(ctx context.Context, db *gorm.DB, userId uuid.UUID) error {
+// RecordPointsLedger 写入一条积分流水
+// 积分余额的每一次变动都必须通过该函数留下记录，流水表只追加不修改
+func RecordPointsLedger(ctx context.Context, db *gorm.DB, userId uuid.UUID, pointsType string, delta float64, reason, refId string, balanceAfter float64) error {
 	if db == nil {
 		db = cmn.GormDB
 	}
 	if userId == uuid.Nil {
 		e := fmt.Errorf("userId is nil")
-		z.Error(e.Error())
+		cmn.LoggerFrom(ctx).Error(e.Error())
 		return e
 	}
-
-	// 查询用户所有资产及其对应的元资产信息
-	var userAssets []struct {
-		MetaAssetId    int64   `gorm:"column:meta_asset_id"`
-		MetaAssetValue float64 `gorm:"column:meta_asset_value"`
-		Count          int64   `gorm:"column:asset_count"`
+	if reason == "" {
+		e := fmt.Errorf("points ledger reason is empty, userId: %s", userId.String())
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
 	}
 
-	// 使用 VUserAssetMeta 视图查询，统计每个元资产的数量
-	err := db.Model(&cmn.VUserAssetMeta{}).
-		Select("meta_asset_id, meta_asset_value, COUNT(id) as asset_count").
-		Where("user_id = ?", userId).
-		Group("meta_asset_id, meta_asset_value").
-		Scan(&userAssets).Error
-
+	ledger := cmn.TPointsLedger{
+		UserId:       userId,
+		PointsType:   pointsType,
+		Delta:        delta,
+		Reason:       reason,
+		RefId:        refId,
+		BalanceAfter: balanceAfter,
+	}
+	err := db.Create(&ledger).Error
 	if err != nil {
-		e := fmt.Errorf("failed to query user assets: %w, userId: %s", err, userId.String())
-		z.Error(e.Error())
+		e := fmt.Errorf("failed to create points ledger: %w, userId: %s, reason: %s", err, userId.String(), reason)
+		cmn.LoggerFrom(ctx).Error(e.Error())
 		return e
 	}
 
-	// 计算资产总积分
-	var assetPoints float64
-	for _, asset := range userAssets {
-		assetPoints += asset.MetaAssetValue * float64(asset.Count)
+	// 流水写入即计数，外层事务回滚时指标可能略高于实际
+	metrics.ObservePointsChange(pointsType, reason, delta)
+
+	return nil
+}
+
+// QueryUserPointsLedger 分页查询用户积分流水，按时间倒序
+// pointsType、reason 为空时不做对应过滤
+func QueryUserPointsLedger(ctx context.Context, userId uuid.UUID, pointsType, reason string, page, pageSize int) ([]cmn.TPointsLedger, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	query := cmn.GormDB.Model(&cmn.TPointsLedger{}).Where("user_id = ?", userId)
+	if pointsType != "" {
+		query = query.Where("points_type = ?", pointsType)
+	}
+	if reason != "" {
+		query = query.Where("reason = ?", reason)
 	}
 
-	// 查询现有积分记录
-	var userPoints cmn.TUserPoints
-	err = db.Where("user_id = ?", userId).First(&userPoints).Error
+	var total int64
+	err := query.Count(&total).Error
 	if err != nil {
-		return err
+		e := fmt.Errorf("failed to count points ledger: %w, userId: %s", err, userId.String())
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return nil, 0, e
 	}
 
-	// 累加积分到原有积分上
-	newTotalPoints := userPoints.DefaultPoints + assetPoints
+	var records []cmn.TPointsLedger
+	err = query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&records).Error
+	if err != nil {
+		e := fmt.Errorf("failed to query points ledger: %w, userId: %s", err, userId.String())
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return nil, 0, e
+	}
 
-	// 更新积分记录
-	err = db.Model(&userPoints).Update("default_points", newTotalPoints).Error
+	return records, total, nil
+}
+
+// UserBalance 用户某一积分类型的余额
+type UserBalance struct {
+	PointsType string  `json:"pointsType" gorm:"column:points_type"` // 积分类型编码
+	Name       string  `json:"name" gorm:"column:name"`              // 积分类型名称
+	Balance    float64 `json:"balance" gorm:"column:balance"`        // 积分余额
+}
+
+// QueryUserBalances 查询用户所有积分类型的余额
+func QueryUserBalances(ctx context.Context, userId uuid.UUID) ([]UserBalance, error) {
+	var balances []UserBalance
+	err := cmn.GormDB.Table("t_user_points_balance AS ub").
+		Select("ub.points_type, pt.name, ub.balance").
+		Joins("LEFT JOIN t_points_type AS pt ON ub.points_type = pt.code").
+		Where("ub.user_id = ?", userId).
+		Order("pt.id ASC").
+		Scan(&balances).Error
 	if err != nil {
-		e := fmt.Errorf("failed to update user points: %w, userId: %v", err, userId)
-		z.Error(e.Error())
-		return e
+		e := fmt.Errorf("failed to query user balances: %w, userId: %s", err, userId.String())
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return nil, e
 	}
 
-	return nil
+	return balances, nil
 }
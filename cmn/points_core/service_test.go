@@ -0,0 +1,383 @@
+package points_core
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// setupTestDB 连接配置文件中的数据库，无法连接时跳过测试
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	cmn.InitLogger(true)
+	cmn.InitConfig()
+
+	dbms := cmn.GetConfig().Dbms
+	dsn := fmt.Sprintf("user=%v password=%v dbname=%v host=%v port=%v sslmode=disable TimeZone=Asia/Shanghai",
+		dbms.User, dbms.Pwd, dbms.Db, dbms.Host, dbms.Port)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		t.Skipf("database not available: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Skipf("database not available: %v", err)
+	}
+	if err = sqlDB.Ping(); err != nil {
+		t.Skipf("database not available: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(50)
+
+	err = db.AutoMigrate(&cmn.TUser{}, &cmn.TUserPoints{}, &cmn.TPointsType{}, &cmn.TUserPointsBalance{}, &cmn.TPointsLot{}, &cmn.TPointsLedger{}, &cmn.TJobRun{}, &cmn.TJobRunItem{})
+	if err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	// 内置积分类型由数据库迁移创建，AutoMigrate 建表后需补充
+	err = db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}).
+		Create(&cmn.TPointsType{Code: PointsTypeDefault, Name: "默认积分", Status: "00"}).Error
+	if err != nil {
+		t.Fatalf("create default points type failed: %v", err)
+	}
+
+	cmn.GormDB = db
+	Init(context.Background())
+
+	return db
+}
+
+// createTestUser 创建测试用户及其积分记录，测试结束后清理
+func createTestUser(t *testing.T, db *gorm.DB, points float64) uuid.UUID {
+	t.Helper()
+
+	userId := uuid.New()
+	err := db.Create(&cmn.TUser{Id: userId, NickName: "points_core_test", MobilePhone: userId.String()[:11]}).Error
+	if err != nil {
+		t.Fatalf("create test user failed: %v", err)
+	}
+	err = db.Create(&cmn.TUserPointsBalance{UserId: userId, PointsType: PointsTypeDefault, Balance: points}).Error
+	if err != nil {
+		t.Fatalf("create test user points failed: %v", err)
+	}
+
+	t.Cleanup(func() {
+		db.Where("user_id = ?", userId).Delete(&cmn.TPointsLedger{})
+		db.Where("user_id = ?", userId).Delete(&cmn.TPointsLot{})
+		db.Where("user_id = ?", userId).Delete(&cmn.TUserPointsBalance{})
+		db.Where("id = ?", userId).Delete(&cmn.TUser{})
+	})
+
+	return userId
+}
+
+func queryBalance(t *testing.T, db *gorm.DB, userId uuid.UUID) float64 {
+	t.Helper()
+
+	var userBalance cmn.TUserPointsBalance
+	err := db.Where("user_id = ? AND points_type = ?", userId, PointsTypeDefault).First(&userBalance).Error
+	if err != nil {
+		t.Fatalf("query user points failed: %v", err)
+	}
+	return userBalance.Balance
+}
+
+func queryLedgerSum(t *testing.T, db *gorm.DB, userId uuid.UUID) float64 {
+	t.Helper()
+
+	var sum float64
+	err := db.Model(&cmn.TPointsLedger{}).
+		Select("COALESCE(SUM(delta), 0)").
+		Where("user_id = ?", userId).
+		Scan(&sum).Error
+	if err != nil {
+		t.Fatalf("query ledger sum failed: %v", err)
+	}
+	return sum
+}
+
+func TestConcurrentCreditDebit(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	userId := createTestUser(t, db, 0)
+
+	const workers = 100
+	var wg sync.WaitGroup
+	var failed atomic.Int64
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := Credit(ctx, db, userId, PointsTypeDefault, 3, ReasonCheckIn, ""); err != nil {
+				failed.Add(1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			// 扣减可能因余额不足失败，失败时不应影响余额
+			_, err := Debit(ctx, db, userId, PointsTypeDefault, 1, ReasonRaffle, "")
+			if err != nil && !errors.Is(err, ErrInsufficientBalance) {
+				failed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failed.Load() > 0 {
+		t.Fatalf("%d unexpected errors", failed.Load())
+	}
+
+	balance := queryBalance(t, db, userId)
+	if balance < 0 {
+		t.Fatalf("balance went negative: %.2f", balance)
+	}
+	ledgerSum := queryLedgerSum(t, db, userId)
+	if math.Abs(balance-ledgerSum) > 1e-9 {
+		t.Fatalf("balance %.2f does not match ledger sum %.2f", balance, ledgerSum)
+	}
+}
+
+func TestConcurrentDebitNoOverspend(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	const initial = 100
+	userId := createTestUser(t, db, initial)
+	// 初始余额不经过流水，补一条记录使流水与余额一致
+	if err := RecordPointsLedger(ctx, db, userId, PointsTypeDefault, initial, ReasonInit, "", initial); err != nil {
+		t.Fatalf("record init ledger failed: %v", err)
+	}
+
+	const workers = 200
+	var wg sync.WaitGroup
+	var succeeded, insufficient, failed atomic.Int64
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Debit(ctx, db, userId, PointsTypeDefault, 1, ReasonRaffle, "")
+			switch {
+			case err == nil:
+				succeeded.Add(1)
+			case errors.Is(err, ErrInsufficientBalance):
+				insufficient.Add(1)
+			default:
+				failed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failed.Load() > 0 {
+		t.Fatalf("%d unexpected errors", failed.Load())
+	}
+	if succeeded.Load() != initial {
+		t.Fatalf("expected %d successful debits, got %d", initial, succeeded.Load())
+	}
+	if insufficient.Load() != workers-initial {
+		t.Fatalf("expected %d insufficient balance errors, got %d", workers-initial, insufficient.Load())
+	}
+	if balance := queryBalance(t, db, userId); balance != 0 {
+		t.Fatalf("expected balance 0, got %.2f", balance)
+	}
+	if ledgerSum := queryLedgerSum(t, db, userId); ledgerSum != 0 {
+		t.Fatalf("expected ledger sum 0, got %.2f", ledgerSum)
+	}
+}
+
+func TestConcurrentTransfer(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	userA := createTestUser(t, db, 500)
+	userB := createTestUser(t, db, 500)
+
+	const workers = 50
+	var wg sync.WaitGroup
+	var failed atomic.Int64
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			err := Transfer(ctx, db, userA, userB, PointsTypeDefault, 7, ReasonActivity, "")
+			if err != nil && !errors.Is(err, ErrInsufficientBalance) {
+				failed.Add(1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			err := Transfer(ctx, db, userB, userA, PointsTypeDefault, 5, ReasonActivity, "")
+			if err != nil && !errors.Is(err, ErrInsufficientBalance) {
+				failed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failed.Load() > 0 {
+		t.Fatalf("%d unexpected errors", failed.Load())
+	}
+
+	balanceA := queryBalance(t, db, userA)
+	balanceB := queryBalance(t, db, userB)
+	if balanceA+balanceB != 1000 {
+		t.Fatalf("total points not conserved: %.2f + %.2f", balanceA, balanceB)
+	}
+	if balanceA < 0 || balanceB < 0 {
+		t.Fatalf("balance went negative: %.2f, %.2f", balanceA, balanceB)
+	}
+	if ledgerA := queryLedgerSum(t, db, userA); balanceA-500 != ledgerA {
+		t.Fatalf("user A ledger %.2f does not match balance change %.2f", ledgerA, balanceA-500)
+	}
+}
+
+func TestPointsExpiryFIFO(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	// 创建一个有效期为7天的积分类型
+	pointsType := cmn.TPointsType{Code: "test_expire_" + uuid.NewString()[:8], Name: "过期测试积分", ExpireDays: 7, Status: "00"}
+	if err := db.Create(&pointsType).Error; err != nil {
+		t.Fatalf("create points type failed: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("id = ?", pointsType.Id).Delete(&cmn.TPointsType{})
+	})
+
+	userId := createTestUser(t, db, 0)
+
+	// 获得两批积分，扣减时应先消耗较早的批次
+	for _, amount := range []float64{30, 50} {
+		if _, err := Credit(ctx, db, userId, pointsType.Code, amount, ReasonActivity, ""); err != nil {
+			t.Fatalf("credit failed: %v", err)
+		}
+	}
+	if _, err := Debit(ctx, db, userId, pointsType.Code, 40, ReasonRaffle, ""); err != nil {
+		t.Fatalf("debit failed: %v", err)
+	}
+
+	var lots []cmn.TPointsLot
+	if err := db.Where("user_id = ?", userId).Order("id ASC").Find(&lots).Error; err != nil {
+		t.Fatalf("query lots failed: %v", err)
+	}
+	if len(lots) != 2 || lots[0].Remaining != 0 || lots[1].Remaining != 40 {
+		t.Fatalf("unexpected lots after debit: %+v", lots)
+	}
+
+	// 将第二批积分置为已到期后结算
+	if err := db.Model(&cmn.TPointsLot{}).Where("id = ?", lots[1].Id).Update("expire_at", time.Now().Add(-time.Minute).UnixMilli()).Error; err != nil {
+		t.Fatalf("update lot expire_at failed: %v", err)
+	}
+	if _, errs := ExpireDuePointsLots(ctx, db); len(errs) > 0 {
+		t.Fatalf("expire due lots failed: %v", errs)
+	}
+
+	var userBalance cmn.TUserPointsBalance
+	if err := db.Where("user_id = ? AND points_type = ?", userId, pointsType.Code).First(&userBalance).Error; err != nil {
+		t.Fatalf("query balance failed: %v", err)
+	}
+	if userBalance.Balance != 0 {
+		t.Fatalf("expected balance 0 after expiry, got %.2f", userBalance.Balance)
+	}
+
+	var expireCount int64
+	if err := db.Model(&cmn.TPointsLedger{}).Where("user_id = ? AND reason = ? AND delta = ?", userId, ReasonExpire, -40).Count(&expireCount).Error; err != nil {
+		t.Fatalf("query expire ledger failed: %v", err)
+	}
+	if expireCount != 1 {
+		t.Fatalf("expected 1 expire ledger record, got %d", expireCount)
+	}
+
+	// 重复结算不应再次扣减
+	if _, errs := ExpireDuePointsLots(ctx, db); len(errs) > 0 {
+		t.Fatalf("expire due lots again failed: %v", errs)
+	}
+	if ledgerSum := queryLedgerSum(t, db, userId); ledgerSum != 0 {
+		t.Fatalf("expected ledger sum 0, got %.2f", ledgerSum)
+	}
+}
+
+func TestClaimJobRunExclusive(t *testing.T) {
+	db := setupTestDB(t)
+
+	jobName := "test_job_" + uuid.NewString()[:8]
+	bizDate := BizDateOf(time.Now())
+	t.Cleanup(func() {
+		db.Where("job_name = ?", jobName).Delete(&cmn.TJobRun{})
+	})
+
+	jobRun, err := claimJobRun(db, jobName, bizDate)
+	if err != nil {
+		t.Fatalf("first claim failed: %v", err)
+	}
+
+	// 运行中的任务不能被再次抢占
+	if _, err = claimJobRun(db, jobName, bizDate); !errors.Is(err, ErrJobRunInProgress) {
+		t.Fatalf("expected ErrJobRunInProgress, got %v", err)
+	}
+
+	// 结束后可以重新执行
+	finishJobRun(db, &jobRun)
+	if jobRun.Status != JobRunStatusSucceeded {
+		t.Fatalf("expected status %s, got %s", JobRunStatusSucceeded, jobRun.Status)
+	}
+	if _, err = claimJobRun(db, jobName, bizDate); err != nil {
+		t.Fatalf("claim after finish failed: %v", err)
+	}
+}
+
+func TestCheckAssetAccrualRerunnable(t *testing.T) {
+	ctx := context.Background()
+
+	// 超出补跑范围的业务日期无需查询数据库即被拒绝
+	old := BizDateOf(time.Now().AddDate(0, 0, -rerunAccrualLookbackN-1))
+	if err := CheckAssetAccrualRerunnable(ctx, nil, old); !errors.Is(err, ErrJobRunTooOld) {
+		t.Fatalf("expected ErrJobRunTooOld for %s, got %v", old, err)
+	}
+
+	db := setupTestDB(t)
+	bizDate := BizDateOf(time.Now().AddDate(0, 0, -rerunAccrualLookbackN))
+	db.Where("job_name = ? AND biz_date = ?", JobAssetAccrual, bizDate).Delete(&cmn.TJobRun{})
+	t.Cleanup(func() {
+		db.Where("job_name = ? AND biz_date = ?", JobAssetAccrual, bizDate).Delete(&cmn.TJobRun{})
+	})
+
+	// 没有运行记录的日期不能补跑
+	if err := CheckAssetAccrualRerunnable(ctx, db, bizDate); !errors.Is(err, ErrJobRunNotFound) {
+		t.Fatalf("expected ErrJobRunNotFound, got %v", err)
+	}
+
+	jobRun, err := claimJobRun(db, JobAssetAccrual, bizDate)
+	if err != nil {
+		t.Fatalf("claim job run failed: %v", err)
+	}
+	if err = CheckAssetAccrualRerunnable(ctx, db, bizDate); !errors.Is(err, ErrJobRunInProgress) {
+		t.Fatalf("expected ErrJobRunInProgress, got %v", err)
+	}
+
+	jobRun.LastError = "interrupted"
+	finishJobRun(db, &jobRun)
+	if err = CheckAssetAccrualRerunnable(ctx, db, bizDate); err != nil {
+		t.Fatalf("failed run should be rerunnable, got %v", err)
+	}
+
+	jobRun.LastError = ""
+	finishJobRun(db, &jobRun)
+	if err = CheckAssetAccrualRerunnable(ctx, db, bizDate); !errors.Is(err, ErrJobRunSucceeded) {
+		t.Fatalf("expected ErrJobRunSucceeded, got %v", err)
+	}
+}
@@ -41,4 +41,7 @@ type ReplyProto struct {
 
 	//SN, call order
 	SN int `json:"SN,omitempty"`
+
+	// RequestId, 请求ID，与响应头 X-Request-ID 一致，用于关联日志
+	RequestId string `json:"requestId,omitempty"`
 }
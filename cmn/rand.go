@@ -0,0 +1,69 @@
+package cmn
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+)
+
+// 随机数工具，均基于 crypto/rand，可用于验证码、会话ID与各类令牌
+// crypto/rand.Read 在系统随机源不可用时直接终止进程，因此以下函数不返回错误
+
+// RandBytes 生成 n 字节的随机数据
+func RandBytes(n int) []byte {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return b
+}
+
+// RandIntn 返回 [0, n) 内均匀分布的随机整数，n <= 0 时 panic
+func RandIntn(n int) int {
+	if n <= 0 {
+		panic("cmn: invalid argument to RandIntn")
+	}
+	if n == 1 {
+		return 0
+	}
+
+	// 拒绝采样：丢弃落在 n 的最大整数倍之外的值，避免取模偏差
+	limit := math.MaxUint64 - math.MaxUint64%uint64(n)
+	var b [8]byte
+	for {
+		_, _ = rand.Read(b[:])
+		v := binary.LittleEndian.Uint64(b[:])
+		if v < limit {
+			return int(v % uint64(n))
+		}
+	}
+}
+
+// RandDigits 生成指定位数的随机数字字符串，各位数字均匀分布
+func RandDigits(length int) string {
+	if length <= 0 {
+		return ""
+	}
+
+	// 单字节取值 0-249 时映射为数字，250-255 丢弃重取，避免取模偏差
+	const limit = 250
+	digits := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(digits) < length {
+		_, _ = rand.Read(buf)
+		for _, b := range buf {
+			if b >= limit {
+				continue
+			}
+			digits = append(digits, '0'+b%10)
+			if len(digits) == length {
+				break
+			}
+		}
+	}
+	return string(digits)
+}
+
+// RandToken 生成含 n 字节随机数据的 URL 安全令牌（base64url，无填充）
+func RandToken(n int) string {
+	return base64.RawURLEncoding.EncodeToString(RandBytes(n))
+}
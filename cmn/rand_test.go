@@ -0,0 +1,105 @@
+package cmn
+
+import (
+	"encoding/base64"
+	"math"
+	"testing"
+)
+
+func TestRandDigits(t *testing.T) {
+	for _, length := range []int{-1, 0, 1, 6, 100} {
+		got := RandDigits(length)
+		want := max(length, 0)
+		if len(got) != want {
+			t.Errorf("len(RandDigits(%d)) = %d, want %d", length, len(got), want)
+		}
+		for _, c := range got {
+			if c < '0' || c > '9' {
+				t.Fatalf("RandDigits(%d) = %q contains non-digit", length, got)
+			}
+		}
+	}
+}
+
+// chiSquare 计算各取值出现次数相对均匀分布的卡方统计量
+func chiSquare(counts []int, total int) float64 {
+	expected := float64(total) / float64(len(counts))
+	var chi float64
+	for _, c := range counts {
+		d := float64(c) - expected
+		chi += d * d / expected
+	}
+	return chi
+}
+
+func TestRandDigitsDistribution(t *testing.T) {
+	const samples = 200000
+	counts := make([]int, 10)
+	s := RandDigits(samples)
+	for i := 0; i < len(s); i++ {
+		counts[s[i]-'0']++
+	}
+
+	// 自由度 9，显著性水平 0.001 的临界值为 27.88
+	if chi := chiSquare(counts, samples); chi > 27.88 {
+		t.Errorf("digits not uniform: chi-square = %.2f, counts = %v", chi, counts)
+	}
+}
+
+func TestRandIntn(t *testing.T) {
+	if got := RandIntn(1); got != 0 {
+		t.Errorf("RandIntn(1) = %d, want 0", got)
+	}
+
+	// 取值不是 2 的幂时同样需要均匀分布
+	const n, samples = 7, 140000
+	counts := make([]int, n)
+	for i := 0; i < samples; i++ {
+		v := RandIntn(n)
+		if v < 0 || v >= n {
+			t.Fatalf("RandIntn(%d) = %d out of range", n, v)
+		}
+		counts[v]++
+	}
+	// 自由度 6，显著性水平 0.001 的临界值为 22.46
+	if chi := chiSquare(counts, samples); chi > 22.46 {
+		t.Errorf("RandIntn not uniform: chi-square = %.2f, counts = %v", chi, counts)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RandIntn(0) did not panic")
+		}
+	}()
+	RandIntn(0)
+}
+
+func TestRandToken(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		token := RandToken(32)
+		b, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil || len(b) != 32 {
+			t.Fatalf("RandToken(32) = %q decodes to %d bytes, err %v", token, len(b), err)
+		}
+		if seen[token] {
+			t.Fatalf("RandToken(32) returned duplicate %q", token)
+		}
+		seen[token] = true
+	}
+}
+
+func TestRandBytesEntropy(t *testing.T) {
+	// 各比特为 1 的比例应接近 1/2
+	const n = 1 << 16
+	ones := 0
+	for _, b := range RandBytes(n) {
+		for ; b != 0; b &= b - 1 {
+			ones++
+		}
+	}
+	ratio := float64(ones) / (n * 8)
+	if math.Abs(ratio-0.5) > 0.01 {
+		t.Errorf("bit ratio = %.4f, want about 0.5", ratio)
+	}
+}
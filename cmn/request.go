@@ -0,0 +1,116 @@
+package cmn
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// HeaderRequestId 请求ID头，客户端或网关传入时沿用，否则由服务生成，并在响应中回传
+const HeaderRequestId = "X-Request-ID"
+
+// maxRequestIdLength 传入请求ID的最大长度，超长或包含非法字符时重新生成
+const maxRequestIdLength = 128
+
+type ctxKey int
+
+const (
+	requestIdKey ctxKey = iota // 请求ID
+	loggerKey                  // 请求级日志
+)
+
+// RequestMiddleware 为每个请求分配请求ID，并将携带请求ID、方法与路由的日志放入 c.Request 的上下文
+// 应作为第一个业务中间件注册，认证中间件再通过 AddLogFields 追加用户信息
+func RequestMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(HeaderRequestId)
+		if !validRequestId(requestId) {
+			requestId = uuid.NewString()
+		}
+		c.Header(HeaderRequestId, requestId)
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		l := GetLogger().With(
+			zap.String("requestId", requestId),
+			zap.String("method", c.Request.Method),
+			zap.String("route", route),
+		)
+
+		ctx := context.WithValue(c.Request.Context(), requestIdKey, requestId)
+		c.Request = c.Request.WithContext(WithLogger(ctx, l))
+		c.Next()
+	}
+}
+
+// AddLogFields 为当前请求的日志追加字段，如认证通过后的用户ID
+func AddLogFields(c *gin.Context, fields ...zap.Field) {
+	l := LoggerFrom(c).With(fields...)
+	c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), l))
+}
+
+// WithLogger 返回携带日志的上下文
+func WithLogger(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// LoggerFrom 获取上下文中的请求级日志，不在请求中时返回全局日志
+// 可直接传入 *gin.Context
+func LoggerFrom(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return logger
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		if c.Request == nil {
+			return logger
+		}
+		ctx = c.Request.Context()
+	}
+	if l, ok := ctx.Value(loggerKey).(*zap.Logger); ok {
+		return l
+	}
+	return logger
+}
+
+// RequestIdFrom 获取上下文中的请求ID，不在请求中时返回空字符串
+// 可直接传入 *gin.Context
+func RequestIdFrom(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		if c.Request == nil {
+			return ""
+		}
+		ctx = c.Request.Context()
+	}
+	requestId, _ := ctx.Value(requestIdKey).(string)
+	return requestId
+}
+
+// Reply 以 JSON 返回响应，并回填当前请求的请求ID
+func Reply(c *gin.Context, code int, reply ReplyProto) {
+	reply.RequestId = RequestIdFrom(c)
+	c.JSON(code, reply)
+}
+
+// validRequestId 校验传入的请求ID，只接受字母、数字与 -_.: 组成的有限长度字符串，避免日志注入
+func validRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for i := 0; i < len(requestId); i++ {
+		ch := requestId[i]
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-' || ch == '_' || ch == '.' || ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
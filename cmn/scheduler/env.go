@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLocation = "Asia/Shanghai"  // 默认调度时区
+	defaultLeaseTTL = 2 * time.Minute  // 默认租约时长，执行期间会自动续约
+	minLeaseTTL     = 10 * time.Second // 租约时长下限，避免续约过于频繁
+)
+
+var (
+	location *time.Location // 调度时区
+	leaseTTL time.Duration  // 租约时长
+	holderId string         // 当前实例标识
+)
+
+var z *zap.Logger
+
+func Init(ctx context.Context) {
+	z = cmn.GetLogger()
+
+	cfg := cmn.GetConfig().Scheduler
+	locationName := cfg.Location
+	if locationName == "" {
+		locationName = defaultLocation
+	}
+	var err error
+	location, err = time.LoadLocation(locationName)
+	if err != nil {
+		z.Fatal("[ FAIL ] invalid scheduler.location", zap.String("location", locationName), zap.Error(err))
+	}
+
+	leaseTTL = defaultLeaseTTL
+	if cfg.LeaseTTL > 0 {
+		leaseTTL = cfg.LeaseTTL
+		if leaseTTL < minLeaseTTL {
+			z.Fatal("[ FAIL ] scheduler.leaseTTL is too short", zap.Duration("leaseTTL", leaseTTL), zap.Duration("min", minLeaseTTL))
+		}
+	}
+
+	holderId = newHolderId()
+
+	cmn.MiniLogger.Info("[ OK ] scheduler module initialized",
+		zap.String("location", location.String()),
+		zap.Duration("leaseTTL", leaseTTL),
+		zap.String("holder", holderId))
+}
+
+// newHolderId 生成实例标识：主机名:进程号:随机串，重启后会变化
+func newHolderId() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), uuid.NewString()[:8])
+}
@@ -0,0 +1,170 @@
+package scheduler
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 任务执行状态
+const (
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+// JobStatus 任务状态，合并数据库记录与本实例的调度信息
+type JobStatus struct {
+	cmn.TSchedulerJob
+	NextRunAt int64 `json:"nextRunAt"` // 本实例下一次计划执行时间，未调度时为0
+	Running   bool  `json:"running"`   // 本实例是否正在执行
+}
+
+// saveJobState 登记任务生效的配置，保留已有的暂停状态与执行记录
+func saveJobState(job *jobEntry) error {
+	state := cmn.TSchedulerJob{
+		JobName:   job.Name,
+		Spec:      job.spec,
+		Enable:    job.enable,
+		Singleton: job.Singleton,
+	}
+	err := cmn.GormDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "job_name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"spec", "enable", "singleton", "updated_at"}),
+	}).Select("job_name", "spec", "enable", "singleton", "updated_at").Create(&state).Error
+	if err != nil {
+		return fmt.Errorf("failed to save job state: %w, job: %s", err, job.Name)
+	}
+	return nil
+}
+
+// isJobPaused 查询任务是否被暂停，暂停状态在集群内共享
+func isJobPaused(jobName string) (bool, error) {
+	var paused []bool
+	err := cmn.GormDB.Model(&cmn.TSchedulerJob{}).
+		Where("job_name = ?", jobName).
+		Pluck("paused", &paused).Error
+	if err != nil {
+		return false, err
+	}
+	return len(paused) > 0 && paused[0], nil
+}
+
+// recordJobStart 记录任务开始执行
+func recordJobStart(jobName, trigger string, start time.Time) {
+	err := cmn.GormDB.Model(&cmn.TSchedulerJob{}).
+		Where("job_name = ?", jobName).
+		Updates(map[string]interface{}{
+			"last_run_at":  start.UnixMilli(),
+			"last_status":  JobStatusRunning,
+			"last_error":   "",
+			"last_run_by":  holderId,
+			"last_trigger": trigger,
+			"updated_at":   time.Now().UnixMilli(),
+		}).Error
+	if err != nil {
+		z.Error("failed to record job start", zap.String("job", jobName), zap.Error(err))
+	}
+}
+
+// recordJobFinish 记录任务执行结果
+func recordJobFinish(jobName string, duration time.Duration, jobErr error) {
+	updates := map[string]interface{}{
+		"last_duration": duration.Milliseconds(),
+		"last_status":   JobStatusSucceeded,
+		"run_count":     gorm.Expr("run_count + 1"),
+		"updated_at":    time.Now().UnixMilli(),
+	}
+	if jobErr != nil {
+		updates["last_status"] = JobStatusFailed
+		updates["last_error"] = jobErr.Error()
+		updates["fail_count"] = gorm.Expr("fail_count + 1")
+	}
+
+	err := cmn.GormDB.Model(&cmn.TSchedulerJob{}).
+		Where("job_name = ?", jobName).
+		Updates(updates).Error
+	if err != nil {
+		z.Error("failed to record job finish", zap.String("job", jobName), zap.Error(err))
+	}
+}
+
+// QueryJobs 查询所有已注册任务的状态，按任务名称排序
+func QueryJobs(ctx context.Context) ([]JobStatus, error) {
+	mu.Lock()
+	names := sortedJobNames()
+	entries := make([]*jobEntry, 0, len(names))
+	entryIds := make(map[string]cron.EntryID, len(names))
+	for _, name := range names {
+		entries = append(entries, jobs[name])
+		entryIds[name] = jobs[name].entryId
+	}
+	cronInstance := c
+	mu.Unlock()
+
+	var states []cmn.TSchedulerJob
+	err := cmn.GormDB.WithContext(ctx).Where("job_name IN ?", names).Find(&states).Error
+	if err != nil {
+		e := fmt.Errorf("failed to query job states: %w", err)
+		z.Error(e.Error())
+		return nil, e
+	}
+	stateMap := make(map[string]cmn.TSchedulerJob, len(states))
+	for _, state := range states {
+		stateMap[state.JobName] = state
+	}
+
+	result := make([]JobStatus, 0, len(entries))
+	for _, job := range entries {
+		state, ok := stateMap[job.Name]
+		if !ok {
+			// 调度器尚未启动时数据库中可能还没有记录
+			state = cmn.TSchedulerJob{JobName: job.Name, Spec: job.Spec, Enable: job.Enable, Singleton: job.Singleton}
+		}
+
+		status := JobStatus{TSchedulerJob: state, Running: job.running.Load()}
+		if cronInstance != nil && entryIds[job.Name] != 0 {
+			next := cronInstance.Entry(entryIds[job.Name]).Next
+			if !next.IsZero() {
+				status.NextRunAt = next.UnixMilli()
+			}
+		}
+		result = append(result, status)
+	}
+
+	return result, nil
+}
+
+// SetJobPaused 暂停或恢复任务的计划执行，对集群内所有实例生效
+func SetJobPaused(ctx context.Context, jobName string, paused bool) error {
+	mu.Lock()
+	_, ok := jobs[jobName]
+	mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, jobName)
+	}
+
+	result := cmn.GormDB.WithContext(ctx).Model(&cmn.TSchedulerJob{}).
+		Where("job_name = ?", jobName).
+		Updates(map[string]interface{}{
+			"paused":     paused,
+			"updated_at": time.Now().UnixMilli(),
+		})
+	if result.Error != nil {
+		e := fmt.Errorf("failed to set job paused: %w, job: %s", result.Error, jobName)
+		z.Error(e.Error())
+		return e
+	}
+	if result.RowsAffected == 0 {
+		return ErrSchedulerNotStarted
+	}
+
+	z.Info("scheduler job paused state changed", zap.String("job", jobName), zap.Bool("paused", paused))
+	return nil
+}
@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+// ensureLease 创建任务的租约记录（已存在时不做修改）
+func ensureLease(jobName string) error {
+	lease := cmn.TJobLease{JobName: jobName}
+	err := cmn.GormDB.Clauses(clause.OnConflict{DoNothing: true}).Create(&lease).Error
+	if err != nil {
+		return fmt.Errorf("failed to create job lease: %w, job: %s", err, jobName)
+	}
+	return nil
+}
+
+// acquireLease 抢占任务本次调度的租约
+// 仅当租约已过期且本次调度时间晚于上次执行的调度时间时才能抢占成功，
+// 单条 UPDATE 保证多个实例同时抢占时只有一个成功，且同一次调度不会被执行两次
+func acquireLease(jobName string, fireAt time.Time) (bool, error) {
+	now := time.Now().UnixMilli()
+	result := cmn.GormDB.Model(&cmn.TJobLease{}).
+		Where("job_name = ? AND lease_until < ? AND last_fire_at < ?", jobName, now, fireAt.UnixMilli()).
+		Updates(map[string]interface{}{
+			"holder":       holderId,
+			"lease_until":  now + leaseTTL.Milliseconds(),
+			"last_fire_at": fireAt.UnixMilli(),
+			"updated_at":   now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// startLeaseRenewal 在任务执行期间定期续约，返回停止续约的函数
+func startLeaseRenewal(ctx context.Context, jobName string) func() {
+	renewCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(leaseTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-ticker.C:
+				now := time.Now().UnixMilli()
+				result := cmn.GormDB.Model(&cmn.TJobLease{}).
+					Where("job_name = ? AND holder = ?", jobName, holderId).
+					Updates(map[string]interface{}{
+						"lease_until": now + leaseTTL.Milliseconds(),
+						"updated_at":  now,
+					})
+				if result.Error != nil {
+					z.Error("failed to renew job lease", zap.String("job", jobName), zap.Error(result.Error))
+					continue
+				}
+				if result.RowsAffected == 0 {
+					z.Warn("job lease lost", zap.String("job", jobName))
+				}
+			}
+		}
+	}()
+
+	return cancel
+}
+
+// releaseLease 任务执行结束后释放租约，保留 last_fire_at 以防止同一次调度被其他实例重复执行
+func releaseLease(jobName string) {
+	err := cmn.GormDB.Model(&cmn.TJobLease{}).
+		Where("job_name = ? AND holder = ?", jobName, holderId).
+		Updates(map[string]interface{}{
+			"lease_until": 0,
+			"updated_at":  time.Now().UnixMilli(),
+		}).Error
+	if err != nil {
+		z.Error("failed to release job lease", zap.String("job", jobName), zap.Error(err))
+	}
+}
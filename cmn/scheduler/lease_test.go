@@ -0,0 +1,147 @@
+package scheduler
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// setupTestDB 连接配置文件中的数据库，无法连接时跳过测试
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	cmn.InitLogger(true)
+	cmn.InitConfig()
+	z = zap.NewNop()
+
+	dbms := cmn.GetConfig().Dbms
+	dsn := fmt.Sprintf("user=%v password=%v dbname=%v host=%v port=%v sslmode=disable TimeZone=Asia/Shanghai",
+		dbms.User, dbms.Pwd, dbms.Db, dbms.Host, dbms.Port)
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		t.Skipf("database not available: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Skipf("database not available: %v", err)
+	}
+	if err = sqlDB.Ping(); err != nil {
+		t.Skipf("database not available: %v", err)
+	}
+
+	err = db.AutoMigrate(&cmn.TJobLease{})
+	if err != nil {
+		t.Fatalf("auto migrate failed: %v", err)
+	}
+
+	cmn.GormDB = db
+	return db
+}
+
+// newTestLease 创建测试用租约，测试结束后删除
+func newTestLease(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+
+	jobName := "test_job_" + uuid.NewString()[:8]
+	if err := ensureLease(jobName); err != nil {
+		t.Fatalf("ensure lease failed: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("job_name = ?", jobName).Delete(&cmn.TJobLease{})
+	})
+	return jobName
+}
+
+func queryLease(t *testing.T, db *gorm.DB, jobName string) cmn.TJobLease {
+	t.Helper()
+
+	var lease cmn.TJobLease
+	if err := db.Where("job_name = ?", jobName).First(&lease).Error; err != nil {
+		t.Fatalf("query lease failed: %v", err)
+	}
+	return lease
+}
+
+func TestAcquireLease(t *testing.T) {
+	db := setupTestDB(t)
+	leaseTTL = time.Minute
+	holderId = "instance-a"
+	jobName := newTestLease(t, db)
+
+	fireAt := time.Now().Truncate(time.Minute)
+	acquired, err := acquireLease(jobName, fireAt)
+	if err != nil || !acquired {
+		t.Fatalf("first acquire = %v, %v, want true", acquired, err)
+	}
+
+	// 租约未过期时其他实例不能抢占，即使是下一次调度
+	holderId = "instance-b"
+	acquired, err = acquireLease(jobName, fireAt.Add(time.Minute))
+	if err != nil || acquired {
+		t.Fatalf("acquire while leased = %v, %v, want false", acquired, err)
+	}
+
+	// 释放后同一次调度不能被再次执行
+	holderId = "instance-a"
+	releaseLease(jobName)
+	holderId = "instance-b"
+	acquired, err = acquireLease(jobName, fireAt)
+	if err != nil || acquired {
+		t.Fatalf("acquire same fire time after release = %v, %v, want false", acquired, err)
+	}
+
+	// 下一次调度可以被任一实例抢占
+	next := fireAt.Add(time.Minute)
+	acquired, err = acquireLease(jobName, next)
+	if err != nil || !acquired {
+		t.Fatalf("acquire next fire time = %v, %v, want true", acquired, err)
+	}
+	lease := queryLease(t, db, jobName)
+	if lease.Holder != "instance-b" || lease.LastFireAt != next.UnixMilli() {
+		t.Fatalf("lease = %+v, want holder instance-b and lastFireAt %d", lease, next.UnixMilli())
+	}
+}
+
+func TestLeaseRenewal(t *testing.T) {
+	db := setupTestDB(t)
+	leaseTTL = 300 * time.Millisecond
+	holderId = "instance-a"
+	jobName := newTestLease(t, db)
+
+	acquired, err := acquireLease(jobName, time.Now())
+	if err != nil || !acquired {
+		t.Fatalf("acquire = %v, %v, want true", acquired, err)
+	}
+	first := queryLease(t, db, jobName).LeaseUntil
+
+	stopRenew := startLeaseRenewal(context.Background(), jobName)
+	defer stopRenew()
+
+	// 续约后租约到期时间延后，持续执行期间不会被其他实例抢占
+	time.Sleep(2 * leaseTTL)
+	renewed := queryLease(t, db, jobName).LeaseUntil
+	if renewed <= first {
+		t.Fatalf("lease not renewed: leaseUntil %d, first %d", renewed, first)
+	}
+	// 续约协程读取 holderId，这里不切换实例；抢占条件只与租约是否过期有关
+	acquired, err = acquireLease(jobName, time.Now())
+	if err != nil || acquired {
+		t.Fatalf("acquire while renewing = %v, %v, want false", acquired, err)
+	}
+
+	// 停止续约并释放后，租约到期时间归零
+	stopRenew()
+	releaseLease(jobName)
+	if lease := queryLease(t, db, jobName); lease.LeaseUntil != 0 {
+		t.Fatalf("lease not released: %+v", lease)
+	}
+}
@@ -0,0 +1,373 @@
+package scheduler
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// JobFunc 定时任务执行函数，ctx 会在调度器停止时取消
+type JobFunc func(ctx context.Context) error
+
+// Job 定时任务定义
+type Job struct {
+	Name      string  // 任务名称，全局唯一，同时作为配置键 scheduler.jobs.<Name>
+	Spec      string  // 默认调度表达式（标准5段cron或 @daily、@every 30s 等），可被配置覆盖；单实例任务不支持 @every
+	Enable    bool    // 默认是否启用，可被配置覆盖
+	Singleton bool    // 是否集群内单实例执行；进程内状态维护类任务（如刷新本地token）应设为 false，其按计划执行的结果不写入任务状态
+	Fn        JobFunc // 执行函数
+}
+
+// 任务触发方式
+const (
+	TriggerSchedule = "schedule" // 按计划触发
+	TriggerManual   = "manual"   // 管理员手动触发
+)
+
+var (
+	ErrJobExists           = errors.New("job already registered")
+	ErrJobNotFound         = errors.New("job not found")
+	ErrJobRunning          = errors.New("job is running")
+	ErrSchedulerStarted    = errors.New("scheduler already started")
+	ErrSchedulerNotStarted = errors.New("scheduler not started")
+	ErrInvalidJob          = errors.New("invalid job definition")
+)
+
+// jobEntry 已注册任务及其运行时状态
+type jobEntry struct {
+	Job
+	spec    string       // 生效的调度表达式
+	enable  bool         // 生效的启用状态
+	entryId cron.EntryID // 调度成功后的 cron 条目ID
+	running atomic.Bool  // 本实例是否正在执行
+}
+
+var (
+	mu      sync.Mutex
+	jobs    = map[string]*jobEntry{}
+	c       *cron.Cron
+	started bool
+
+	runCtx    context.Context    // 任务执行使用的上下文
+	runCancel context.CancelFunc // 停止时取消所有执行中的任务
+	stopped   chan struct{}      // 调度器停止且执行中的任务全部退出后关闭
+	manualWg  sync.WaitGroup     // 手动触发的执行中任务
+)
+
+// Register 注册定时任务，需在 Start 之前调用
+func Register(job Job) error {
+	if job.Name == "" || job.Spec == "" || job.Fn == nil {
+		return fmt.Errorf("%w: name, spec and fn are required", ErrInvalidJob)
+	}
+	if _, err := parseSpec(job.Spec, job.Singleton); err != nil {
+		return fmt.Errorf("%w: job %s: %w", ErrInvalidJob, job.Name, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if started {
+		return fmt.Errorf("%w: cannot register job %s", ErrSchedulerStarted, job.Name)
+	}
+	if _, ok := jobs[job.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.Name)
+	}
+
+	jobs[job.Name] = &jobEntry{Job: job}
+	return nil
+}
+
+// Start 按配置启动所有已注册且启用的任务
+// ctx 取消时调度器停止调度并取消执行中的任务，需要等待任务退出时调用 Stop
+func Start(ctx context.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if started {
+		return ErrSchedulerStarted
+	}
+
+	c = cron.New(
+		cron.WithLocation(location),
+		cron.WithChain(cron.Recover(cronLogger{})),
+	)
+	runCtx, runCancel = context.WithCancel(ctx)
+
+	for _, name := range sortedJobNames() {
+		job := jobs[name]
+
+		// 配置覆盖默认调度表达式与启用状态
+		jobConfig := cmn.GetConfig().Scheduler.Jobs[name]
+		job.spec = job.Spec
+		if jobConfig.Spec != "" {
+			job.spec = jobConfig.Spec
+		}
+		job.enable = job.Enable
+		if jobConfig.Enable != nil {
+			job.enable = *jobConfig.Enable
+		}
+
+		schedule, err := parseSpec(job.spec, job.Singleton)
+		if err != nil {
+			runCancel()
+			return fmt.Errorf("invalid spec %q for job %s: %w", job.spec, name, err)
+		}
+
+		// 禁用的任务同样登记状态，便于管理端查看与手动触发
+		err = saveJobState(job)
+		if err != nil {
+			runCancel()
+			return err
+		}
+		if job.Singleton {
+			err = ensureLease(name)
+			if err != nil {
+				runCancel()
+				return err
+			}
+		}
+
+		if !job.enable {
+			z.Info("scheduler job disabled", zap.String("job", name))
+			continue
+		}
+
+		job.entryId = c.Schedule(schedule, cron.FuncJob(func() {
+			fireAt := scheduledTime(schedule, time.Now().In(location))
+			scheduledRun(runCtx, job, fireAt)
+		}))
+		z.Info("scheduler job scheduled", zap.String("job", name), zap.String("spec", job.spec), zap.Bool("singleton", job.Singleton))
+	}
+
+	c.Start()
+	started = true
+
+	go func() {
+		<-ctx.Done()
+		stopScheduling()
+	}()
+
+	return nil
+}
+
+// Stop 停止调度新的任务，取消执行中任务的上下文并等待其退出
+// 可重复调用，每次调用都会等待执行中的任务退出，等待时间受 ctx 控制，超时返回 ctx 的错误
+func Stop(ctx context.Context) error {
+	done := stopScheduling()
+	if done == nil {
+		return nil
+	}
+
+	select {
+	case <-done:
+		z.Info("scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		z.Warn("scheduler stop timed out, some jobs are still running")
+		return ctx.Err()
+	}
+}
+
+// stopScheduling 停止调度并取消执行中的任务，返回所有任务退出后关闭的通道；调度器未启动过时返回 nil
+func stopScheduling() <-chan struct{} {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if c == nil {
+		return nil
+	}
+	if started {
+		started = false
+		cronStopped := c.Stop()
+		runCancel()
+
+		stopped = make(chan struct{})
+		go func(done chan struct{}) {
+			<-cronStopped.Done()
+			manualWg.Wait()
+			close(done)
+		}(stopped)
+	}
+
+	return stopped
+}
+
+// Trigger 立即执行一次指定任务，忽略暂停状态与配置中的启用状态
+// 任务在后台执行，本实例或其他实例正在执行该任务时返回 ErrJobRunning
+func Trigger(name string) error {
+	mu.Lock()
+	job, ok := jobs[name]
+	if !ok {
+		mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if !started {
+		mu.Unlock()
+		return ErrSchedulerNotStarted
+	}
+	// 持有 mu 时登记，保证 Stop 能等待到本次执行
+	manualWg.Add(1)
+	ctx := runCtx
+	mu.Unlock()
+
+	release, err := acquireJob(ctx, job, time.Now())
+	if err != nil {
+		manualWg.Done()
+		return err
+	}
+
+	go func() {
+		defer manualWg.Done()
+		executeJob(ctx, job, TriggerManual, release)
+	}()
+	return nil
+}
+
+// scheduledRun 按计划执行一次任务；暂停的任务直接跳过
+func scheduledRun(ctx context.Context, job *jobEntry, fireAt time.Time) {
+	paused, err := isJobPaused(job.Name)
+	if err != nil {
+		z.Error("failed to query job paused state", zap.String("job", job.Name), zap.Error(err))
+		return
+	}
+	if paused {
+		z.Info("scheduler job paused, skip", zap.String("job", job.Name), zap.Time("fireAt", fireAt))
+		return
+	}
+
+	release, err := acquireJob(ctx, job, fireAt)
+	if err != nil {
+		if errors.Is(err, ErrJobRunning) {
+			z.Debug("scheduler job is running, skip", zap.String("job", job.Name), zap.Time("fireAt", fireAt))
+			return
+		}
+		z.Error("failed to acquire job", zap.String("job", job.Name), zap.Error(err))
+		return
+	}
+
+	executeJob(ctx, job, TriggerSchedule, release)
+}
+
+// acquireJob 获取任务的执行权，返回执行结束后需调用的释放函数
+// 本实例正在执行时直接失败；单实例任务还需抢占租约，抢占失败说明其他实例正在或已经执行本次调度
+func acquireJob(ctx context.Context, job *jobEntry, fireAt time.Time) (func(), error) {
+	if !job.running.CompareAndSwap(false, true) {
+		return nil, ErrJobRunning
+	}
+
+	if !job.Singleton {
+		return func() { job.running.Store(false) }, nil
+	}
+
+	acquired, err := acquireLease(job.Name, fireAt)
+	if err != nil {
+		job.running.Store(false)
+		return nil, fmt.Errorf("failed to acquire job lease: %w", err)
+	}
+	if !acquired {
+		job.running.Store(false)
+		return nil, ErrJobRunning
+	}
+
+	stopRenew := startLeaseRenewal(ctx, job.Name)
+	return func() {
+		stopRenew()
+		releaseLease(job.Name)
+		job.running.Store(false)
+	}, nil
+}
+
+// executeJob 执行任务并记录执行结果，结束后调用 release 释放执行权
+// 非单实例任务在每个实例上按计划执行，执行频率高且各实例共用一条状态记录，
+// 因此只记录手动触发的执行结果，按计划执行的结果仅写入日志
+func executeJob(ctx context.Context, job *jobEntry, trigger string, release func()) {
+	defer release()
+
+	persist := job.Singleton || trigger == TriggerManual
+	logRun := z.Info
+	if !persist {
+		logRun = z.Debug
+	}
+
+	start := time.Now()
+	logRun("scheduler job started", zap.String("job", job.Name), zap.String("trigger", trigger))
+	if persist {
+		recordJobStart(job.Name, trigger, start)
+	}
+
+	err := runJobFn(ctx, job)
+	duration := time.Since(start)
+	if persist {
+		recordJobFinish(job.Name, duration, err)
+	}
+
+	if err != nil {
+		z.Error("scheduler job failed", zap.String("job", job.Name), zap.Duration("duration", duration), zap.Error(err))
+		return
+	}
+	logRun("scheduler job finished", zap.String("job", job.Name), zap.Duration("duration", duration))
+}
+
+// runJobFn 调用任务函数，将 panic 转换为错误以便记录
+func runJobFn(ctx context.Context, job *jobEntry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panic: %v", r)
+		}
+	}()
+
+	return job.Fn(ctx)
+}
+
+// parseSpec 解析调度表达式
+// 单实例任务以调度时间在集群内去重，@every 的触发时间取决于各实例的启动时间，无法对齐，因此不允许
+func parseSpec(spec string, singleton bool) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := schedule.(cron.ConstantDelaySchedule); ok && singleton {
+		return nil, fmt.Errorf("spec %q: @every is not supported for singleton jobs", spec)
+	}
+	return schedule, nil
+}
+
+// scheduledTime 计算本次触发对应的调度时间
+// 各实例的触发时刻可能有毫秒级偏差，统一换算为调度表上的时间点，作为集群内去重的依据
+// 仅适用于按分钟对齐的调度表达式，parseSpec 保证单实例任务不会使用 @every
+func scheduledTime(schedule cron.Schedule, now time.Time) time.Time {
+	t := schedule.Next(now.Add(-time.Minute))
+	if t.After(now) {
+		return now.Truncate(time.Second)
+	}
+	return t
+}
+
+// sortedJobNames 返回按名称排序的任务名称列表，调用方需持有 mu
+func sortedJobNames() []string {
+	names := make([]string, 0, len(jobs))
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cronLogger 将 cron 内部日志输出到 zap
+type cronLogger struct{}
+
+func (cronLogger) Info(msg string, keysAndValues ...interface{}) {
+	z.Sugar().Debugw(msg, keysAndValues...)
+}
+
+func (cronLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	z.Sugar().Errorw(msg, append(keysAndValues, "error", err)...)
+}
@@ -0,0 +1,125 @@
+package scheduler
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+func TestScheduledTime(t *testing.T) {
+	loc, err := time.LoadLocation(defaultLocation)
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "fired on time",
+			spec: "0 0 * * *",
+			now:  time.Date(2026, 1, 2, 0, 0, 0, 0, loc),
+			want: time.Date(2026, 1, 2, 0, 0, 0, 0, loc),
+		},
+		{
+			name: "fired a few milliseconds late",
+			spec: "0 0 * * *",
+			now:  time.Date(2026, 1, 2, 0, 0, 0, 37*int(time.Millisecond), loc),
+			want: time.Date(2026, 1, 2, 0, 0, 0, 0, loc),
+		},
+		{
+			name: "fired seconds late",
+			spec: "15 * * * *",
+			now:  time.Date(2026, 1, 2, 3, 15, 42, 0, loc),
+			want: time.Date(2026, 1, 2, 3, 15, 0, 0, loc),
+		},
+		{
+			name: "descriptor spec",
+			spec: "@hourly",
+			now:  time.Date(2026, 1, 2, 3, 0, 1, 0, loc),
+			want: time.Date(2026, 1, 2, 3, 0, 0, 0, loc),
+		},
+		{
+			name: "fired more than a minute late falls back to now",
+			spec: "0 0 * * *",
+			now:  time.Date(2026, 1, 2, 0, 2, 5, 500*int(time.Millisecond), loc),
+			want: time.Date(2026, 1, 2, 0, 2, 5, 0, loc),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := cron.ParseStandard(tt.spec)
+			if err != nil {
+				t.Fatalf("parse spec %q: %v", tt.spec, err)
+			}
+			if got := scheduledTime(schedule, tt.now); !got.Equal(tt.want) {
+				t.Errorf("scheduledTime(%q, %v) = %v, want %v", tt.spec, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	tests := []struct {
+		spec      string
+		singleton bool
+		wantErr   bool
+	}{
+		{spec: "0 0 * * *", singleton: true},
+		{spec: "@daily", singleton: true},
+		{spec: "@every 30s", singleton: false},
+		{spec: "@every 30s", singleton: true, wantErr: true},
+		{spec: "not a spec", singleton: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		_, err := parseSpec(tt.spec, tt.singleton)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSpec(%q, %v) error = %v, wantErr %v", tt.spec, tt.singleton, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRegisterRejectsEverySingleton(t *testing.T) {
+	z = zap.NewNop()
+	err := Register(Job{
+		Name:      "test_every_singleton",
+		Spec:      "@every 30s",
+		Singleton: true,
+		Fn:        func(ctx context.Context) error { return nil },
+	})
+	if !errors.Is(err, ErrInvalidJob) {
+		t.Fatalf("Register() error = %v, want ErrInvalidJob", err)
+	}
+	if _, ok := jobs["test_every_singleton"]; ok {
+		t.Fatal("rejected job should not be registered")
+	}
+}
+
+func TestExecuteJobSkipsStateForScheduledNonSingleton(t *testing.T) {
+	z = zap.NewNop()
+	// 未连接数据库，写入任务状态会 panic
+	db := cmn.GormDB
+	cmn.GormDB = nil
+	t.Cleanup(func() { cmn.GormDB = db })
+
+	ran, released := false, false
+	job := &jobEntry{Job: Job{
+		Name: "test_non_singleton",
+		Fn: func(ctx context.Context) error {
+			ran = true
+			return nil
+		},
+	}}
+	executeJob(context.Background(), job, TriggerSchedule, func() { released = true })
+	if !ran || !released {
+		t.Fatalf("ran = %v, released = %v, want both true", ran, released)
+	}
+}
@@ -0,0 +1,136 @@
+package settings
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
+)
+
+const (
+	notifyChannel = "settings_changed" // 配置变更通知频道，payload 为配置键
+
+	listenRetryMin = time.Second      // 监听连接断开后的首次重连间隔
+	listenRetryMax = 30 * time.Second // 重连间隔上限
+)
+
+var z *zap.Logger
+
+func Init(ctx context.Context) {
+	z = cmn.GetLogger()
+
+	err := reloadAll(ctx)
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to load settings from config table", zap.Error(err))
+	}
+
+	cmn.GoBackground("settings-listener", func() {
+		listen(ctx)
+	})
+
+	cmn.MiniLogger.Info("[ OK ] settings module initialized", zap.Int("stored", len(stored)))
+}
+
+// reloadAll 重新读取配置表中的所有记录
+func reloadAll(ctx context.Context) error {
+	var rows []cmn.TCfgCommon
+	err := cmn.GormDB.WithContext(ctx).Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	byKey := make(map[string]cmn.TCfgCommon, len(rows))
+	for _, row := range rows {
+		byKey[row.Key] = row
+	}
+	apply(ctx, byKey)
+	return nil
+}
+
+// reloadKey 重新读取配置表中的单个配置项，记录已删除时保留当前值
+func reloadKey(ctx context.Context, key string) error {
+	var rows []cmn.TCfgCommon
+	err := cmn.GormDB.WithContext(ctx).Where("key = ?", key).Limit(1).Find(&rows).Error
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	apply(ctx, map[string]cmn.TCfgCommon{key: rows[0]})
+	return nil
+}
+
+// listen 监听其他实例的配置变更通知，连接断开后按退避间隔重连，直到 ctx 取消
+func listen(ctx context.Context) {
+	retry := listenRetryMin
+	for ctx.Err() == nil {
+		err := listenOnce(ctx, func() {
+			retry = listenRetryMin
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		z.Warn("settings listener disconnected, retrying", zap.Duration("after", retry), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+		retry = min(retry*2, listenRetryMax)
+	}
+}
+
+// listenOnce 占用一个数据库连接执行 LISTEN 并处理通知，onListening 在 LISTEN 成功后调用
+func listenOnce(ctx context.Context, onListening func()) error {
+	sqlDB, err := cmn.GormDB.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var listenErr error
+	_ = conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			listenErr = fmt.Errorf("unexpected driver connection %T", driverConn)
+			return nil
+		}
+		pgConn := stdConn.Conn()
+
+		_, listenErr = pgConn.Exec(ctx, "LISTEN "+notifyChannel)
+		if listenErr != nil {
+			return driver.ErrBadConn
+		}
+		onListening()
+
+		// 断线期间可能错过通知，重新连接后全量刷新
+		listenErr = reloadAll(ctx)
+		if listenErr != nil {
+			return driver.ErrBadConn
+		}
+
+		for {
+			notification, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				listenErr = err
+				// 处于 LISTEN 状态的连接不能归还连接池
+				return driver.ErrBadConn
+			}
+			err = reloadKey(ctx, notification.Payload)
+			if err != nil {
+				z.Error("failed to reload setting", zap.String("key", notification.Payload), zap.Error(err))
+			}
+		}
+	})
+	return listenErr
+}
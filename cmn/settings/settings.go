@@ -0,0 +1,359 @@
+package settings
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 运行时配置保存在 t_cfg_common 中，各模块在 Init 中注册配置项的类型、默认值与校验规则，
+// 通过类型化的读取函数获取当前值。值在内存中缓存，修改后通过 Postgres NOTIFY 通知所有实例刷新。
+
+// Kind 配置值类型
+type Kind string
+
+const (
+	KindString   Kind = "string"   // 字符串
+	KindInt      Kind = "int"      // 整数，解析为 int64
+	KindFloat    Kind = "float"    // 浮点数，解析为 float64
+	KindBool     Kind = "bool"     // 布尔值
+	KindDuration Kind = "duration" // 时长，如 30s、5m
+	KindJSON     Kind = "json"     // JSON，解析为 json.RawMessage
+)
+
+var (
+	ErrUnknownSetting = errors.New("setting not registered")
+	ErrInvalidValue   = errors.New("invalid setting value")
+	ErrInvalidDef     = errors.New("invalid setting definition")
+)
+
+// Definition 配置项定义
+type Definition struct {
+	Key         string                                     // 配置键，如 raffle.consumePointsValue
+	Kind        Kind                                       // 值类型
+	Default     string                                     // 默认值的字符串形式，配置表中没有该键时使用
+	Description string                                     // 说明，展示在管理端
+	Validate    func(ctx context.Context, value any) error // 解析后的值校验，可为空
+}
+
+// Setting 配置项的当前状态
+type Setting struct {
+	Key         string `json:"key"`         // 配置键
+	Kind        Kind   `json:"kind"`        // 值类型
+	Value       string `json:"value"`       // 当前值
+	Default     string `json:"default"`     // 默认值
+	Description string `json:"description"` // 说明
+	IsDefault   bool   `json:"isDefault"`   // 是否使用默认值（配置表中没有该键或值无效）
+	UpdatedAt   int64  `json:"updatedAt"`   // 配置表中的更新时间，使用默认值时为0
+}
+
+// entry 已注册配置项的缓存，放入 entries 后不再修改，值变化时整体替换，读取方可在释放锁后使用
+type entry struct {
+	def       Definition
+	raw       string // 当前值的字符串形式
+	value     any    // 解析后的当前值
+	isDefault bool
+	updatedAt int64
+}
+
+type subscription struct {
+	keys map[string]bool
+	fn   func(ctx context.Context)
+}
+
+var (
+	mu            sync.RWMutex
+	entries       = map[string]*entry{}         // 已注册的配置项
+	stored        = map[string]cmn.TCfgCommon{} // 配置表中的记录，包含未注册的键
+	subscriptions []subscription
+)
+
+// Register 注册配置项，模块在 Init 中调用
+// 配置表中已有的值无法解析或校验不通过时记录警告并使用默认值
+func Register(ctx context.Context, defs ...Definition) error {
+	for _, def := range defs {
+		if def.Key == "" {
+			return fmt.Errorf("%w: key is empty", ErrInvalidDef)
+		}
+		value, err := parse(def.Kind, def.Default)
+		if err != nil {
+			return fmt.Errorf("%w: default of %s: %v", ErrInvalidDef, def.Key, err)
+		}
+
+		mu.Lock()
+		_, exists := entries[def.Key]
+		row, hasRow := stored[def.Key]
+		mu.Unlock()
+		if exists {
+			return fmt.Errorf("%w: %s registered twice", ErrInvalidDef, def.Key)
+		}
+
+		e := &entry{def: def, raw: def.Default, value: value, isDefault: true}
+		if hasRow {
+			v, err := parseAndValidate(ctx, def, row.Value)
+			if err != nil {
+				z.Warn("invalid setting in config table, using default", zap.String("key", def.Key), zap.Error(err))
+			} else {
+				e.raw, e.value, e.isDefault, e.updatedAt = row.Value, v, false, row.UpdatedAt
+			}
+		}
+
+		mu.Lock()
+		entries[def.Key] = e
+		mu.Unlock()
+	}
+	return nil
+}
+
+// Subscribe 注册配置变更回调，keys 中任一配置项变化（包括其他实例修改）后调用一次
+func Subscribe(keys []string, fn func(ctx context.Context)) {
+	sub := subscription{keys: map[string]bool{}, fn: fn}
+	for _, key := range keys {
+		sub.keys[key] = true
+	}
+
+	mu.Lock()
+	subscriptions = append(subscriptions, sub)
+	mu.Unlock()
+}
+
+// get 读取已注册配置项的当前值，未注册或类型不符时返回 false
+func get(key string, kind Kind) (any, bool) {
+	mu.RLock()
+	e, ok := entries[key]
+	mu.RUnlock()
+	if !ok {
+		z.Error("setting not registered", zap.String("key", key))
+		return nil, false
+	}
+	if e.def.Kind != kind {
+		z.Error("setting kind mismatch", zap.String("key", key), zap.String("kind", string(e.def.Kind)), zap.String("want", string(kind)))
+		return nil, false
+	}
+	return e.value, true
+}
+
+// String 读取字符串配置
+func String(key string) string {
+	v, ok := get(key, KindString)
+	if !ok {
+		return ""
+	}
+	return v.(string)
+}
+
+// Int 读取整数配置
+func Int(key string) int64 {
+	v, ok := get(key, KindInt)
+	if !ok {
+		return 0
+	}
+	return v.(int64)
+}
+
+// Float 读取浮点数配置
+func Float(key string) float64 {
+	v, ok := get(key, KindFloat)
+	if !ok {
+		return 0
+	}
+	return v.(float64)
+}
+
+// Bool 读取布尔配置
+func Bool(key string) bool {
+	v, ok := get(key, KindBool)
+	if !ok {
+		return false
+	}
+	return v.(bool)
+}
+
+// Duration 读取时长配置
+func Duration(key string) time.Duration {
+	v, ok := get(key, KindDuration)
+	if !ok {
+		return 0
+	}
+	return v.(time.Duration)
+}
+
+// JSON 读取 JSON 配置并解析到 out
+func JSON(key string, out any) error {
+	v, ok := get(key, KindJSON)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+	return json.Unmarshal(v.(json.RawMessage), out)
+}
+
+// List 返回所有已注册配置项的当前状态，按配置键排序
+func List() []Setting {
+	mu.RLock()
+	result := make([]Setting, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, Setting{
+			Key:         e.def.Key,
+			Kind:        e.def.Kind,
+			Value:       e.raw,
+			Default:     e.def.Default,
+			Description: e.def.Description,
+			IsDefault:   e.isDefault,
+			UpdatedAt:   e.updatedAt,
+		})
+	}
+	mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// Set 校验并保存一组配置，全部通过校验后在同一事务中写入并通知其他实例
+func Set(ctx context.Context, db *gorm.DB, values map[string]string) error {
+	if db == nil {
+		db = cmn.GormDB
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	for key, raw := range values {
+		mu.RLock()
+		e, ok := entries[key]
+		mu.RUnlock()
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+		}
+		_, err := parseAndValidate(ctx, e.def, raw)
+		if err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	rows := make(map[string]cmn.TCfgCommon, len(values))
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			row := cmn.TCfgCommon{Key: key, Value: values[key]}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "key"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+			}).Create(&row).Error
+			if err != nil {
+				return err
+			}
+			rows[key] = row
+
+			// NOTIFY 在事务提交后才会送达
+			err = tx.Exec("SELECT pg_notify(?, ?)", notifyChannel, key).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		e := fmt.Errorf("failed to save settings: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error())
+		return e
+	}
+
+	apply(ctx, rows)
+	return nil
+}
+
+// apply 用配置表中的记录更新缓存，并通知值发生变化的配置项的订阅者
+func apply(ctx context.Context, rows map[string]cmn.TCfgCommon) {
+	changed := map[string]bool{}
+
+	mu.Lock()
+	for key, row := range rows {
+		stored[key] = row
+		e, ok := entries[key]
+		if !ok {
+			continue
+		}
+		if e.raw == row.Value {
+			entries[key] = &entry{def: e.def, raw: e.raw, value: e.value, updatedAt: row.UpdatedAt}
+			continue
+		}
+		value, err := parse(e.def.Kind, row.Value)
+		if err != nil {
+			z.Warn("invalid setting in config table, keep current value", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		entries[key] = &entry{def: e.def, raw: row.Value, value: value, updatedAt: row.UpdatedAt}
+		changed[key] = true
+	}
+	subs := append([]subscription(nil), subscriptions...)
+	mu.Unlock()
+
+	if len(changed) == 0 {
+		return
+	}
+	for key := range changed {
+		z.Info("setting changed", zap.String("key", key))
+	}
+	for _, sub := range subs {
+		for key := range changed {
+			if sub.keys[key] {
+				sub.fn(ctx)
+				break
+			}
+		}
+	}
+}
+
+// parseAndValidate 解析配置值并执行配置项的校验规则
+func parseAndValidate(ctx context.Context, def Definition, raw string) (any, error) {
+	value, err := parse(def.Kind, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidValue, def.Key, err)
+	}
+	if def.Validate != nil {
+		err = def.Validate(ctx, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidValue, def.Key, err)
+		}
+	}
+	return value, nil
+}
+
+// parse 按类型解析配置值
+func parse(kind Kind, raw string) (any, error) {
+	switch kind {
+	case KindString:
+		return raw, nil
+	case KindInt:
+		return strconv.ParseInt(raw, 10, 64)
+	case KindFloat:
+		return strconv.ParseFloat(raw, 64)
+	case KindBool:
+		return strconv.ParseBool(raw)
+	case KindDuration:
+		return time.ParseDuration(raw)
+	case KindJSON:
+		if !json.Valid([]byte(raw)) {
+			return nil, fmt.Errorf("not valid json")
+		}
+		return json.RawMessage(raw), nil
+	}
+	return nil, fmt.Errorf("unknown kind %q", kind)
+}
@@ -0,0 +1,231 @@
+package settings
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// resetState 清空已注册的配置项与订阅，测试结束后恢复
+func resetState(t *testing.T, rows ...cmn.TCfgCommon) {
+	t.Helper()
+
+	z = zap.NewNop()
+	mu.Lock()
+	entries = map[string]*entry{}
+	stored = map[string]cmn.TCfgCommon{}
+	for _, row := range rows {
+		stored[row.Key] = row
+	}
+	subscriptions = nil
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		mu.Lock()
+		entries = map[string]*entry{}
+		stored = map[string]cmn.TCfgCommon{}
+		subscriptions = nil
+		mu.Unlock()
+	})
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		kind    Kind
+		raw     string
+		want    any
+		wantErr bool
+	}{
+		{KindString, "abc", "abc", false},
+		{KindInt, "42", int64(42), false},
+		{KindInt, "4.2", nil, true},
+		{KindFloat, "0.5", 0.5, false},
+		{KindBool, "true", true, false},
+		{KindBool, "yes", nil, true},
+		{KindDuration, "1m30s", 90 * time.Second, false},
+		{KindDuration, "90", nil, true},
+		{KindJSON, `{"a":1}`, nil, false},
+		{KindJSON, `{"a":`, nil, true},
+		{Kind("unknown"), "x", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parse(tt.kind, tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parse(%s, %q) error = %v, wantErr %v", tt.kind, tt.raw, err, tt.wantErr)
+			continue
+		}
+		if tt.want != nil && got != tt.want {
+			t.Errorf("parse(%s, %q) = %v, want %v", tt.kind, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	resetState(t,
+		cmn.TCfgCommon{Key: "test.stored", Value: "7", UpdatedAt: 1000},
+		cmn.TCfgCommon{Key: "test.invalid", Value: "-1"},
+	)
+	ctx := context.Background()
+	nonNegative := func(ctx context.Context, value any) error {
+		if value.(int64) < 0 {
+			return fmt.Errorf("%d < 0", value.(int64))
+		}
+		return nil
+	}
+
+	err := Register(ctx,
+		Definition{Key: "test.default", Kind: KindDuration, Default: "5m"},
+		Definition{Key: "test.stored", Kind: KindInt, Default: "1", Validate: nonNegative},
+		Definition{Key: "test.invalid", Kind: KindInt, Default: "3", Validate: nonNegative},
+	)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	if got := Duration("test.default"); got != 5*time.Minute {
+		t.Errorf("Duration(test.default) = %v, want 5m", got)
+	}
+	if got := Int("test.stored"); got != 7 {
+		t.Errorf("Int(test.stored) = %d, want 7", got)
+	}
+	// 配置表中的值校验不通过时使用默认值
+	if got := Int("test.invalid"); got != 3 {
+		t.Errorf("Int(test.invalid) = %d, want 3", got)
+	}
+	// 类型不符或未注册时返回零值
+	if got := String("test.stored"); got != "" {
+		t.Errorf("String(test.stored) = %q, want empty", got)
+	}
+	if got := Bool("test.missing"); got {
+		t.Errorf("Bool(test.missing) = true, want false")
+	}
+
+	err = Register(ctx, Definition{Key: "test.default", Kind: KindString})
+	if !errors.Is(err, ErrInvalidDef) {
+		t.Errorf("duplicate Register() error = %v, want ErrInvalidDef", err)
+	}
+	err = Register(ctx, Definition{Key: "test.badDefault", Kind: KindBool, Default: "maybe"})
+	if !errors.Is(err, ErrInvalidDef) {
+		t.Errorf("Register() with invalid default error = %v, want ErrInvalidDef", err)
+	}
+
+	list := List()
+	if len(list) != 3 || list[0].Key != "test.default" || !list[0].IsDefault || list[2].Key != "test.stored" || list[2].UpdatedAt != 1000 {
+		t.Errorf("List() = %+v", list)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	resetState(t)
+	err := Register(context.Background(), Definition{Key: "test.json", Kind: KindJSON, Default: `{"limit":3,"tags":["a"]}`})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	var v struct {
+		Limit int      `json:"limit"`
+		Tags  []string `json:"tags"`
+	}
+	err = JSON("test.json", &v)
+	if err != nil || v.Limit != 3 || len(v.Tags) != 1 {
+		t.Errorf("JSON() = %+v, %v", v, err)
+	}
+	if err = JSON("test.missing", &v); !errors.Is(err, ErrUnknownSetting) {
+		t.Errorf("JSON(test.missing) error = %v, want ErrUnknownSetting", err)
+	}
+}
+
+func TestApplyNotifiesChangedKeys(t *testing.T) {
+	resetState(t)
+	ctx := context.Background()
+	err := Register(ctx,
+		Definition{Key: "test.a", Kind: KindInt, Default: "1"},
+		Definition{Key: "test.b", Kind: KindFloat, Default: "0.5"},
+	)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	var calledA, calledAB int
+	Subscribe([]string{"test.a"}, func(ctx context.Context) { calledA++ })
+	Subscribe([]string{"test.a", "test.b"}, func(ctx context.Context) { calledAB++ })
+
+	apply(ctx, map[string]cmn.TCfgCommon{
+		"test.a": {Key: "test.a", Value: "2"},
+		"test.b": {Key: "test.b", Value: "1.5"},
+	})
+	if Int("test.a") != 2 || Float("test.b") != 1.5 {
+		t.Fatalf("values not applied: a=%d b=%v", Int("test.a"), Float("test.b"))
+	}
+	// 同一次变更中多个配置项变化时每个订阅者只通知一次
+	if calledA != 1 || calledAB != 1 {
+		t.Errorf("subscribers called a=%d ab=%d, want 1 and 1", calledA, calledAB)
+	}
+
+	// 值未变化（如收到本实例发出的通知）时不通知
+	apply(ctx, map[string]cmn.TCfgCommon{"test.a": {Key: "test.a", Value: "2"}})
+	if calledA != 1 {
+		t.Errorf("subscriber called on unchanged value")
+	}
+
+	// 无法解析的值保留当前值
+	apply(ctx, map[string]cmn.TCfgCommon{"test.b": {Key: "test.b", Value: "abc"}})
+	if Float("test.b") != 1.5 || calledAB != 1 {
+		t.Errorf("invalid value applied: b=%v ab=%d", Float("test.b"), calledAB)
+	}
+}
+
+// 在 -race 下运行，确认 apply 更新缓存时与读取不存在数据竞争
+func TestApplyConcurrentWithGet(t *testing.T) {
+	resetState(t)
+	ctx := context.Background()
+	err := Register(ctx, Definition{Key: "test.a", Kind: KindInt, Default: "0"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 100; i++ {
+			apply(ctx, map[string]cmn.TCfgCommon{"test.a": {Key: "test.a", Value: fmt.Sprint(i)}})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if v := Int("test.a"); v < 0 || v > 100 {
+			t.Fatalf("unexpected value %d", v)
+		}
+	}
+	<-done
+	if Int("test.a") != 100 {
+		t.Errorf("test.a = %d, want 100", Int("test.a"))
+	}
+}
+
+func TestSetRejectsInvalidValues(t *testing.T) {
+	resetState(t)
+	ctx := context.Background()
+	err := Register(ctx, Definition{Key: "test.flag", Kind: KindBool, Default: "false"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// 校验在访问数据库之前完成
+	err = Set(ctx, nil, map[string]string{"test.flag": "true", "test.missing": "1"})
+	if !errors.Is(err, ErrUnknownSetting) {
+		t.Errorf("Set() error = %v, want ErrUnknownSetting", err)
+	}
+	err = Set(ctx, nil, map[string]string{"test.flag": "maybe"})
+	if !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Set() error = %v, want ErrInvalidValue", err)
+	}
+	if Bool("test.flag") {
+		t.Errorf("Bool(test.flag) = true after rejected Set")
+	}
+}
@@ -0,0 +1,133 @@
+package sms
+
+import (
+	"WudangMeta/cmn/metrics"
+	"sync"
+	"time"
+)
+
+// 服务商熔断状态
+const (
+	BreakerClosed   = "closed"    // 正常放行
+	BreakerOpen     = "open"      // 熔断中，跳过该服务商
+	BreakerHalfOpen = "half_open" // 熔断到期，放行一次试探请求
+)
+
+// breaker 单个服务商的熔断器
+// 连续失败次数达到阈值后熔断，熔断到期后放行一次试探请求，成功则恢复，失败则重新熔断
+type breaker struct {
+	mu            sync.Mutex
+	provider      string
+	threshold     int           // 熔断的连续失败次数，0表示不熔断
+	openTimeout   time.Duration // 熔断持续时间
+	state         string
+	failures      int  // 连续失败次数
+	probing       bool // 半开状态下是否已放行试探请求
+	openedAt      time.Time
+	lastError     string
+	lastFailureAt time.Time
+	lastSuccessAt time.Time
+}
+
+func newBreaker(provider string, threshold int, openTimeout time.Duration) *breaker {
+	b := &breaker{provider: provider, threshold: threshold, openTimeout: openTimeout, state: BreakerClosed}
+	metrics.SetSmsBreakerState(provider, metrics.SmsBreakerClosed)
+	return b
+}
+
+// configure 更新熔断参数，保留当前状态
+func (b *breaker) configure(threshold int, openTimeout time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold, b.openTimeout = threshold, openTimeout
+}
+
+// allow 返回是否可以向服务商发送请求，半开状态下同一时间只放行一个试探请求
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		if now.Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = false
+	}
+	if b.state == BreakerHalfOpen {
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// record 记录一次发送结果
+func (b *breaker) record(err error, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		b.lastSuccessAt = now
+		b.setState(BreakerClosed)
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	b.lastFailureAt = now
+	if b.state == BreakerHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.openedAt = now
+		b.setState(BreakerOpen)
+	}
+}
+
+// release 放弃本次请求且不计入结果（如调用方取消），半开状态下允许再次试探
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) setState(state string) {
+	b.state = state
+	switch state {
+	case BreakerOpen:
+		metrics.SetSmsBreakerState(b.provider, metrics.SmsBreakerOpen)
+	case BreakerHalfOpen:
+		metrics.SetSmsBreakerState(b.provider, metrics.SmsBreakerHalfOpen)
+	default:
+		metrics.SetSmsBreakerState(b.provider, metrics.SmsBreakerClosed)
+	}
+}
+
+// fill 将熔断器状态写入健康信息
+func (b *breaker) fill(h *ProviderHealth, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h.State = b.state
+	// 熔断已到期但尚未有请求试探时，视为半开
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.openTimeout {
+		h.State = BreakerHalfOpen
+	}
+	h.ConsecutiveFailures = b.failures
+	h.LastError = b.lastError
+	h.LastFailureAt = unixMilli(b.lastFailureAt)
+	h.LastSuccessAt = unixMilli(b.lastSuccessAt)
+	if b.state == BreakerOpen {
+		h.OpenedAt = unixMilli(b.openedAt)
+		h.OpenUntil = unixMilli(b.openedAt.Add(b.openTimeout))
+	}
+}
+
+// unixMilli 零值时间返回0
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
@@ -0,0 +1,85 @@
+package sms
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	errSend := errors.New("send failed")
+	b := newBreaker("test", 2, 30*time.Second)
+
+	if !b.allow(now) {
+		t.Fatalf("closed breaker should allow")
+	}
+	b.record(errSend, now)
+	if b.state != BreakerClosed {
+		t.Fatalf("state after 1 failure = %s, want closed", b.state)
+	}
+	b.record(errSend, now)
+	if b.state != BreakerOpen || b.allow(now.Add(10*time.Second)) {
+		t.Fatalf("breaker should open after 2 consecutive failures")
+	}
+
+	// 熔断到期后只放行一个试探请求
+	probeAt := now.Add(30 * time.Second)
+	if !b.allow(probeAt) {
+		t.Fatalf("breaker should allow a probe after open timeout")
+	}
+	if b.allow(probeAt) {
+		t.Fatalf("half-open breaker should allow only one probe")
+	}
+
+	// 试探失败重新熔断
+	b.record(errSend, probeAt)
+	if b.state != BreakerOpen || b.allow(probeAt.Add(time.Second)) {
+		t.Fatalf("failed probe should reopen breaker, state = %s", b.state)
+	}
+
+	// 试探被取消时允许再次试探
+	probeAt = probeAt.Add(30 * time.Second)
+	if !b.allow(probeAt) {
+		t.Fatalf("breaker should allow a probe after open timeout")
+	}
+	b.release()
+	if !b.allow(probeAt) {
+		t.Fatalf("released probe should allow another probe")
+	}
+
+	// 试探成功恢复
+	b.record(nil, probeAt)
+	if b.state != BreakerClosed || b.failures != 0 {
+		t.Fatalf("successful probe should close breaker, state = %s failures = %d", b.state, b.failures)
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := newBreaker("test", 0, time.Second)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		b.record(errors.New("send failed"), now)
+	}
+	if b.state != BreakerClosed || !b.allow(now) {
+		t.Errorf("breaker with threshold 0 should never open, state = %s", b.state)
+	}
+}
+
+func TestBreakerFill(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	b := newBreaker("test", 1, time.Minute)
+	b.record(errors.New("timeout"), now)
+
+	var h ProviderHealth
+	b.fill(&h, now.Add(time.Second))
+	if h.State != BreakerOpen || h.LastError != "timeout" || h.OpenUntil != now.Add(time.Minute).UnixMilli() || h.LastSuccessAt != 0 {
+		t.Errorf("fill() = %+v", h)
+	}
+
+	// 熔断到期但尚无试探请求时显示为半开
+	b.fill(&h, now.Add(time.Minute))
+	if h.State != BreakerHalfOpen {
+		t.Errorf("fill() after open timeout state = %s, want half_open", h.State)
+	}
+}
@@ -0,0 +1,100 @@
+package sms
+
+import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/settings"
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 发送验证码前的图形验证
+//
+// 风险升高时要求客户端先完成图形验证并携带票据。风险以 t_sms_send_log 中同一手机号或同一IP
+// 在窗口期内的发送请求数（含被限流拒绝的请求）衡量，达到阈值即要求图形验证。
+
+// 图形验证配置键
+const (
+	settingCaptchaMode          = "sms.captcha.mode"          // 图形验证模式
+	settingCaptchaRiskThreshold = "sms.captcha.riskThreshold" // 要求图形验证的请求数阈值
+	settingCaptchaRiskWindow    = "sms.captcha.riskWindow"    // 统计请求数的窗口期
+)
+
+// 图形验证模式
+const (
+	CaptchaModeOff    = "off"    // 不要求
+	CaptchaModeRisk   = "risk"   // 风险达到阈值时要求
+	CaptchaModeAlways = "always" // 始终要求
+)
+
+func registerCaptchaSettings(ctx context.Context) error {
+	return settings.Register(ctx,
+		settings.Definition{
+			Key:         settingCaptchaMode,
+			Kind:        settings.KindString,
+			Default:     CaptchaModeRisk,
+			Description: "发送短信验证码前的图形验证模式：off 不要求，risk 风险达到阈值时要求，always 始终要求",
+			Validate: func(ctx context.Context, value any) error {
+				switch value.(string) {
+				case CaptchaModeOff, CaptchaModeRisk, CaptchaModeAlways:
+					return nil
+				}
+				return fmt.Errorf("unknown captcha mode %q", value)
+			},
+		},
+		settings.Definition{
+			Key:         settingCaptchaRiskThreshold,
+			Kind:        settings.KindInt,
+			Default:     "3",
+			Description: "窗口期内同一手机号或IP的发送请求数达到该值后要求图形验证",
+			Validate: func(ctx context.Context, value any) error {
+				if value.(int64) < 1 {
+					return fmt.Errorf("threshold %d < 1", value.(int64))
+				}
+				return nil
+			},
+		},
+		settings.Definition{
+			Key:         settingCaptchaRiskWindow,
+			Kind:        settings.KindDuration,
+			Default:     "1h",
+			Description: "统计发送请求数的窗口期",
+			Validate: func(ctx context.Context, value any) error {
+				if value.(time.Duration) < time.Minute {
+					return fmt.Errorf("window %v < 1m", value)
+				}
+				return nil
+			},
+		},
+	)
+}
+
+// CaptchaRequired 返回向手机号发送验证码前是否需要图形验证
+func CaptchaRequired(ctx context.Context, db *gorm.DB, phone, ip string) (bool, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	switch settings.String(settingCaptchaMode) {
+	case CaptchaModeOff:
+		return false, nil
+	case CaptchaModeAlways:
+		return true, nil
+	}
+
+	threshold := settings.Int(settingCaptchaRiskThreshold)
+	since := time.Now().Add(-settings.Duration(settingCaptchaRiskWindow)).UnixMilli()
+	var requests int64
+	err := db.WithContext(ctx).Model(&cmn.TSmsSendLog{}).
+		Where("(mobile_phone = ? OR ip = ?) AND created_at > ?", phone, ip, since).
+		Count(&requests).Error
+	if err != nil {
+		e := fmt.Errorf("failed to evaluate sms captcha risk: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error(), zap.String("phone", phone), zap.String("ip", ip))
+		return false, e
+	}
+	return requests >= threshold, nil
+}
@@ -0,0 +1,239 @@
+package sms
+
+import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/settings"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 验证码用途，不同用途的验证码互不通用
+const (
+	PurposeLogin       = "login"        // 登录
+	PurposeBindPhone   = "bind_phone"   // 绑定手机号
+	PurposeChangePhone = "change_phone" // 更换手机号
+)
+
+// 验证码状态
+const (
+	CodeStatusValid      = "00" // 有效
+	CodeStatusUsed       = "01" // 已使用
+	CodeStatusSuperseded = "02" // 已被新验证码取代
+	CodeStatusLocked     = "03" // 错误次数过多已锁定
+	CodeStatusSendFailed = "04" // 发送失败
+)
+
+// 验证码配置键
+const (
+	settingCodeTtl         = "sms.code.ttl"         // 验证码有效期
+	settingCodeMaxAttempts = "sms.code.maxAttempts" // 单个验证码允许的验证失败次数
+)
+
+const (
+	JobPurgeSmsCodes = "sms_code_purge" // 清理过期验证码的定时任务
+
+	purgeBatchSize = 5000 // 清理过期验证码时每批删除的记录数
+)
+
+var (
+	ErrInvalidPurpose = errors.New("invalid sms code purpose")
+	ErrCodeNotFound   = errors.New("sms code not found or expired")
+	ErrCodeMismatch   = errors.New("sms code mismatch")
+	ErrCodeLocked     = errors.New("sms code locked after too many failed attempts")
+	ErrInTransaction  = errors.New("sms code must not be verified inside a transaction")
+)
+
+// IsValidPurpose 检查验证码用途是否合法
+func IsValidPurpose(purpose string) bool {
+	switch purpose {
+	case PurposeLogin, PurposeBindPhone, PurposeChangePhone:
+		return true
+	}
+	return false
+}
+
+func registerCodeSettings(ctx context.Context) error {
+	return settings.Register(ctx,
+		settings.Definition{
+			Key:         settingCodeTtl,
+			Kind:        settings.KindDuration,
+			Default:     "5m",
+			Description: "短信验证码有效期",
+			Validate: func(ctx context.Context, value any) error {
+				if value.(time.Duration) < time.Minute {
+					return fmt.Errorf("ttl %v < 1m", value)
+				}
+				return nil
+			},
+		},
+		settings.Definition{
+			Key:         settingCodeMaxAttempts,
+			Kind:        settings.KindInt,
+			Default:     "5",
+			Description: "单个短信验证码允许的验证失败次数，达到后验证码失效",
+			Validate: func(ctx context.Context, value any) error {
+				if value.(int64) < 1 {
+					return fmt.Errorf("max attempts %d < 1", value.(int64))
+				}
+				return nil
+			},
+		},
+	)
+}
+
+// CodeTtl 返回验证码有效期
+func CodeTtl() time.Duration {
+	return settings.Duration(settingCodeTtl)
+}
+
+// SaveCode 在发送前保存验证码，同一手机号同一用途之前未使用的验证码全部失效，返回验证码记录ID
+// 发送失败时调用 MarkCodeSendFailed 使其失效
+func SaveCode(ctx context.Context, db *gorm.DB, phone, purpose, code string) (int64, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+	if !IsValidPurpose(purpose) {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidPurpose, purpose)
+	}
+
+	record := cmn.TSmsCodes{
+		MobilePhone: phone,
+		Code:        code,
+		Purpose:     purpose,
+		Status:      CodeStatusValid,
+		ExpiresAt:   time.Now().Add(CodeTtl()).UnixMilli(),
+	}
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&cmn.TSmsCodes{}).
+			Where("mobile_phone = ? AND purpose = ? AND status = ?", phone, purpose, CodeStatusValid).
+			Update("status", CodeStatusSuperseded).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&record).Error
+	})
+	if err != nil {
+		e := fmt.Errorf("failed to save sms code: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error(), zap.String("phone", phone), zap.String("purpose", purpose))
+		return 0, e
+	}
+	return record.Id, nil
+}
+
+// MarkCodeSendFailed 发送失败后使验证码失效，写入失败只记录日志，验证码到期后同样失效
+func MarkCodeSendFailed(ctx context.Context, db *gorm.DB, codeId int64) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	err := db.WithContext(ctx).Model(&cmn.TSmsCodes{}).
+		Where("id = ? AND status = ?", codeId, CodeStatusValid).
+		Update("status", CodeStatusSendFailed).Error
+	if err != nil {
+		cmn.LoggerFrom(ctx).Error("failed to mark sms code send failed", zap.Int64("codeId", codeId), zap.Error(err))
+	}
+}
+
+// VerifyCode 校验并使用验证码，成功后验证码失效
+// 校验失败时累计错误次数，达到上限后验证码锁定，需重新获取
+// 错误次数须在本函数的事务中立即提交，传入事务时会成为保存点，调用方回滚会丢弃错误次数，因此拒绝事务参数
+func VerifyCode(ctx context.Context, db *gorm.DB, phone, purpose, code string) error {
+	if db == nil {
+		db = cmn.GormDB
+	}
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		cmn.LoggerFrom(ctx).Error(ErrInTransaction.Error(), zap.String("phone", phone), zap.String("purpose", purpose))
+		return ErrInTransaction
+	}
+
+	var result error
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁定当前有效的验证码，避免并发请求绕过错误次数限制
+		var smsCode cmn.TSmsCodes
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("mobile_phone = ? AND purpose = ? AND status = ? AND expires_at > ?", phone, purpose, CodeStatusValid, time.Now().UnixMilli()).
+			Order("id DESC").
+			First(&smsCode).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result = ErrCodeNotFound
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if subtle.ConstantTimeCompare([]byte(smsCode.Code), []byte(code)) == 1 {
+			return tx.Model(&smsCode).Update("status", CodeStatusUsed).Error
+		}
+
+		attempts, status := failedAttempt(smsCode.Attempts, settings.Int(settingCodeMaxAttempts))
+		result = ErrCodeMismatch
+		if status == CodeStatusLocked {
+			result = ErrCodeLocked
+		}
+		return tx.Model(&smsCode).Updates(map[string]any{"attempts": attempts, "status": status}).Error
+	})
+	if err != nil {
+		e := fmt.Errorf("failed to verify sms code: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error(), zap.String("phone", phone), zap.String("purpose", purpose))
+		return e
+	}
+	if result != nil {
+		cmn.LoggerFrom(ctx).Warn("sms code verification failed", zap.String("phone", phone), zap.String("purpose", purpose), zap.Error(result))
+	}
+	return result
+}
+
+// failedAttempt 返回验证失败后的错误次数与验证码状态
+func failedAttempt(attempts int, maxAttempts int64) (int, string) {
+	attempts++
+	if int64(attempts) >= maxAttempts {
+		return attempts, CodeStatusLocked
+	}
+	return attempts, CodeStatusValid
+}
+
+// PurgeExpiredCodes 分批删除已过期的验证码，返回删除的记录数
+func PurgeExpiredCodes(ctx context.Context, db *gorm.DB) (int64, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	var total int64
+	now := time.Now().UnixMilli()
+	for ctx.Err() == nil {
+		result := db.WithContext(ctx).Exec(
+			`DELETE FROM "`+cmn.TSmsCodesName+`" WHERE id IN (SELECT id FROM "`+cmn.TSmsCodesName+`" WHERE expires_at <= ? LIMIT ?)`,
+			now, purgeBatchSize)
+		if result.Error != nil {
+			e := fmt.Errorf("failed to purge expired sms codes: %w", result.Error)
+			z.Error(e.Error(), zap.Int64("deleted", total))
+			return total, e
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < purgeBatchSize {
+			break
+		}
+	}
+	return total, ctx.Err()
+}
+
+// smsCodePurger 清理过期验证码的定时任务
+func smsCodePurger(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		deleted, err := PurgeExpiredCodes(ctx, db)
+		if err != nil {
+			return err
+		}
+		z.Info("expired sms codes purged", zap.Int64("deleted", deleted))
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+package sms
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestIsValidPurpose(t *testing.T) {
+	for _, purpose := range []string{PurposeLogin, PurposeBindPhone, PurposeChangePhone} {
+		if !IsValidPurpose(purpose) {
+			t.Errorf("IsValidPurpose(%q) = false, want true", purpose)
+		}
+	}
+	for _, purpose := range []string{"", "LOGIN", "reset"} {
+		if IsValidPurpose(purpose) {
+			t.Errorf("IsValidPurpose(%q) = true, want false", purpose)
+		}
+	}
+}
+
+func TestFailedAttempt(t *testing.T) {
+	tests := []struct {
+		attempts    int
+		maxAttempts int64
+		want        int
+		wantStatus  string
+	}{
+		{0, 5, 1, CodeStatusValid},
+		{3, 5, 4, CodeStatusValid},
+		{4, 5, 5, CodeStatusLocked},
+		{0, 1, 1, CodeStatusLocked},
+	}
+	for _, tt := range tests {
+		got, status := failedAttempt(tt.attempts, tt.maxAttempts)
+		if got != tt.want || status != tt.wantStatus {
+			t.Errorf("failedAttempt(%d, %d) = %d, %s, want %d, %s", tt.attempts, tt.maxAttempts, got, status, tt.want, tt.wantStatus)
+		}
+	}
+}
+
+func TestCodeTtl(t *testing.T) {
+	err := registerCodeSettings(context.Background())
+	if err != nil {
+		t.Fatalf("registerCodeSettings() error = %v", err)
+	}
+
+	if got := CodeTtl(); got != 5*time.Minute {
+		t.Errorf("CodeTtl() = %v, want 5m", got)
+	}
+}
+
+func TestVerifyCodeRejectsTransaction(t *testing.T) {
+	cmn.InitLogger(true)
+
+	// 事务中的错误次数会随调用方回滚丢失，校验前即拒绝，不会访问数据库
+	tx := &gorm.DB{Statement: &gorm.Statement{ConnPool: &sql.Tx{}}}
+	err := VerifyCode(context.Background(), tx, "13800000000", PurposeLogin, "123456")
+	if !errors.Is(err, ErrInTransaction) {
+		t.Fatalf("VerifyCode() error = %v, want ErrInTransaction", err)
+	}
+}
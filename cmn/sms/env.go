@@ -2,54 +2,61 @@ package sms
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/scheduler"
+	"context"
+	"reflect"
 
-	"github.com/spf13/viper"
-	v20210111 "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
 	"go.uber.org/zap"
 )
 
-var (
-	z        *zap.Logger
-	platform string
+var z *zap.Logger
 
-	juheConfig JuheConfig
-
-	tecentConfig TecentConfig
-	tecentClient *v20210111.Client
+func Init(ctx context.Context) {
+	z = cmn.GetLogger()
 
-	shxConfig ShxTongConfig
-)
+	err := registerLimitSettings(ctx)
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register sms limit settings", zap.Error(err))
+	}
+	err = registerCodeSettings(ctx)
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register sms code settings", zap.Error(err))
+	}
+	err = registerCaptchaSettings(ctx)
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register sms captcha settings", zap.Error(err))
+	}
 
-func Init() {
-	z = cmn.GetLogger()
+	err = scheduler.Register(scheduler.Job{
+		Name:      JobPurgeSmsCodes,
+		Spec:      "30 3 * * *",
+		Enable:    true,
+		Singleton: true,
+		Fn:        smsCodePurger(cmn.GormDB),
+	})
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register sms code purge job", zap.Error(err))
+	}
 
 	// 若果没有开启短信服务，则不进行初始化
-	enable := viper.GetBool("sms.enable")
-	if !enable {
+	cfg := cmn.GetConfig().Sms
+	if !cfg.Enable {
 		cmn.MiniLogger.Info("[ -- ] sms module is disabled")
 		return
 	}
 
-	platform = viper.GetString("sms.platform")
-	switch platform {
-	case "juhe":
-		err := initJuheConfig()
-		if err != nil {
-			z.Fatal("[ FAIL ] init juhe sms config", zap.Error(err))
-		}
-	case "tecent":
-		err := initTecentConfig()
-		if err != nil {
-			z.Fatal("[ FAIL ] init tecent sms config", zap.Error(err))
-		}
-	case "shx":
-		err := initShxTongConfig()
-		if err != nil {
-			z.Fatal("[ FAIL ] init shx sms config", zap.Error(err))
-		}
-	default:
-		z.Fatal("[ FAIL ] sms platform is not supported", zap.String("platform", platform))
+	err = loadProviders(cfg)
+	if err != nil {
+		z.Fatal("[ FAIL ] init sms providers", zap.Error(err))
 	}
 
-	cmn.MiniLogger.Info("[ OK ] sms module initialed", zap.String("platform", platform))
+	// 服务商、超时与熔断配置修改后热更新，同名服务商保留熔断状态
+	cmn.SubscribeConfig("sms", func(ctx context.Context, old, new *cmn.Config) error {
+		if reflect.DeepEqual(old.Sms, new.Sms) {
+			return nil
+		}
+		return loadProviders(new.Sms)
+	})
+
+	cmn.MiniLogger.Info("[ OK ] sms module initialed", zap.Strings("providers", ProviderNames()))
 }
@@ -1,68 +1,75 @@
 package sms
 
 import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/tracing"
 	"fmt"
 
-	"github.com/spf13/viper"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	tecentSMS "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
 	"go.uber.org/zap"
 )
 
-// 初始化聚合平台配置
-func initJuheConfig() error {
+// 初始化聚合平台服务
+func newJuheService(data cmn.SmsDataConfig) (Service, error) {
 	// 初始化配置信息
-	juheConfig.ApiUrl = viper.GetString("sms.data.apiUrl")
+	var juheConfig JuheConfig
+	juheConfig.ApiUrl = data.ApiUrl
 	if juheConfig.ApiUrl == "" {
 		z.Error("juhe apiUrl is empty")
-		return fmt.Errorf("juhe apiUrl is empty")
+		return nil, fmt.Errorf("juhe apiUrl is empty")
 	}
-	juheConfig.Key = viper.GetString("sms.data.key")
+	juheConfig.Key = data.Key
 	if juheConfig.Key == "" {
 		z.Error("juhe key is empty")
-		return fmt.Errorf("juhe key is empty")
+		return nil, fmt.Errorf("juhe key is empty")
 	}
-	return nil
+	juheConfig.TemplateId = data.TemplateId
+	if juheConfig.TemplateId == "" {
+		z.Error("juhe templateId is empty")
+		return nil, fmt.Errorf("juhe templateId is empty")
+	}
+
+	return &juheServiceImpl{conf: juheConfig}, nil
 }
 
-// 初始化腾讯云平台配置
-func initTecentConfig() error {
+// 初始化腾讯云平台服务
+func newTecentService(data cmn.SmsDataConfig) (Service, error) {
 	// 初始化配置信息
-	tecentConfig.AppID = viper.GetString("sms.data.appId")
+	var tecentConfig TecentConfig
+	tecentConfig.AppID = data.AppId
 	if tecentConfig.AppID == "" {
 		z.Error("tecent appId is empty")
-		return fmt.Errorf("tecent appId is empty")
+		return nil, fmt.Errorf("tecent appId is empty")
 	}
-	tecentConfig.AppKey = viper.GetString("sms.data.appKey")
+	tecentConfig.AppKey = data.AppKey
 	if tecentConfig.AppKey == "" {
 		z.Error("tecent appKey is empty")
-		return fmt.Errorf("tecent appKey is empty")
+		return nil, fmt.Errorf("tecent appKey is empty")
 	}
-	tecentConfig.TemplateID = viper.GetString("sms.data.templateId")
+	tecentConfig.TemplateID = data.TemplateId
 	if tecentConfig.TemplateID == "" {
 		z.Error("tecent templateId is empty")
-		return fmt.Errorf("tecent templateId is empty")
+		return nil, fmt.Errorf("tecent templateId is empty")
 	}
-	tecentConfig.SignName = viper.GetString("sms.data.signName")
+	tecentConfig.SignName = data.SignName
 	if tecentConfig.SignName == "" {
 		z.Error("tecent signName is empty")
-		return fmt.Errorf("tecent signName is empty")
+		return nil, fmt.Errorf("tecent signName is empty")
 	}
 
-	secretID := viper.GetString("sms.data.secretId")
+	secretID := data.SecretId
 	if secretID == "" {
 		z.Error("tecent secretId is empty")
-		return fmt.Errorf("tecent secretId is empty")
+		return nil, fmt.Errorf("tecent secretId is empty")
 	}
-	secretKey := viper.GetString("sms.data.secretKey")
+	secretKey := data.SecretKey
 	if secretKey == "" {
 		z.Error("tecent secretKey is empty")
-		return fmt.Errorf("tecent secretKey is empty")
+		return nil, fmt.Errorf("tecent secretKey is empty")
 	}
 
-	var err error
-
 	// 初始化客户端
 	credential := common.NewCredential(
 		secretID,
@@ -73,36 +80,39 @@ func initTecentConfig() error {
 	cpf.HttpProfile.ReqTimeout = 10 // 请求超时时间，单位为秒(默认60秒)
 	cpf.HttpProfile.Endpoint = "sms.tencentcloudapi.com"
 	cpf.SignMethod = "HmacSHA1"
-	tecentClient, err = tecentSMS.NewClient(credential, "ap-guangzhou", cpf)
+	tecentClient, err := tecentSMS.NewClient(credential, "ap-guangzhou", cpf)
 	if err != nil {
 		z.Error("init tecent sms client failed", zap.Error(err))
-		return fmt.Errorf("init tecent sms client failed: %v", err)
+		return nil, fmt.Errorf("init tecent sms client failed: %v", err)
 	}
+	tecentClient.WithHttpTransport(tracing.Transport(nil))
 
-	return nil
+	return &tecentServiceImpl{conf: tecentConfig, client: tecentClient}, nil
 }
 
-// 初始化闪信通平台配置
-func initShxTongConfig() error {
-	shxConfig.ApiUrl = viper.GetString("sms.data.apiUrl")
+// 初始化闪信通平台服务
+func newShxService(data cmn.SmsDataConfig) (Service, error) {
+	var shxConfig ShxTongConfig
+	shxConfig.ApiUrl = data.ApiUrl
 	if shxConfig.ApiUrl == "" {
 		z.Error("shxtong apiUrl is empty")
-		return fmt.Errorf("shxtong apiUrl is empty")
+		return nil, fmt.Errorf("shxtong apiUrl is empty")
 	}
-	shxConfig.UserName = viper.GetString("sms.data.userName")
+	shxConfig.UserName = data.UserName
 	if shxConfig.UserName == "" {
 		z.Error("shxtong userName is empty")
-		return fmt.Errorf("shxtong userName is empty")
+		return nil, fmt.Errorf("shxtong userName is empty")
 	}
-	shxConfig.Password = viper.GetString("sms.data.password")
+	shxConfig.Password = data.Password
 	if shxConfig.Password == "" {
 		z.Error("shxtong password is empty")
-		return fmt.Errorf("shxtong password is empty")
+		return nil, fmt.Errorf("shxtong password is empty")
 	}
-	shxConfig.Template = viper.GetString("sms.data.template")
+	shxConfig.Template = data.Template
 	if shxConfig.Template == "" {
 		z.Error("shxtong template is empty")
-		return fmt.Errorf("shxtong template is empty")
+		return nil, fmt.Errorf("shxtong template is empty")
 	}
-	return nil
+
+	return &shxServiceImpl{conf: shxConfig}, nil
 }
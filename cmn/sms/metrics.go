@@ -0,0 +1,65 @@
+package sms
+
+import (
+	"WudangMeta/cmn/metrics"
+	"WudangMeta/cmn/tracing"
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 本地产生的发送结果码，服务商返回的错误码直接使用原值
+const (
+	codeOk           = "ok"            // 发送成功
+	codeInvalidParam = "invalid_param" // 手机号或验证码不合法、服务未配置
+	codeRequestError = "request_error" // 请求服务商失败
+	codeDecodeError  = "decode_error"  // 服务商响应解析失败
+	codeUnknown      = "unknown"       // 未分类的错误
+)
+
+// sendError 携带结果码的发送错误，用于按结果码统计发送失败
+type sendError struct {
+	code string
+	err  error
+}
+
+func (e *sendError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sendError) Unwrap() error {
+	return e.err
+}
+
+// newSendError 为发送错误附加结果码
+func newSendError(code string, err error) error {
+	return &sendError{code: code, err: err}
+}
+
+// instrumentedService 记录每次发送结果并创建链路追踪 span 的短信服务
+type instrumentedService struct {
+	provider string
+	inner    Service
+}
+
+func (s *instrumentedService) SendVerifyCode(ctx context.Context, phone string, code string) error {
+	ctx, span := tracing.StartSpan(ctx, "sms.SendVerifyCode", attribute.String("sms.provider", s.provider))
+	err := s.inner.SendVerifyCode(ctx, phone, code)
+	span.SetAttributes(attribute.String("sms.result", resultCode(err)))
+	tracing.EndSpan(span, err)
+	metrics.ObserveSmsSend(s.provider, err == nil, resultCode(err))
+	return err
+}
+
+// resultCode 获取发送结果码
+func resultCode(err error) string {
+	if err == nil {
+		return codeOk
+	}
+	var se *sendError
+	if errors.As(err, &se) {
+		return se.code
+	}
+	return codeUnknown
+}
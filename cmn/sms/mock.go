@@ -0,0 +1,109 @@
+package sms
+
+import (
+	"WudangMeta/cmn"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// mockMessage 模拟服务商记录的验证码
+type mockMessage struct {
+	Phone  string `json:"phone"`
+	Code   string `json:"code"`
+	SentAt int64  `json:"sentAt"`
+}
+
+// mockServiceImpl 模拟短信服务商，验证码写入本地文件或发送到本地地址，供开发与测试环境使用
+type mockServiceImpl struct {
+	file string
+	url  string
+}
+
+// mockFileMu 串行化对模拟文件的追加写入
+var mockFileMu sync.Mutex
+
+// 初始化模拟服务
+func newMockService(data cmn.SmsDataConfig) (Service, error) {
+	if data.MockFile == "" && data.MockUrl == "" {
+		z.Error("mock mockFile and mockUrl are empty")
+		return nil, fmt.Errorf("mock mockFile and mockUrl are empty")
+	}
+	z.Warn("mock sms provider enabled, verification codes are not delivered to phones",
+		zap.String("file", data.MockFile),
+		zap.String("url", data.MockUrl))
+	return &mockServiceImpl{file: data.MockFile, url: data.MockUrl}, nil
+}
+
+// SendVerifyCode 记录验证码
+func (s *mockServiceImpl) SendVerifyCode(ctx context.Context, phone string, code string) error {
+	if phone == "" || code == "" {
+		return newSendError(codeInvalidParam, fmt.Errorf("phone or code is empty"))
+	}
+
+	msg, err := json.Marshal(mockMessage{Phone: phone, Code: code, SentAt: time.Now().UnixMilli()})
+	if err != nil {
+		return newSendError(codeInvalidParam, err)
+	}
+
+	if s.file != "" {
+		err = appendMockFile(s.file, msg)
+		if err != nil {
+			z.Error("failed to write mock sms file", zap.String("file", s.file), zap.Error(err))
+			return newSendError(codeRequestError, fmt.Errorf("failed to write mock sms file: %w", err))
+		}
+	}
+
+	if s.url != "" {
+		err = postMockMessage(ctx, s.url, msg)
+		if err != nil {
+			z.Error("failed to post mock sms", zap.String("url", s.url), zap.Error(err))
+			return newSendError(codeRequestError, fmt.Errorf("failed to post mock sms: %w", err))
+		}
+	}
+	return nil
+}
+
+// appendMockFile 以 JSON Lines 格式追加一条记录
+func appendMockFile(path string, msg []byte) error {
+	mockFileMu.Lock()
+	defer mockFileMu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(msg, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// postMockMessage 以 JSON 发送记录，非 2xx 响应视为失败
+func postMockMessage(ctx context.Context, url string, msg []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("mock endpoint returned http status %d", resp.StatusCode)
+	}
+	return nil
+}
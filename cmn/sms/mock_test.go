@@ -0,0 +1,89 @@
+package sms
+
+import (
+	"WudangMeta/cmn"
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMockServiceFile(t *testing.T) {
+	z = zap.NewNop()
+	file := filepath.Join(t.TempDir(), "sms.jsonl")
+	service, err := newMockService(cmn.SmsDataConfig{MockFile: file})
+	if err != nil {
+		t.Fatalf("newMockService() error = %v", err)
+	}
+
+	for _, code := range []string{"111111", "222222"} {
+		err = service.SendVerifyCode(context.Background(), "13800000000", code)
+		if err != nil {
+			t.Fatalf("SendVerifyCode() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("open mock file: %v", err)
+	}
+	defer f.Close()
+	var codes []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg mockMessage
+		if err = json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("invalid mock line %q: %v", scanner.Text(), err)
+		}
+		if msg.Phone != "13800000000" || msg.SentAt == 0 {
+			t.Errorf("mock message = %+v", msg)
+		}
+		codes = append(codes, msg.Code)
+	}
+	if len(codes) != 2 || codes[0] != "111111" || codes[1] != "222222" {
+		t.Errorf("mock codes = %v", codes)
+	}
+}
+
+func TestMockServiceUrl(t *testing.T) {
+	z = zap.NewNop()
+	var received mockMessage
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	service, err := newMockService(cmn.SmsDataConfig{MockUrl: server.URL})
+	if err != nil {
+		t.Fatalf("newMockService() error = %v", err)
+	}
+	err = service.SendVerifyCode(context.Background(), "13800000000", "123456")
+	if err != nil || received.Code != "123456" {
+		t.Fatalf("SendVerifyCode() error = %v, received %+v", err, received)
+	}
+
+	status = http.StatusInternalServerError
+	if err = service.SendVerifyCode(context.Background(), "13800000000", "123456"); err == nil {
+		t.Errorf("SendVerifyCode() succeeded on http 500, want error")
+	}
+	if resultCode(err) != codeRequestError {
+		t.Errorf("resultCode() = %s, want %s", resultCode(err), codeRequestError)
+	}
+}
+
+func TestNewMockServiceRequiresTarget(t *testing.T) {
+	z = zap.NewNop()
+	if _, err := newMockService(cmn.SmsDataConfig{}); err == nil {
+		t.Errorf("newMockService() without file or url succeeded, want error")
+	}
+}
@@ -1,8 +1,9 @@
 package sms
 
 type JuheConfig struct {
-	ApiUrl string
-	Key    string
+	ApiUrl     string
+	Key        string
+	TemplateId string
 }
 
 type TecentConfig struct {
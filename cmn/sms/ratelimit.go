@@ -0,0 +1,313 @@
+package sms
+
+import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/metrics"
+	"WudangMeta/cmn/settings"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 验证码发送限流
+//
+// 每次发送前按手机号、IP 的滑动窗口与服务商每日额度检查，决策与发送结果写入 t_sms_send_log。
+// 检查与记录在同一事务中完成，并以 advisory lock 串行化同一手机号、IP 与服务商的请求，多实例部署时同样有效。
+// 每日额度按服务商分别计算，放行时返回未超出额度的服务商供故障转移使用，全部超出时拒绝。
+// 限额通过运行时配置调整，设为0表示不限制。
+
+// 限流配置键
+const (
+	settingPhonePerMinute = "sms.limit.phonePerMinute" // 单个手机号每分钟发送次数
+	settingPhonePerDay    = "sms.limit.phonePerDay"    // 单个手机号24小时内发送次数
+	settingIpPerMinute    = "sms.limit.ipPerMinute"    // 单个IP每分钟发送次数
+	settingIpPerDay       = "sms.limit.ipPerDay"       // 单个IP24小时内发送次数
+	settingDailyBudget    = "sms.limit.dailyBudget"    // 各服务商每自然日发送总量，default 为未单独配置的服务商的额度
+)
+
+// 限流决策
+const (
+	DecisionAllowed = "allowed" // 放行
+	DecisionBlocked = "blocked" // 拒绝
+	DecisionAborted = "aborted" // 放行后未发送，不计入限额
+)
+
+// 发送结果
+const (
+	SendResultSent   = "sent"   // 发送成功
+	SendResultFailed = "failed" // 发送失败
+)
+
+// 限流规则
+const (
+	RulePhonePerMinute = "phone_per_minute"
+	RulePhonePerDay    = "phone_per_day"
+	RuleIpPerMinute    = "ip_per_minute"
+	RuleIpPerDay       = "ip_per_day"
+	RuleDailyBudget    = "daily_budget"
+)
+
+const budgetDefaultKey = "default" // 每日额度中未单独配置的服务商使用的键
+
+// LimitDecision 限流检查结果
+type LimitDecision struct {
+	LogId      int64         // 决策记录ID，用于回写发送结果
+	Allowed    bool          // 是否放行
+	Providers  []string      // 放行时未超出每日额度的服务商，按优先级排列
+	Rule       string        // 拒绝时触发的规则
+	RetryAfter time.Duration // 拒绝时距可再次发送的时长
+	Cooldown   time.Duration // 放行时距同一手机号可再次发送的最短时长，供客户端倒计时
+}
+
+// windowRule 滑动窗口规则，窗口内放行次数达到 limit 后拒绝
+type windowRule struct {
+	name   string
+	column string
+	value  string
+	window time.Duration
+	limit  int64
+}
+
+func registerLimitSettings(ctx context.Context) error {
+	nonNegative := func(ctx context.Context, value any) error {
+		if value.(int64) < 0 {
+			return fmt.Errorf("limit %d < 0", value.(int64))
+		}
+		return nil
+	}
+	return settings.Register(ctx,
+		settings.Definition{Key: settingPhonePerMinute, Kind: settings.KindInt, Default: "1", Description: "单个手机号每分钟可发送的验证码数，0表示不限制", Validate: nonNegative},
+		settings.Definition{Key: settingPhonePerDay, Kind: settings.KindInt, Default: "10", Description: "单个手机号24小时内可发送的验证码数，0表示不限制", Validate: nonNegative},
+		settings.Definition{Key: settingIpPerMinute, Kind: settings.KindInt, Default: "5", Description: "单个IP每分钟可发送的验证码数，0表示不限制", Validate: nonNegative},
+		settings.Definition{Key: settingIpPerDay, Kind: settings.KindInt, Default: "50", Description: "单个IP24小时内可发送的验证码数，0表示不限制", Validate: nonNegative},
+		settings.Definition{
+			Key:         settingDailyBudget,
+			Kind:        settings.KindJSON,
+			Default:     `{"default": 10000}`,
+			Description: "各短信服务商每自然日的发送总量，如 {\"tecent\": 5000, \"default\": 10000}，0表示不限制",
+			Validate: func(ctx context.Context, value any) error {
+				_, err := parseBudget(value)
+				return err
+			},
+		},
+	)
+}
+
+// parseBudget 解析每日额度配置
+func parseBudget(value any) (map[string]int64, error) {
+	var budget map[string]int64
+	err := json.Unmarshal(value.(json.RawMessage), &budget)
+	if err != nil {
+		return nil, err
+	}
+	for provider, limit := range budget {
+		if limit < 0 {
+			return nil, fmt.Errorf("budget of %s %d < 0", provider, limit)
+		}
+	}
+	return budget, nil
+}
+
+// dailyBudget 返回服务商的每日额度，0表示不限制
+func dailyBudget(provider string) int64 {
+	var budget map[string]int64
+	err := settings.JSON(settingDailyBudget, &budget)
+	if err != nil {
+		z.Error("failed to read sms daily budget", zap.Error(err))
+		return 0
+	}
+	if limit, ok := budget[provider]; ok {
+		return limit
+	}
+	return budget[budgetDefaultKey]
+}
+
+// CheckSendLimit 检查是否允许向手机号发送验证码并记录决策，放行后需调用 RecordSendResult 回写发送结果
+func CheckSendLimit(ctx context.Context, db *gorm.DB, phone, ip string) (*LimitDecision, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	rules := []windowRule{
+		{name: RulePhonePerMinute, column: "mobile_phone", value: phone, window: time.Minute, limit: settings.Int(settingPhonePerMinute)},
+		{name: RulePhonePerDay, column: "mobile_phone", value: phone, window: 24 * time.Hour, limit: settings.Int(settingPhonePerDay)},
+		{name: RuleIpPerMinute, column: "ip", value: ip, window: time.Minute, limit: settings.Int(settingIpPerMinute)},
+		{name: RuleIpPerDay, column: "ip", value: ip, window: 24 * time.Hour, limit: settings.Int(settingIpPerDay)},
+	}
+	names := ProviderNames()
+	budgets := make([]int64, len(names))
+	for i, name := range names {
+		budgets[i] = dailyBudget(name)
+	}
+
+	// 固定顺序加锁，避免死锁
+	lockKeys := []string{"sms:phone:" + phone, "sms:ip:" + ip}
+	sortedNames := slices.Clone(names)
+	slices.Sort(sortedNames)
+	for _, name := range sortedNames {
+		lockKeys = append(lockKeys, "sms:provider:"+name)
+	}
+
+	var decision LimitDecision
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, key := range lockKeys {
+			err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		decision = LimitDecision{Allowed: true}
+		for _, rule := range rules {
+			retryAfter, err := checkWindow(tx, rule, now)
+			if err != nil {
+				return err
+			}
+			if retryAfter > 0 {
+				decision = LimitDecision{Rule: rule.name, RetryAfter: retryAfter}
+				break
+			}
+		}
+
+		if decision.Allowed && len(names) > 0 {
+			dayStart := startOfDay(now)
+			for i, name := range names {
+				if budgets[i] > 0 {
+					var sent int64
+					err := tx.Model(&cmn.TSmsSendLog{}).
+						Where("provider = ? AND decision = ? AND created_at >= ?", name, DecisionAllowed, dayStart.UnixMilli()).
+						Count(&sent).Error
+					if err != nil {
+						return err
+					}
+					if sent >= budgets[i] {
+						continue
+					}
+				}
+				decision.Providers = append(decision.Providers, name)
+			}
+			if len(decision.Providers) == 0 {
+				decision = LimitDecision{Rule: RuleDailyBudget, RetryAfter: dayStart.AddDate(0, 0, 1).Sub(now)}
+			}
+		}
+
+		// 放行时先记为首选服务商，发送后回写实际发送的服务商
+		record := cmn.TSmsSendLog{
+			MobilePhone: phone,
+			Ip:          ip,
+			Decision:    DecisionAllowed,
+		}
+		if len(decision.Providers) > 0 {
+			record.Provider = decision.Providers[0]
+		}
+		if !decision.Allowed {
+			record.Decision = DecisionBlocked
+			record.Rule = decision.Rule
+			record.RetryAfter = int64(math.Ceil(decision.RetryAfter.Seconds()))
+		}
+		err := tx.Create(&record).Error
+		if err != nil {
+			return err
+		}
+		decision.LogId = record.Id
+		return nil
+	})
+	if err != nil {
+		e := fmt.Errorf("failed to check sms send limit: %w", err)
+		cmn.LoggerFrom(ctx).Error(e.Error(), zap.String("phone", phone), zap.String("ip", ip))
+		return nil, e
+	}
+
+	if decision.Allowed {
+		// 使用决策时的限额，避免配置在检查后变更导致冷却时间与决策不一致
+		if limit := rules[0].limit; limit > 0 {
+			decision.Cooldown = time.Minute / time.Duration(limit)
+		}
+	} else {
+		metrics.ObserveSmsBlocked(decision.Rule)
+		cmn.LoggerFrom(ctx).Warn("sms send blocked",
+			zap.String("phone", phone),
+			zap.String("ip", ip),
+			zap.String("rule", decision.Rule),
+			zap.Duration("retryAfter", decision.RetryAfter))
+	}
+	return &decision, nil
+}
+
+// RecordSendResult 回写放行请求的发送结果与实际发送的服务商，写入失败只记录日志
+func RecordSendResult(ctx context.Context, db *gorm.DB, logId int64, provider string, sendErr error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	updates := map[string]any{"result": SendResultSent}
+	if sendErr != nil {
+		updates = map[string]any{"result": SendResultFailed, "error": sendErr.Error()}
+	}
+	if provider != "" {
+		updates["provider"] = provider
+	}
+	err := db.WithContext(ctx).Model(&cmn.TSmsSendLog{}).Where("id = ?", logId).Updates(updates).Error
+	if err != nil {
+		cmn.LoggerFrom(ctx).Error("failed to record sms send result", zap.Int64("logId", logId), zap.Error(err))
+	}
+}
+
+// AbortSend 放行后因故未发送（如保存验证码失败）时撤销放行，本次请求不计入限额，写入失败只记录日志
+func AbortSend(ctx context.Context, db *gorm.DB, logId int64, reason error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	err := db.WithContext(ctx).Model(&cmn.TSmsSendLog{}).
+		Where("id = ?", logId).
+		Updates(map[string]any{"decision": DecisionAborted, "error": reason.Error()}).Error
+	if err != nil {
+		cmn.LoggerFrom(ctx).Error("failed to abort sms send", zap.Int64("logId", logId), zap.Error(err))
+	}
+}
+
+// checkWindow 检查滑动窗口规则，超限时返回距窗口内最早一次放行移出窗口的时长
+func checkWindow(tx *gorm.DB, rule windowRule, now time.Time) (time.Duration, error) {
+	if rule.limit <= 0 || rule.value == "" {
+		return 0, nil
+	}
+
+	// 窗口内倒数第 limit 次放行的时间，不存在说明未超限
+	var times []int64
+	err := tx.Model(&cmn.TSmsSendLog{}).
+		Where(rule.column+" = ? AND decision = ? AND created_at > ?", rule.value, DecisionAllowed, now.Add(-rule.window).UnixMilli()).
+		Order("created_at DESC").
+		Offset(int(rule.limit-1)).
+		Limit(1).
+		Pluck("created_at", &times).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(times) == 0 {
+		return 0, nil
+	}
+	return retryAfter(times[0], rule.window, now), nil
+}
+
+// retryAfter 计算 sentAt 时刻的放行记录移出窗口的剩余时长，至少1秒
+func retryAfter(sentAt int64, window time.Duration, now time.Time) time.Duration {
+	d := time.UnixMilli(sentAt).Add(window).Sub(now)
+	if d < time.Second {
+		return time.Second
+	}
+	return d
+}
+
+// startOfDay 返回 t 所在自然日的零点
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,64 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name   string
+		sentAt time.Time
+		window time.Duration
+		want   time.Duration
+	}{
+		{"minute window", now.Add(-20 * time.Second), time.Minute, 40 * time.Second},
+		{"day window", now.Add(-23 * time.Hour), 24 * time.Hour, time.Hour},
+		{"about to expire", now.Add(-time.Minute + 100*time.Millisecond), time.Minute, time.Second},
+	}
+	for _, tt := range tests {
+		got := retryAfter(tt.sentAt.UnixMilli(), tt.window, now)
+		if got != tt.want {
+			t.Errorf("%s: retryAfter() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStartOfDay(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	got := startOfDay(time.Date(2025, 6, 1, 0, 30, 0, 0, loc))
+	want := time.Date(2025, 6, 1, 0, 0, 0, 0, loc)
+	if !got.Equal(want) {
+		t.Errorf("startOfDay() = %v, want %v", got, want)
+	}
+}
+
+func TestParseBudget(t *testing.T) {
+	budget, err := parseBudget(json.RawMessage(`{"tecent": 5000, "default": 100}`))
+	if err != nil || budget["tecent"] != 5000 || budget["default"] != 100 {
+		t.Errorf("parseBudget() = %v, %v", budget, err)
+	}
+
+	for _, raw := range []string{`{"tecent": -1}`, `{"tecent": "many"}`, `[1]`} {
+		_, err = parseBudget(json.RawMessage(raw))
+		if err == nil {
+			t.Errorf("parseBudget(%s) succeeded, want error", raw)
+		}
+	}
+}
+
+func TestDailyBudget(t *testing.T) {
+	err := registerLimitSettings(context.Background())
+	if err != nil {
+		t.Fatalf("registerLimitSettings() error = %v", err)
+	}
+
+	// 未单独配置的服务商使用 default 额度
+	if got := dailyBudget("tecent"); got != 10000 {
+		t.Errorf("dailyBudget(tecent) = %d, want 10000", got)
+	}
+}
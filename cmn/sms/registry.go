@@ -0,0 +1,205 @@
+package sms
+
+import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 短信服务商注册表
+//
+// 按配置的优先级依次尝试各服务商，发送失败或超时后切换到下一个服务商。
+// 每个服务商有独立的熔断器，熔断中的服务商直接跳过；配置热更新时重建服务商，同名服务商保留熔断状态。
+
+var (
+	ErrNoProvider         = errors.New("no sms provider available")
+	ErrAllProvidersFailed = errors.New("all sms providers failed")
+	ErrCircuitOpen        = errors.New("sms provider circuit is open")
+)
+
+// platformFactories 各短信平台的服务构造函数，新增平台在此注册并在配置校验中补充必填项
+var platformFactories = map[string]func(data cmn.SmsDataConfig) (Service, error){
+	"juhe":   newJuheService,
+	"tecent": newTecentService,
+	"shx":    newShxService,
+	"mock":   newMockService,
+}
+
+// provider 已注册的服务商
+type provider struct {
+	name     string
+	platform string
+	priority int
+	service  Service
+	breaker  *breaker
+}
+
+// ProviderHealth 服务商健康状态
+type ProviderHealth struct {
+	Name                string `json:"name"`                // 服务商名称
+	Platform            string `json:"platform"`            // 短信平台
+	Priority            int    `json:"priority"`            // 优先级，数值越小越先尝试
+	State               string `json:"state"`               // 熔断状态 closed/open/half_open
+	ConsecutiveFailures int    `json:"consecutiveFailures"` // 连续失败次数
+	LastError           string `json:"lastError"`           // 最近一次失败原因
+	LastFailureAt       int64  `json:"lastFailureAt"`       // 最近一次失败时间
+	LastSuccessAt       int64  `json:"lastSuccessAt"`       // 最近一次成功时间
+	OpenedAt            int64  `json:"openedAt"`            // 熔断开始时间
+	OpenUntil           int64  `json:"openUntil"`           // 熔断到期时间
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   []*provider   // 按优先级排序
+	sendTimeout time.Duration // 单个服务商的发送超时
+)
+
+// loadProviders 根据配置构建服务商，任一服务商初始化失败时保持原服务商
+func loadProviders(cfg cmn.SmsConfig) error {
+	providersMu.RLock()
+	existing := map[string]*breaker{}
+	for _, p := range providers {
+		existing[p.name] = p.breaker
+	}
+	providersMu.RUnlock()
+
+	var built []*provider
+	for _, pc := range cfg.ProviderConfigs() {
+		factory, ok := platformFactories[pc.Platform]
+		if !ok {
+			return fmt.Errorf("sms platform %s of provider %s is not supported", pc.Platform, pc.Name)
+		}
+		service, err := factory(pc.Data)
+		if err != nil {
+			return fmt.Errorf("failed to init sms provider %s: %w", pc.Name, err)
+		}
+
+		b, ok := existing[pc.Name]
+		if ok {
+			b.configure(cfg.Breaker.FailureThreshold, cfg.Breaker.OpenTimeout)
+		} else {
+			b = newBreaker(pc.Name, cfg.Breaker.FailureThreshold, cfg.Breaker.OpenTimeout)
+		}
+		built = append(built, &provider{
+			name:     pc.Name,
+			platform: pc.Platform,
+			priority: pc.Priority,
+			service:  &instrumentedService{provider: pc.Name, inner: service},
+			breaker:  b,
+		})
+	}
+
+	providersMu.Lock()
+	providers = built
+	sendTimeout = cfg.Timeout
+	providersMu.Unlock()
+	return nil
+}
+
+// currentProviders 返回当前服务商快照
+func currentProviders() ([]*provider, time.Duration) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	return providers, sendTimeout
+}
+
+// ProviderNames 返回按优先级排序的服务商名称
+func ProviderNames() []string {
+	list, _ := currentProviders()
+	names := make([]string, 0, len(list))
+	for _, p := range list {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// Health 返回各服务商的健康状态
+func Health() []ProviderHealth {
+	list, _ := currentProviders()
+	now := time.Now()
+	result := make([]ProviderHealth, 0, len(list))
+	for _, p := range list {
+		h := ProviderHealth{Name: p.name, Platform: p.platform, Priority: p.priority}
+		p.breaker.fill(&h, now)
+		result = append(result, h)
+	}
+	return result
+}
+
+// Send 按优先级依次尝试服务商发送验证码，返回发送成功的服务商
+// candidates 不为 nil 时只尝试其中的服务商，如未超出每日额度的服务商
+func Send(ctx context.Context, phone, code string, candidates []string) (string, error) {
+	list, timeout := currentProviders()
+
+	var (
+		errs       []error
+		lastFailed string // 上一个发送失败的服务商，尝试下一个服务商时记录一次故障转移
+	)
+	for _, p := range list {
+		if candidates != nil && !slices.Contains(candidates, p.name) {
+			continue
+		}
+		if !p.breaker.allow(time.Now()) {
+			errs = append(errs, fmt.Errorf("%s: %w", p.name, ErrCircuitOpen))
+			continue
+		}
+
+		if lastFailed != "" {
+			metrics.ObserveSmsFailover(lastFailed)
+		}
+		err := sendWithTimeout(ctx, p.service, timeout, phone, code)
+		if ctx.Err() != nil {
+			// 调用方取消，不计入服务商失败
+			p.breaker.release()
+			return "", ctx.Err()
+		}
+		p.breaker.record(err, time.Now())
+		if err == nil {
+			return p.name, nil
+		}
+
+		lastFailed = p.name
+		cmn.LoggerFrom(ctx).Warn("sms provider failed",
+			zap.String("provider", p.name),
+			zap.String("phone", phone),
+			zap.Error(err))
+		errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+	}
+
+	if len(errs) == 0 {
+		return "", ErrNoProvider
+	}
+	e := fmt.Errorf("%w: %w", ErrAllProvidersFailed, errors.Join(errs...))
+	cmn.LoggerFrom(ctx).Error(e.Error(), zap.String("phone", phone))
+	return "", e
+}
+
+// sendWithTimeout 以单个服务商的超时时间发送
+func sendWithTimeout(ctx context.Context, service Service, timeout time.Duration, phone, code string) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return service.SendVerifyCode(ctx, phone, code)
+}
+
+// failoverService 按优先级故障转移的短信服务
+type failoverService struct{}
+
+// NewService 返回按优先级在已配置的服务商间故障转移的短信服务
+func NewService() Service {
+	return failoverService{}
+}
+
+func (failoverService) SendVerifyCode(ctx context.Context, phone string, code string) error {
+	_, err := Send(ctx, phone, code, nil)
+	return err
+}
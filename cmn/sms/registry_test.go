@@ -0,0 +1,184 @@
+package sms
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeService 按预设结果返回的短信服务，记录调用次数
+type fakeService struct {
+	err   error
+	delay time.Duration
+	calls int
+}
+
+func (s *fakeService) SendVerifyCode(ctx context.Context, phone string, code string) error {
+	s.calls++
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return s.err
+}
+
+// setProviders 替换已注册的服务商，测试结束后清空
+func setProviders(t *testing.T, timeout time.Duration, list ...*provider) {
+	t.Helper()
+	z = zap.NewNop()
+	providersMu.Lock()
+	providers, sendTimeout = list, timeout
+	providersMu.Unlock()
+	t.Cleanup(func() {
+		providersMu.Lock()
+		providers, sendTimeout = nil, 0
+		providersMu.Unlock()
+	})
+}
+
+// testContext 携带空日志的上下文，发送过程通过 cmn.LoggerFrom 记录日志
+func testContext() context.Context {
+	return cmn.WithLogger(context.Background(), zap.NewNop())
+}
+
+func newTestProvider(name string, service Service, threshold int) *provider {
+	return &provider{name: name, platform: "mock", service: service, breaker: newBreaker(name, threshold, time.Minute)}
+}
+
+func TestSendFailover(t *testing.T) {
+	primary := &fakeService{err: errors.New("primary down")}
+	slow := &fakeService{delay: time.Second}
+	backup := &fakeService{}
+	setProviders(t, 50*time.Millisecond,
+		newTestProvider("primary", primary, 2),
+		newTestProvider("slow", slow, 2),
+		newTestProvider("backup", backup, 2),
+	)
+
+	// 失败与超时的服务商依次被跳过
+	got, err := Send(testContext(), "13800000000", "123456", nil)
+	if err != nil || got != "backup" {
+		t.Fatalf("Send() = %q, %v, want backup", got, err)
+	}
+
+	// 连续失败达到阈值后熔断，不再请求
+	_, _ = Send(testContext(), "13800000000", "123456", nil)
+	_, _ = Send(testContext(), "13800000000", "123456", nil)
+	if primary.calls != 2 || slow.calls != 2 || backup.calls != 3 {
+		t.Errorf("calls primary=%d slow=%d backup=%d, want 2, 2, 3", primary.calls, slow.calls, backup.calls)
+	}
+
+	health := Health()
+	if len(health) != 3 || health[0].State != BreakerOpen || health[1].State != BreakerOpen || health[2].State != BreakerClosed {
+		t.Errorf("Health() = %+v", health)
+	}
+}
+
+func TestSendCandidates(t *testing.T) {
+	primary := &fakeService{}
+	backup := &fakeService{}
+	setProviders(t, 0, newTestProvider("primary", primary, 1), newTestProvider("backup", backup, 1))
+
+	// 只尝试候选服务商，如首选服务商超出每日额度
+	got, err := Send(testContext(), "13800000000", "123456", []string{"backup"})
+	if err != nil || got != "backup" || primary.calls != 0 {
+		t.Fatalf("Send() = %q, %v, primary calls %d", got, err, primary.calls)
+	}
+
+	_, err = Send(testContext(), "13800000000", "123456", []string{})
+	if !errors.Is(err, ErrNoProvider) {
+		t.Errorf("Send() without candidates error = %v, want ErrNoProvider", err)
+	}
+}
+
+func TestSendAllFailed(t *testing.T) {
+	errDown := errors.New("down")
+	setProviders(t, 0, newTestProvider("a", &fakeService{err: errDown}, 1), newTestProvider("b", &fakeService{err: errDown}, 1))
+
+	_, err := Send(testContext(), "13800000000", "123456", nil)
+	if !errors.Is(err, ErrAllProvidersFailed) || !errors.Is(err, errDown) {
+		t.Fatalf("Send() error = %v, want ErrAllProvidersFailed wrapping provider error", err)
+	}
+
+	// 全部熔断后直接返回
+	_, err = Send(testContext(), "13800000000", "123456", nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Send() error = %v, want ErrCircuitOpen", err)
+	}
+}
+
+func TestSendCanceled(t *testing.T) {
+	service := &fakeService{delay: time.Second}
+	p := newTestProvider("a", service, 1)
+	setProviders(t, 0, p)
+
+	ctx, cancel := context.WithTimeout(testContext(), 20*time.Millisecond)
+	defer cancel()
+	_, err := Send(ctx, "13800000000", "123456", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Send() error = %v, want context.DeadlineExceeded", err)
+	}
+	// 调用方取消不计入服务商失败
+	if p.breaker.state != BreakerClosed || p.breaker.failures != 0 {
+		t.Errorf("canceled send counted as failure: state = %s failures = %d", p.breaker.state, p.breaker.failures)
+	}
+}
+
+func TestLoadProviders(t *testing.T) {
+	setProviders(t, 0)
+	file := filepath.Join(t.TempDir(), "sms.jsonl")
+	cfg := cmn.SmsConfig{
+		Providers: []cmn.SmsProviderConfig{
+			{Name: "backup", Platform: "mock", Priority: 2, Data: cmn.SmsDataConfig{MockFile: file}},
+			{Name: "primary", Platform: "mock", Priority: 1, Data: cmn.SmsDataConfig{MockFile: file}},
+		},
+		Timeout: time.Second,
+		Breaker: cmn.SmsBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute},
+	}
+	err := loadProviders(cfg)
+	if err != nil {
+		t.Fatalf("loadProviders() error = %v", err)
+	}
+	if names := ProviderNames(); strings.Join(names, ",") != "primary,backup" {
+		t.Fatalf("ProviderNames() = %v, want primary,backup", names)
+	}
+
+	// 重新加载时同名服务商保留熔断状态
+	list, _ := currentProviders()
+	list[0].breaker.record(errors.New("down"), time.Now())
+	err = loadProviders(cfg)
+	if err != nil {
+		t.Fatalf("loadProviders() error = %v", err)
+	}
+	if h := Health(); h[0].State != BreakerOpen {
+		t.Errorf("breaker state lost after reload: %+v", h[0])
+	}
+
+	// 初始化失败时保持原服务商
+	cfg.Providers = append(cfg.Providers, cmn.SmsProviderConfig{Name: "bad", Platform: "unknown"})
+	if err = loadProviders(cfg); err == nil {
+		t.Fatalf("loadProviders() with unknown platform succeeded")
+	}
+	if len(ProviderNames()) != 2 {
+		t.Errorf("providers replaced after failed reload: %v", ProviderNames())
+	}
+
+	got, err := Send(testContext(), "13800000000", "123456", nil)
+	if err != nil || got != "backup" {
+		t.Fatalf("Send() = %q, %v, want backup", got, err)
+	}
+	content, _ := os.ReadFile(file)
+	if !strings.Contains(string(content), `"code":"123456"`) {
+		t.Errorf("mock file content = %q", content)
+	}
+}
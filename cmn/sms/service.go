@@ -1,7 +1,11 @@
 package sms
 
 import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/tracing"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,73 +13,69 @@ import (
 	"time"
 
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
+	tecentErrors "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
 	tecentSMS "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/sms/v20210111"
 	"go.uber.org/zap"
 )
 
+// Service 短信服务，各平台实现与故障转移服务均实现该接口
 type Service interface {
-	SendVerifyCode(phone string, code string) error
+	SendVerifyCode(ctx context.Context, phone string, code string) error
 }
 
+// httpClient 短信服务商接口使用的客户端，为每次请求创建链路追踪 span
+var httpClient = &http.Client{Transport: tracing.Transport(nil)}
+
 type juheServiceImpl struct {
+	conf JuheConfig
 }
 
 type tecentServiceImpl struct {
+	conf   TecentConfig
+	client *tecentSMS.Client
 }
 
 type shxServiceImpl struct {
-}
-
-func NewService() Service {
-	switch platform {
-	case "juhe":
-		return &juheServiceImpl{}
-	case "tecent":
-		return &tecentServiceImpl{}
-	case "shx":
-		return &shxServiceImpl{}
-	default:
-		z.Warn("sms platform is not supported", zap.String("platform", platform))
-	}
-	return nil
+	conf ShxTongConfig
 }
 
 // SendVerifyCode 发送验证码
-func (*juheServiceImpl) SendVerifyCode(phone string, code string) error {
+func (s *juheServiceImpl) SendVerifyCode(ctx context.Context, phone string, code string) error {
+	juheConfig := s.conf
 	if juheConfig.Key == "" {
 		z.Error("sms is not enabled")
-		return fmt.Errorf("juhe sms key is empty")
+		return newSendError(codeInvalidParam, fmt.Errorf("juhe sms key is empty"))
 	}
 	if phone == "" || code == "" {
 		z.Error("sms phone or code is empty")
-		return fmt.Errorf("phone or code is empty")
+		return newSendError(codeInvalidParam, fmt.Errorf("phone or code is empty"))
 	}
 	if !IsValidPhone(phone) {
 		z.Error("sms phone is invalid")
-		return fmt.Errorf("phone is invalid")
+		return newSendError(codeInvalidParam, fmt.Errorf("phone is invalid"))
 	}
 
 	// 初始化参数
 	param := url.Values{}
 
 	// 接口请求参数
-	param.Set("mobile", phone)             // 接收短信的手机号码
-	param.Set("tpl_id", "xxxx")            // 短信模板ID，请参考个人中心短信模板设置
-	param.Set("tpl_value", "#code#=12341") // 模板变量，如无则不用填写
-	param.Set("key", juheConfig.Key)       // 接口请求Key
+	param.Set("mobile", phone)                 // 接收短信的手机号码
+	param.Set("tpl_id", juheConfig.TemplateId) // 短信模板ID，请参考个人中心短信模板设置
+	param.Set("tpl_value", "#code#="+code)     // 模板变量，请求时整体进行URL编码
+	param.Set("key", juheConfig.Key)           // 接口请求Key
 
 	// 发送请求
-	data, err := Post(juheConfig.ApiUrl, param)
+	data, err := Post(ctx, juheConfig.ApiUrl, param)
 	if err != nil {
 		z.Error("sms is not enabled")
-		return err
+		return newSendError(codeRequestError, err)
 	} else {
 		var netReturn map[string]interface{}
 		jsonErr := json.Unmarshal(data, &netReturn)
 		if jsonErr != nil {
 			// 解析JSON异常，根据自身业务逻辑进行调整修改
 			z.Error("sms is not enabled")
-			return jsonErr
+			return newSendError(codeDecodeError, jsonErr)
 		} else {
 			errorCode := netReturn["error_code"]
 			reason := netReturn["reason"]
@@ -86,25 +86,26 @@ func (*juheServiceImpl) SendVerifyCode(phone string, code string) error {
 			} else {
 				// 查询失败，根据自身业务逻辑进行调整修改
 				z.Error("sms is not enabled")
-				return fmt.Errorf("error_code: %v, reason: %v, data: %v", errorCode, reason, data)
+				return newSendError(fmt.Sprintf("%v", errorCode), fmt.Errorf("error_code: %v, reason: %v, data: %v", errorCode, reason, data))
 			}
 		}
 	}
 }
 
 // SendVerifyCode 发送验证码
-func (*tecentServiceImpl) SendVerifyCode(phone string, code string) error {
+func (s *tecentServiceImpl) SendVerifyCode(ctx context.Context, phone string, code string) error {
+	tecentConfig, tecentClient := s.conf, s.client
 	if tecentConfig.AppKey == "" {
 		z.Error("tecent sms is not enabled")
-		return fmt.Errorf("tecent sms appKey is empty")
+		return newSendError(codeInvalidParam, fmt.Errorf("tecent sms appKey is empty"))
 	}
 	if phone == "" || code == "" {
 		z.Error("sms phone or code is empty")
-		return fmt.Errorf("phone or code is empty")
+		return newSendError(codeInvalidParam, fmt.Errorf("phone or code is empty"))
 	}
 	if !IsValidPhone(phone) {
 		z.Error("sms phone is invalid")
-		return fmt.Errorf("phone is invalid")
+		return newSendError(codeInvalidParam, fmt.Errorf("phone is invalid"))
 	}
 
 	request := tecentSMS.NewSendSmsRequest()
@@ -122,32 +123,50 @@ func (*tecentServiceImpl) SendVerifyCode(phone string, code string) error {
 	request.ExtendCode = common.StringPtr("")
 	request.SenderId = common.StringPtr("")
 
-	response, err := tecentClient.SendSms(request)
+	response, err := tecentClient.SendSmsWithContext(ctx, request)
 	if err != nil {
 		z.Error("sms is not enabled", zap.Error(err))
-		return err
+		var sdkErr *tecentErrors.TencentCloudSDKError
+		if errors.As(err, &sdkErr) {
+			return newSendError(sdkErr.GetCode(), err)
+		}
+		return newSendError(codeRequestError, err)
 	}
 
 	b, _ := json.Marshal(response.Response)
-	// 打印返回的json字符串
-	fmt.Printf("%s", b)
+	cmn.LoggerFrom(ctx).Debug("tecent sms response", zap.ByteString("response", b))
+
+	// 单号码发送，状态码不为 Ok 时表示发送失败
+	if response.Response != nil && len(response.Response.SendStatusSet) > 0 {
+		status := response.Response.SendStatusSet[0]
+		if status.Code != nil && *status.Code != "Ok" {
+			message := ""
+			if status.Message != nil {
+				message = *status.Message
+			}
+			e := fmt.Errorf("tecent sms send failed, code: %s, message: %s", *status.Code, message)
+			z.Error(e.Error())
+			return newSendError(*status.Code, e)
+		}
+	}
 
 	return nil
 }
 
 // SendVerifyCode 发送验证码
-func (*shxServiceImpl) SendVerifyCode(phone string, code string) error {
+func (s *shxServiceImpl) SendVerifyCode(ctx context.Context, phone string, code string) error {
+	shxConfig := s.conf
 	if shxConfig.ApiUrl == "" {
 		z.Error("shx sms is not enabled")
-		return fmt.Errorf("shx sms apiUrl is empty")
+		return newSendError(codeInvalidParam, fmt.Errorf("shx sms apiUrl is empty"))
 	}
 	if phone == "" || code == "" {
 		z.Error("sms phone or code is empty")
-		return fmt.Errorf("phone or code is empty")
+		return newSendError(codeInvalidParam, fmt.Errorf("phone or code is empty"))
 	}
 	if !IsValidPhone(phone) {
 		z.Error("sms phone is invalid")
-		return fmt.Errorf("phone is invalid")
+		return newSendError(codeInvalidParam, fmt.Errorf("phone is invalid"))
 	}
 
 	// 构造请求参数
@@ -160,10 +179,10 @@ func (*shxServiceImpl) SendVerifyCode(phone string, code string) error {
 	form.Set("MsgIdentify", fmt.Sprintf("shx-%d", time.Now().UnixNano()))
 
 	// 发送 POST 请求
-	resp, err := http.PostForm(shxConfig.ApiUrl, form)
+	resp, err := postForm(ctx, shxConfig.ApiUrl, form)
 	if err != nil {
 		z.Error("failed to send sms", zap.Error(err))
-		return fmt.Errorf("failed to send sms: %w", err)
+		return newSendError(codeRequestError, fmt.Errorf("failed to send sms: %w", err))
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -176,7 +195,13 @@ func (*shxServiceImpl) SendVerifyCode(phone string, code string) error {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		z.Error("failed to read sms response", zap.Error(err))
-		return fmt.Errorf("failed to read sms response: %w", err)
+		return newSendError(codeRequestError, fmt.Errorf("failed to read sms response: %w", err))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		e := fmt.Errorf("shx sms api returned http status %d, response: %s", resp.StatusCode, string(body))
+		z.Error(e.Error())
+		return newSendError(fmt.Sprintf("http_%d", resp.StatusCode), e)
 	}
 
 	z.Info("sms sent", zap.String("response", string(body)))
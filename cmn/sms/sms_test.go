@@ -1,14 +1,56 @@
 package sms
 
-import "testing"
+import (
+	"WudangMeta/cmn"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go.uber.org/zap"
+)
 
 func TestSendVerifyCode(t *testing.T) {
+	if len(ProviderNames()) == 0 {
+		t.Skip("no sms provider configured")
+	}
 	service := NewService()
 
-	err := service.SendVerifyCode("15819888226", "1234")
+	err := service.SendVerifyCode(context.Background(), "15819888226", "1234")
 	if err != nil {
 		t.Errorf("SendVerifyCode failed: %v", err)
 	} else {
 		t.Log("SendVerifyCode success")
 	}
 }
+
+func TestJuheServiceSendsCode(t *testing.T) {
+	z = zap.NewNop()
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		form = r.PostForm
+		_, _ = w.Write([]byte(`{"error_code": 0, "reason": "ok"}`))
+	}))
+	defer server.Close()
+
+	_, err := newJuheService(cmn.SmsDataConfig{ApiUrl: server.URL, Key: "key"})
+	if err == nil {
+		t.Fatalf("newJuheService() should require templateId")
+	}
+	service, err := newJuheService(cmn.SmsDataConfig{ApiUrl: server.URL, Key: "key", TemplateId: "262311"})
+	if err != nil {
+		t.Fatalf("newJuheService() error = %v", err)
+	}
+
+	err = service.SendVerifyCode(context.Background(), "13800000000", "654321")
+	if err != nil {
+		t.Fatalf("SendVerifyCode() error = %v", err)
+	}
+	if form.Get("tpl_id") != "262311" || form.Get("tpl_value") != "#code#=654321" || form.Get("mobile") != "13800000000" {
+		t.Errorf("juhe request form = %v", form)
+	}
+}
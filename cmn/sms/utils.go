@@ -1,15 +1,17 @@
 package sms
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strings"
 )
 
 // Post 方式发起网络请求 ,params 是url.Values类型
-func Post(apiURL string, params url.Values) (rs []byte, err error) {
-	resp, err := http.PostForm(apiURL, params)
+func Post(ctx context.Context, apiURL string, params url.Values) (rs []byte, err error) {
+	resp, err := postForm(ctx, apiURL, params)
 	if err != nil {
 		return nil, err
 	}
@@ -23,6 +25,16 @@ func Post(apiURL string, params url.Values) (rs []byte, err error) {
 	return io.ReadAll(resp.Body)
 }
 
+// postForm 以表单方式发起 POST 请求，请求随 ctx 取消
+func postForm(ctx context.Context, apiURL string, params url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return httpClient.Do(req)
+}
+
 // IsValidPhone 验证手机号是否合法
 func IsValidPhone(phone string) bool {
 	regex := regexp.MustCompile(`^1[3-9]\d{9}$`)
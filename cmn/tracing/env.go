@@ -0,0 +1,133 @@
+package tracing
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultServiceName  = "wudang-meta" // 默认服务名称
+	instrumentationName = "WudangMeta"  // 埋点名称
+)
+
+// 导出方式
+const (
+	ExporterOtlp = "otlp" // 通过 OTLP/HTTP 导出到采集器
+	ExporterFile = "file" // 以 JSON 写入本地文件，用于本地调试与测试
+)
+
+var z *zap.Logger
+
+var provider *sdktrace.TracerProvider // 未启用时为 nil，埋点使用全局默认的空实现
+var exportFile *os.File               // 文件导出时打开的文件
+
+// Config 链路追踪配置，定义在 cmn 中以便随应用配置一起加载
+type Config = cmn.TracingConfig
+
+func Init(ctx context.Context) {
+	z = cmn.GetLogger()
+
+	cfg := cmn.GetConfig().Tracing
+	if !cfg.Enable {
+		cmn.MiniLogger.Info("[ -- ] tracing module disabled")
+		return
+	}
+
+	err := Setup(ctx, cfg)
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to setup tracing", zap.Error(err))
+	}
+
+	err = RegisterGormCallbacks(cmn.GormDB)
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register gorm tracing callbacks", zap.Error(err))
+	}
+
+	cmn.MiniLogger.Info("[ OK ] tracing module initialized",
+		zap.String("exporter", cfg.Exporter), zap.Float64("sampleRatio", cfg.SampleRatio))
+}
+
+// Setup 按配置创建并注册全局 TracerProvider
+func Setup(ctx context.Context, cfg Config) error {
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = defaultServiceName
+	}
+	if cfg.SampleRatio <= 0 || cfg.SampleRatio > 1 {
+		cfg.SampleRatio = 1
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterOtlp:
+		opts := []otlptracehttp.Option{}
+		if cfg.OtlpEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.OtlpEndpoint))
+		}
+		if cfg.OtlpInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to create otlp exporter: %w", err)
+		}
+	case ExporterFile:
+		if cfg.FilePath == "" {
+			return fmt.Errorf("tracing.filePath is required for file exporter")
+		}
+		exportFile, err = os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(exportFile))
+		if err != nil {
+			return fmt.Errorf("failed to create file exporter: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported tracing exporter: %q", cfg.Exporter)
+	}
+
+	res := resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(cmn.Version),
+	)
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return nil
+}
+
+// Shutdown 导出缓冲中的链路数据并关闭 TracerProvider
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+
+	err := provider.Shutdown(ctx)
+	if exportFile != nil {
+		if closeErr := exportFile.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		exportFile = nil
+	}
+	provider = nil
+
+	return err
+}
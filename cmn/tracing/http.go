@@ -0,0 +1,104 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// transport 为 net/http 请求创建客户端 span 并传播链路上下文
+type transport struct {
+	base http.RoundTripper
+}
+
+// Transport 包装 http.RoundTripper，base 为空时使用 http.DefaultTransport
+// 请求需通过 http.NewRequestWithContext 携带上下文，span 才能挂到调用方链路下
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracer().Start(req.Context(), "HTTP "+req.Method+" "+req.URL.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.URLPath(req.URL.Path),
+		))
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, fmt.Sprintf("http status %d", resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// fasthttpHeaderCarrier 适配 fasthttp 请求头用于传播链路上下文
+type fasthttpHeaderCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c fasthttpHeaderCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c fasthttpHeaderCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+func (c fasthttpHeaderCarrier) Keys() []string {
+	var keys []string
+	for key := range c.header.All() {
+		keys = append(keys, string(key))
+	}
+	return keys
+}
+
+// DoFasthttp 使用 fasthttp 客户端发送请求，并为其创建客户端 span
+func DoFasthttp(ctx context.Context, client *fasthttp.Client, name string, req *fasthttp.Request, resp *fasthttp.Response) error {
+	ctx, span := tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(string(req.Header.Method())),
+			semconv.ServerAddress(string(req.URI().Host())),
+			semconv.URLPath(string(req.URI().Path())),
+		))
+	defer span.End()
+
+	otel.GetTextMapPropagator().Inject(ctx, fasthttpHeaderCarrier{header: &req.Header})
+
+	err := client.Do(req, resp)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	status := resp.StatusCode()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if status >= 400 {
+		span.SetStatus(codes.Error, fmt.Sprintf("http status %d", status))
+	}
+	return nil
+}
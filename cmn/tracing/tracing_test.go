@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// exportedSpan 文件导出的 span 中测试关心的字段
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		TraceID string
+		SpanID  string
+	}
+}
+
+// readSpans 读取文件导出的 span，以名称为键
+func readSpans(t *testing.T, path string) map[string]exportedSpan {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open trace file: %v", err)
+	}
+	defer f.Close()
+
+	spans := map[string]exportedSpan{}
+	decoder := json.NewDecoder(bufio.NewReader(f))
+	for decoder.More() {
+		var span exportedSpan
+		if err := decoder.Decode(&span); err != nil {
+			t.Fatalf("failed to decode span: %v", err)
+		}
+		spans[span.Name] = span
+	}
+	return spans
+}
+
+func TestFileExporterWithClientSpan(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "traces.json")
+	err := Setup(context.Background(), Config{Exporter: ExporterFile, FilePath: path})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	ctx, span := StartSpan(context.Background(), "test.parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/ping", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	client := &http.Client{Transport: Transport(nil)}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	EndSpan(span, nil)
+
+	if err = Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	spans := readSpans(t, path)
+	parent, ok := spans["test.parent"]
+	if !ok {
+		t.Fatalf("parent span not exported, got %v", spans)
+	}
+	var clientSpan exportedSpan
+	for name, s := range spans {
+		if name != "test.parent" {
+			clientSpan = s
+		}
+	}
+	if clientSpan.Name == "" {
+		t.Fatalf("client span not exported, got %v", spans)
+	}
+	if clientSpan.Parent.SpanID != parent.SpanContext.SpanID || clientSpan.SpanContext.TraceID != parent.SpanContext.TraceID {
+		t.Errorf("client span is not a child of parent span: parent %+v, client %+v", parent, clientSpan)
+	}
+
+	// 下游收到的 traceparent 应指向客户端 span
+	expected := "00-" + clientSpan.SpanContext.TraceID + "-" + clientSpan.SpanContext.SpanID + "-01"
+	if traceparent != expected {
+		t.Errorf("unexpected traceparent header %q, expected %q", traceparent, expected)
+	}
+}
@@ -2,9 +2,9 @@ package ubanquan_core
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/scheduler"
 	"context"
 
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -20,35 +20,33 @@ var (
 
 var z *zap.Logger
 
-func Init() {
+func Init(ctx context.Context) {
 	z = cmn.GetLogger()
 
-	AppId = viper.GetString("ubanquan.appId")
-	if AppId == "" {
-		z.Fatal("[ FAIL ] ubanquan.appId is empty")
-	}
-	AppSecret = viper.GetString("ubanquan.appSecret")
-	if AppSecret == "" {
-		z.Fatal("[ FAIL ] ubanquan.appSecret is empty")
-	}
-	BaseApiUrl = viper.GetString("ubanquan.baseApiUrl")
-	if BaseApiUrl == "" {
-		z.Fatal("[ FAIL ] ubanquan.baseApiUrl is empty")
-	}
+	cfg := cmn.GetConfig().Ubanquan
+	AppId = cfg.AppId
+	AppSecret = cfg.AppSecret
+	BaseApiUrl = cfg.BaseApiUrl
 
 	// 初始化token
-	ctx := context.Background()
 	err := InitializeToken(ctx, AppId, AppSecret)
 	if err != nil {
 		z.Fatal("[ FAIL ] failed to initialize ubanquan token", zap.Error(err))
 	}
 
-	// 启动token维护协程
-	StartTokenMaintainer(ctx)
+	// 注册token维护任务，每30秒检查一次
+	err = scheduler.Register(scheduler.Job{
+		Name:      "ubanquan_token_refresh",
+		Spec:      "@every 30s",
+		Enable:    true,
+		Singleton: false,
+		Fn:        maintainToken,
+	})
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register ubanquan token refresh job", zap.Error(err))
+	}
 
 	cmn.MiniLogger.Info("[ OK ] ubanquan-core module initialized",
 		zap.String("appId", AppId),
-		zap.String("appSecret", AppSecret[:10]+"...)"),
-		zap.String("accessToken", GetGlobalToken().AccessToken[:10]+"...)"),
 		zap.Int64("expiresTime", GetGlobalToken().ExpireTime))
 }
@@ -2,11 +2,14 @@ package ubanquan_core
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/metrics"
 	"WudangMeta/cmn/points_core"
+	"WudangMeta/cmn/tracing"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -23,12 +26,12 @@ func UpdateAllUsersAssets(ctx context.Context) ([]*AssetUpdateResult, error) {
 	var userExternals []cmn.TUserExternal
 	err := cmn.GormDB.Where("platform = ? AND open_id != '' AND open_id IS NOT NULL", PlatformName).Find(&userExternals).Error
 	if err != nil {
-		z.Error("failed to query users with ubanquan openId", zap.Error(err))
+		cmn.LoggerFrom(ctx).Error("failed to query users with ubanquan openId", zap.Error(err))
 		return nil, fmt.Errorf("failed to query users with ubanquan openId: %w", err)
 	}
 
 	if len(userExternals) == 0 {
-		z.Info("no users with ubanquan openId found")
+		cmn.LoggerFrom(ctx).Info("no users with ubanquan openId found")
 		return []*AssetUpdateResult{}, nil
 	}
 
@@ -38,9 +41,18 @@ func UpdateAllUsersAssets(ctx context.Context) ([]*AssetUpdateResult, error) {
 
 	// 遍历每个用户进行资产更新
 	for _, userExternal := range userExternals {
+		// 服务关闭时停止处理剩余用户
+		if ctx.Err() != nil {
+			cmn.LoggerFrom(ctx).Warn("batch update interrupted",
+				zap.Int("processed", len(results)),
+				zap.Int("total_users", len(userExternals)),
+				zap.Error(ctx.Err()))
+			return results, ctx.Err()
+		}
+
 		result, err := UpdateUserAssetByUserId(ctx, userExternal.UserId)
 		if err != nil {
-			z.Error("failed to update user asset",
+			cmn.LoggerFrom(ctx).Error("failed to update user asset",
 				zap.Error(err),
 				zap.String("user_id", userExternal.UserId.String()),
 				zap.String("open_id", userExternal.OpenId))
@@ -62,10 +74,13 @@ func UpdateAllUsersAssets(ctx context.Context) ([]*AssetUpdateResult, error) {
 		results = append(results, result)
 
 		// 添加短暂延迟，避免对优版权API造成过大压力
-		time.Sleep(100 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+		case <-time.After(100 * time.Millisecond):
+		}
 	}
 
-	z.Info("batch update completed",
+	cmn.LoggerFrom(ctx).Info("batch update completed",
 		zap.Int("total_users", len(userExternals)),
 		zap.Int("success_count", successCount),
 		zap.Int("failure_count", failureCount))
@@ -86,26 +101,26 @@ func UpdateUserAssetByUserId(ctx context.Context, userId uuid.UUID) (*AssetUpdat
 	err := cmn.GormDB.Where("user_id = ? AND platform = ?", userId, PlatformName).First(&userExternal).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			z.Error("user external info not found", zap.String("user_id", userId.String()))
+			cmn.LoggerFrom(ctx).Error("user external info not found", zap.String("user_id", userId.String()))
 			result.ErrorMsg = "user has not bound ubanquan account"
 			return result, err
 		}
-		z.Error("failed to get user external info", zap.Error(err), zap.String("user_id", userId.String()))
+		cmn.LoggerFrom(ctx).Error("failed to get user external info", zap.Error(err), zap.String("user_id", userId.String()))
 		result.ErrorMsg = "failed to get user external info"
 		return result, err
 	}
 
 	if userExternal.OpenId == "" {
 		e := fmt.Errorf("user external openId is empty for user_id: %s", userId.String())
-		z.Error(e.Error())
+		cmn.LoggerFrom(ctx).Error(e.Error())
 		result.ErrorMsg = "user has not bound ubanquan account"
 		return result, e
 	}
 
 	// 调用优版权API获取用户资产
-	cardResp, err := fetchUserAssetsFromUbanquan(userExternal.OpenId)
+	cardResp, err := fetchUserAssetsFromUbanquan(ctx, userExternal.OpenId)
 	if err != nil {
-		z.Error("failed to fetch user assets from ubanquan", zap.Error(err), zap.String("user_id", userId.String()))
+		cmn.LoggerFrom(ctx).Error("failed to fetch user assets from ubanquan", zap.Error(err), zap.String("user_id", userId.String()))
 		result.ErrorMsg = fmt.Sprintf("failed to fetch user assets: %v", err)
 		return result, err
 	}
@@ -113,7 +128,7 @@ func UpdateUserAssetByUserId(ctx context.Context, userId uuid.UUID) (*AssetUpdat
 	// 同步资产到本地数据库
 	addedCount, skippedCount, err := syncUserAssetsToDatabase(ctx, userId, cardResp)
 	if err != nil {
-		z.Error("failed to sync user assets to database", zap.Error(err), zap.String("user_id", userId.String()))
+		cmn.LoggerFrom(ctx).Error("failed to sync user assets to database", zap.Error(err), zap.String("user_id", userId.String()))
 		result.ErrorMsg = fmt.Sprintf("failed to sync user assets: %v", err)
 		return result, err
 	}
@@ -127,12 +142,12 @@ func UpdateUserAssetByUserId(ctx context.Context, userId uuid.UUID) (*AssetUpdat
 }
 
 // fetchUserAssetsFromUbanquan 从优版权API获取用户资产信息
-func fetchUserAssetsFromUbanquan(openId string) (*UbanquanCardResponse, error) {
+func fetchUserAssetsFromUbanquan(ctx context.Context, openId string) (*UbanquanCardResponse, error) {
 	// 获取全局token
 	token := GetGlobalToken()
 	if token == nil {
 		e := fmt.Errorf("global token is not available")
-		z.Error(e.Error())
+		cmn.LoggerFrom(ctx).Error(e.Error())
 		return nil, e
 	}
 
@@ -148,12 +163,19 @@ func fetchUserAssetsFromUbanquan(openId string) (*UbanquanCardResponse, error) {
 	fastReq.Header.Set("access-token", token.AccessToken)
 
 	// 发送请求
+	start := time.Now()
+	errKind := ""
+	defer func() {
+		metrics.ObserveExternalRequest(metrics.ServiceUbanquan, "card", start, errKind)
+	}()
+
 	client := &fasthttp.Client{
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
-	err := client.Do(fastReq, fastResp)
+	err := tracing.DoFasthttp(ctx, client, "ubanquan card", fastReq, fastResp)
 	if err != nil {
+		errKind = metrics.ErrKindNetwork
 		return nil, fmt.Errorf("failed to send request to ubanquan card API: %w", err)
 	}
 
@@ -161,11 +183,13 @@ func fetchUserAssetsFromUbanquan(openId string) (*UbanquanCardResponse, error) {
 	var cardResp UbanquanCardResponse
 	err = json.Unmarshal(fastResp.Body(), &cardResp)
 	if err != nil {
+		errKind = metrics.ErrKindDecode
 		return nil, fmt.Errorf("failed to unmarshal ubanquan card response: %w", err)
 	}
 
 	// 检查API响应状态
 	if !cardResp.Success {
+		errKind = metrics.ErrKindApi
 		return nil, fmt.Errorf("ubanquan card API returned error, code: %v, message: %v", cardResp.Code, cardResp.Message)
 	}
 
@@ -226,10 +250,12 @@ func syncUserAssetsToDatabase(ctx context.Context, userId uuid.UUID, cardResp *U
 					continue
 				}
 
-				// 给用户增加该资产积分
-				err = points_core.AddUserPoints(ctx, tx, userId, metaAsset.Value)
-				if err != nil {
-					return fmt.Errorf("failed to add user points by asset: %w, user_id: %s, meta_asset_id: %d", err, userId.String(), metaAsset.Id)
+				// 给用户增加该资产积分（未定价的元资产不产生积分）
+				if queryMetaAsset.Value > 0 {
+					_, err = points_core.Credit(ctx, tx, userId, points_core.PointsTypeDefault, queryMetaAsset.Value, points_core.ReasonAsset, strconv.FormatInt(userAsset.Id, 10))
+					if err != nil {
+						return fmt.Errorf("failed to add user points by asset: %w, user_id: %s, meta_asset_id: %d", err, userId.String(), queryMetaAsset.Id)
+					}
 				}
 
 				addedCount++
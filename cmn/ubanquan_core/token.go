@@ -1,6 +1,8 @@
 package ubanquan_core
 
 import (
+	"WudangMeta/cmn/metrics"
+	"WudangMeta/cmn/tracing"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -55,33 +57,26 @@ func InitializeToken(ctx context.Context, appId, appSecret string) error {
 	return nil
 }
 
-// StartTokenMaintainer 启动token维护协程
-// 在token过期前自动刷新token
-func StartTokenMaintainer(ctx context.Context) {
-	go func() {
-		z.Info("ubanquan token maintainer started")
-		ticker := time.NewTicker(30 * time.Second) // 每30秒检查一次
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				z.Info("token maintainer stopped due to context cancellation")
-				return
-			case <-ticker.C:
-				// 检查token是否需要刷新
-				if shouldRefreshToken() {
-					if err := refreshGlobalToken(ctx); err != nil {
-						z.Error("failed to refresh token", zap.Error(err))
-						// 如果刷新失败，尝试重新获取token
-						if err := reinitializeToken(ctx); err != nil {
-							z.Error("failed to reinitialize token", zap.Error(err))
-						}
-					}
-				}
-			}
+// maintainToken 定时检查token，在过期前自动刷新
+// token保存在进程内存中，每个实例都需要各自维护，因此注册为非单实例任务
+func maintainToken(ctx context.Context) error {
+	// 检查token是否需要刷新
+	if !shouldRefreshToken() {
+		return nil
+	}
+
+	err := refreshGlobalToken(ctx)
+	if err != nil {
+		z.Error("failed to refresh token", zap.Error(err))
+		// 如果刷新失败，尝试重新获取token
+		err = reinitializeToken(ctx)
+		if err != nil {
+			z.Error("failed to reinitialize token", zap.Error(err))
+			return err
 		}
-	}()
+	}
+
+	return nil
 }
 
 // shouldRefreshToken 检查是否需要刷新token
@@ -168,13 +163,20 @@ func fetchAccessToken(ctx context.Context, appId string, appSecret string) (*Tok
 	fastReq.Header.SetContentType("application/json")
 	fastReq.SetBody(reqBody)
 
+	start := time.Now()
+	errKind := ""
+	defer func() {
+		metrics.ObserveExternalRequest(metrics.ServiceUbanquan, "token", start, errKind)
+	}()
+
 	client := &fasthttp.Client{
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
-	err = client.Do(fastReq, fastResp)
+	err = tracing.DoFasthttp(ctx, client, "ubanquan token", fastReq, fastResp)
 	if err != nil {
+		errKind = metrics.ErrKindNetwork
 		return nil, fmt.Errorf("failed to send request to ubanquan token API: %w", err)
 	}
 
@@ -188,15 +190,18 @@ func fetchAccessToken(ctx context.Context, appId string, appSecret string) (*Tok
 
 	err = json.Unmarshal(fastResp.Body(), &tokenResp)
 	if err != nil {
+		errKind = metrics.ErrKindDecode
 		return nil, fmt.Errorf("failed to unmarshal token response: %w", err)
 	}
 
 	// 检查API响应状态
 	if !tokenResp.Success {
+		errKind = metrics.ErrKindApi
 		return nil, fmt.Errorf("ubanquan token API returned error, code: %v, message: %v", tokenResp.Code, tokenResp.Message)
 	}
 
 	if tokenResp.Data == nil {
+		errKind = metrics.ErrKindApi
 		return nil, fmt.Errorf("ubanquan token API returned empty data")
 	}
 
@@ -218,13 +223,20 @@ func refreshAccessToken(ctx context.Context, refreshToken string) (*Token, error
 	fastReq.SetRequestURI(url)
 	fastReq.Header.SetMethod("GET")
 
+	start := time.Now()
+	errKind := ""
+	defer func() {
+		metrics.ObserveExternalRequest(metrics.ServiceUbanquan, "flush", start, errKind)
+	}()
+
 	client := &fasthttp.Client{
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
-	err := client.Do(fastReq, fastResp)
+	err := tracing.DoFasthttp(ctx, client, "ubanquan flush", fastReq, fastResp)
 	if err != nil {
+		errKind = metrics.ErrKindNetwork
 		return nil, fmt.Errorf("failed to send request to ubanquan flush API: %w", err)
 	}
 
@@ -238,17 +250,30 @@ func refreshAccessToken(ctx context.Context, refreshToken string) (*Token, error
 
 	err = json.Unmarshal(fastResp.Body(), &tokenResp)
 	if err != nil {
+		errKind = metrics.ErrKindDecode
 		return nil, fmt.Errorf("failed to unmarshal refresh token response: %w", err)
 	}
 
 	// 检查API响应状态
 	if !tokenResp.Success {
+		errKind = metrics.ErrKindApi
 		return nil, fmt.Errorf("ubanquan flush API returned error, code: %v, message: %v", tokenResp.Code, tokenResp.Message)
 	}
 
 	if tokenResp.Data == nil {
+		errKind = metrics.ErrKindApi
 		return nil, fmt.Errorf("ubanquan flush API returned empty data")
 	}
 
 	return tokenResp.Data, nil
 }
+
+// TokenValid 检查全局token是否已获取且未过期
+func TokenValid() (bool, time.Time) {
+	token := GetGlobalToken()
+	if token == nil || token.AccessToken == "" {
+		return false, time.Time{}
+	}
+	expiresAt := time.UnixMilli(token.ExpireTime)
+	return time.Now().Before(expiresAt), expiresAt
+}
@@ -1,31 +1,11 @@
 package cmn
 
 import (
-	"errors"
 	"fmt"
-	"math/rand"
 	"os"
 	"time"
-
-	"gorm.io/gorm"
 )
 
-// RandDigits 生成指定位数的随机数字字符串
-func RandDigits(length int) string {
-	if length <= 0 {
-		return ""
-	}
-
-	// 创建独立随机数源
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	digits := make([]byte, length)
-	for i := 0; i < length; i++ {
-		digits[i] = '0' + byte(r.Intn(10)) // 0-9
-	}
-	return string(digits)
-}
-
 // GetDurationUntilNextTargetTime 计算当前时间到下一个指定时间点的间隔
 func GetDurationUntilNextTargetTime(hour, minute, second int, locationName string) (time.Duration, error) {
 	loc, err := time.LoadLocation(locationName)
@@ -71,24 +51,3 @@ func InitDir(dir string) error {
 
 	return nil
 }
-
-// GetConfigFromDB 从配置表读取配置值，如果不存在则创建默认值
-func GetConfigFromDB(key, defaultValue string) (string, error) {
-	var config TCfgCommon
-	err := GormDB.Where("key = ?", key).First(&config).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// 配置不存在，创建默认配置
-			config = TCfgCommon{
-				Key:   key,
-				Value: defaultValue,
-			}
-			if err = GormDB.Create(&config).Error; err != nil {
-				return "", err
-			}
-			return defaultValue, nil
-		}
-		return "", err
-	}
-	return config.Value, nil
-}
@@ -17,3 +17,5 @@ ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT ALL ON SEQUENCES TO wudang_user;
 ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT ALL ON FUNCTIONS TO wudang_user;
 
 
+
+-- 表与视图由程序的迁移命令创建：main migrate up
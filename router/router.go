@@ -1,7 +1,9 @@
 package router
 
 import (
+	"WudangMeta/serve/admin"
 	"WudangMeta/serve/asset"
+	"WudangMeta/serve/health"
 	"WudangMeta/serve/points"
 	"WudangMeta/serve/raffle"
 	"WudangMeta/serve/ranking"
@@ -22,28 +24,71 @@ func InitRoutes(r *gin.Engine) {
 	rankingHandler := ranking.NewHandler()
 	taskHandler := task.NewHandler()
 	raffleHandler := raffle.NewHandler()
+	adminHandler := admin.NewHandler()
+	healthHandler := health.NewHandler()
+
+	// 存活与就绪检查，供容器编排系统探测使用
+	r.GET("/healthz", healthHandler.HandleHealthz) // 存活检查
+	r.GET("/readyz", healthHandler.HandleReadyz)   // 就绪检查
 
 	// 路由组 /api
 	api := r.Group("/api")
 	{
-		api.GET("/sms-code", userMgtHandler.HandleSendSMSCode)   // 发送短信验证码
-		api.POST("/login/by-sms", userMgtHandler.HandleSMSLogin) // 短信验证码登录
+		api.GET("/captcha", userMgtHandler.HandleGetCaptcha)            // 获取图形验证挑战
+		api.POST("/captcha/verify", userMgtHandler.HandleVerifyCaptcha) // 提交图形验证答案
+		api.GET("/sms-code", userMgtHandler.HandleSendSMSCode)          // 发送短信验证码
+		api.POST("/login/by-sms", userMgtHandler.HandleSMSLogin)        // 短信验证码登录
+
+		api.POST("/admin/login", adminHandler.HandleLogin) // 管理员登录
+
+		api.GET("/raffle/winners", raffleHandler.HandleQueryRaffleWinners) // 查询抽奖获奖者
+		api.GET("/asset/meta", assetHandler.HandleQueryMetaAssets)         // 查询元数据资产
+		api.POST("/ranking/list", rankingHandler.HandleQueryRankingList)   // 查询排行榜列表
+
+		// 需要管理员认证的路由组
+		adminApi := api.Group("/")
+		adminApi.Use(admin.AuthMiddleware())
+		{
+			adminApi.POST("/admin/logout", adminHandler.HandleLogout)                                                       // 管理员退出登录
+			adminApi.GET("/admin/me", adminHandler.HandleGetCurrentAdmin)                                                   // 获取当前管理员信息
+			adminApi.PUT("/admin/password", admin.Audit(admin.AuditAdminPasswordChange), adminHandler.HandleChangePassword) // 修改自己的密码
+		}
 
-		api.GET("/raffle/winners", raffleHandler.HandleQueryRaffleWinners)                // 查询抽奖获奖者
-		api.PUT("/raffle/prize/:id", raffleHandler.HandleUpdatePrize)                     // 更新奖品信息
-		api.POST("/raffle/prize", raffleHandler.HandleCreatePrize)                        // 新增奖品
-		api.GET("/raffle/prizes", raffleHandler.HandleQueryPrizes)                        // 查询所有奖品信息
-		api.DELETE("/raffle/prizes", raffleHandler.HandleDeletePrizes)                    // 删除奖品
-		api.PUT("/raffle/config/consume-points", raffleHandler.HandleUpdateConsumePoints) // 更新抽奖消耗积分配置
-		api.GET("/raffle/config/consume-points", raffleHandler.HandleQueryConsumePoints)  // 获取抽奖消耗积分配置
-		api.GET("/user/info/single", userMgtHandler.HandleGetUserInfoByPhone)             // 获取单个用户信息
-		api.GET("/user/info", userMgtHandler.HandleQueryUserInfoList)                     // 获取用户信息列表
-		api.GET("/asset/meta", assetHandler.HandleQueryMetaAssets)                        // 查询元数据资产
-		api.POST("/ranking/list", rankingHandler.HandleQueryRankingList)                  // 查询排行榜列表
-		api.GET("/asset", assetHandler.HandleQueryUserAssetsByPhone)                      // 根据手机号查询用户资产
-		api.GET("/raffle/designated-user", raffleHandler.HandleQueryDesignatedUsers)      // 查询指定用户的抽奖信息
-		api.POST("/raffle/designated-user", raffleHandler.HandleCreateDesignatedUser)     // 新增指定用户抽奖信息
-		api.DELETE("/raffle/designated-user", raffleHandler.HandleDeleteDesignatedUsers)  // 删除指定用户抽奖信息
+		// 须修改初始密码的管理员修改密码后才能访问的路由
+		adminApi = adminApi.Group("/", admin.RequirePasswordChanged())
+		{
+			adminApi.GET("/admin/users", admin.RequirePermission(admin.PermAdminManage), adminHandler.HandleQueryAdmins)                                                                                // 查询管理员列表
+			adminApi.POST("/admin/users", admin.Audit(admin.AuditAdminCreate), admin.RequirePermission(admin.PermAdminManage), adminHandler.HandleCreateAdmin)                                          // 新增管理员
+			adminApi.PUT("/admin/users/:id", admin.Audit(admin.AuditAdminUpdate), admin.RequirePermission(admin.PermAdminManage), adminHandler.HandleUpdateAdmin)                                       // 修改管理员
+			adminApi.GET("/admin/jobs", admin.RequirePermission(admin.PermJobRead), adminHandler.HandleQueryJobs)                                                                                       // 查询定时任务
+			adminApi.POST("/admin/jobs/:name/trigger", admin.Audit(admin.AuditJobTrigger), admin.RequirePermission(admin.PermJobManage), adminHandler.HandleTriggerJob)                                 // 立即执行定时任务
+			adminApi.POST("/admin/jobs/:name/pause", admin.Audit(admin.AuditJobPause), admin.RequirePermission(admin.PermJobManage), adminHandler.HandlePauseJob)                                       // 暂停定时任务
+			adminApi.POST("/admin/jobs/:name/resume", admin.Audit(admin.AuditJobResume), admin.RequirePermission(admin.PermJobManage), adminHandler.HandleResumeJob)                                    // 恢复定时任务
+			adminApi.GET("/admin/log/level", admin.RequirePermission(admin.PermLogLevelManage), adminHandler.HandleGetLogLevel)                                                                         // 查询日志级别
+			adminApi.PUT("/admin/log/level", admin.Audit(admin.AuditLogLevelUpdate), admin.RequirePermission(admin.PermLogLevelManage), adminHandler.HandleSetLogLevel)                                 // 调整日志级别
+			adminApi.GET("/admin/settings", admin.RequirePermission(admin.PermSettingsRead), adminHandler.HandleQuerySettings)                                                                          // 查询运行时配置
+			adminApi.PUT("/admin/settings", admin.Audit(admin.AuditSettingsUpdate), admin.RequirePermission(admin.PermSettingsWrite), adminHandler.HandleUpdateSettings)                                // 修改运行时配置
+			adminApi.GET("/admin/audit-logs", admin.RequirePermission(admin.PermAuditLogRead), adminHandler.HandleQueryAuditLogs)                                                                       // 查询管理操作审计记录
+			adminApi.GET("/admin/audit-logs/export", admin.RequirePermission(admin.PermAuditLogRead), adminHandler.HandleExportAuditLogs)                                                               // 导出管理操作审计记录
+			adminApi.GET("/admin/sms/providers", admin.RequirePermission(admin.PermSmsProviderRead), adminHandler.HandleQuerySmsProviders)                                                              // 查询短信服务商健康状态
+			adminApi.PUT("/raffle/prize/:id", admin.Audit(admin.AuditPrizeUpdate), admin.RequirePermission(admin.PermPrizeWrite), raffleHandler.HandleUpdatePrize)                                      // 更新奖品信息
+			adminApi.POST("/raffle/prize", admin.Audit(admin.AuditPrizeCreate), admin.RequirePermission(admin.PermPrizeWrite), raffleHandler.HandleCreatePrize)                                         // 新增奖品
+			adminApi.GET("/raffle/prizes", admin.RequirePermission(admin.PermPrizeRead), raffleHandler.HandleQueryPrizes)                                                                               // 查询所有奖品信息
+			adminApi.DELETE("/raffle/prizes", admin.Audit(admin.AuditPrizeDelete), admin.RequirePermission(admin.PermPrizeWrite), raffleHandler.HandleDeletePrizes)                                     // 删除奖品
+			adminApi.PUT("/raffle/config/consume-points", admin.Audit(admin.AuditConsumePointsUpdate), admin.RequirePermission(admin.PermRaffleConfigWrite), raffleHandler.HandleUpdateConsumePoints)   // 更新抽奖消耗积分配置
+			adminApi.GET("/raffle/config/consume-points", admin.RequirePermission(admin.PermRaffleConfigRead), raffleHandler.HandleQueryConsumePoints)                                                  // 获取抽奖消耗积分配置
+			adminApi.GET("/raffle/designated-user", admin.RequirePermission(admin.PermDesignatedUserRead), raffleHandler.HandleQueryDesignatedUsers)                                                    // 查询指定用户的抽奖信息
+			adminApi.POST("/raffle/designated-user", admin.Audit(admin.AuditDesignatedUserCreate), admin.RequirePermission(admin.PermDesignatedUserWrite), raffleHandler.HandleCreateDesignatedUser)    // 新增指定用户抽奖信息
+			adminApi.DELETE("/raffle/designated-user", admin.Audit(admin.AuditDesignatedUserDelete), admin.RequirePermission(admin.PermDesignatedUserWrite), raffleHandler.HandleDeleteDesignatedUsers) // 删除指定用户抽奖信息
+			adminApi.GET("/user/info/single", admin.RequirePermission(admin.PermUserRead), userMgtHandler.HandleGetUserInfoByPhone)                                                                     // 获取单个用户信息
+			adminApi.GET("/user/info", admin.RequirePermission(admin.PermUserRead), userMgtHandler.HandleQueryUserInfoList)                                                                             // 获取用户信息列表
+			adminApi.GET("/asset", admin.RequirePermission(admin.PermUserRead), assetHandler.HandleQueryUserAssetsByPhone)                                                                              // 根据手机号查询用户资产
+			adminApi.GET("/points/types", admin.RequirePermission(admin.PermPointsTypeRead), pointsHandler.HandleQueryPointsTypes)                                                                      // 查询积分类型
+			adminApi.POST("/points/types", admin.Audit(admin.AuditPointsTypeCreate), admin.RequirePermission(admin.PermPointsTypeWrite), pointsHandler.HandleCreatePointsType)                          // 新增积分类型
+			adminApi.PUT("/points/types/:id", admin.Audit(admin.AuditPointsTypeUpdate), admin.RequirePermission(admin.PermPointsTypeWrite), pointsHandler.HandleUpdatePointsType)                       // 修改积分类型
+			adminApi.GET("/points/accrual/runs", admin.RequirePermission(admin.PermJobRead), pointsHandler.HandleQueryAssetAccrualRuns)                                                                 // 查询每日资产积分累加运行记录
+			adminApi.POST("/points/accrual/runs/:bizDate", admin.Audit(admin.AuditAccrualRerun), admin.RequirePermission(admin.PermJobManage), pointsHandler.HandleRerunAssetAccrual)                   // 重新执行指定日期的资产积分累加
+		}
 
 		// 需要认证的路由组
 		authApi := api.Group("/")
@@ -53,6 +98,7 @@ func InitRoutes(r *gin.Engine) {
 			authApi.GET("/ubanquan/authentication", ubanquanHandler.HandleAuthentication) // 优版权用户授权
 			authApi.PUT("/ubanquan/asset", ubanquanHandler.HandleUpdateMyAsset)           // 更新优版权用户资产
 			authApi.GET("/points/me", pointsHandler.HandleQueryMyPoints)                  // 获取我的积分
+			authApi.GET("/points/me/history", pointsHandler.HandleQueryMyPointsHistory)   // 查询我的积分流水
 			authApi.GET("/asset/me", assetHandler.HandleQueryMyAsset)                     // 查询我的资产
 			authApi.POST("/task/fortune", taskHandler.HandleAnalyzeMyFortune)             // 分析我的运势
 			authApi.GET("/task/fortune/me", taskHandler.HandleQueryMyFortune)             // 查询我的运势数据
@@ -0,0 +1,311 @@
+package admin
+
+import (
+	"WudangMeta/cmn"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 审计操作
+const (
+	AuditAdminCreate          = "admin.create"           // 新增管理员
+	AuditAdminUpdate          = "admin.update"           // 修改管理员
+	AuditAdminPasswordChange  = "admin.password_change"  // 修改自己的密码
+	AuditJobTrigger           = "job.trigger"            // 立即执行定时任务
+	AuditJobPause             = "job.pause"              // 暂停定时任务
+	AuditJobResume            = "job.resume"             // 恢复定时任务
+	AuditLogLevelUpdate       = "log_level.update"       // 调整日志级别
+	AuditSettingsUpdate       = "settings.update"        // 修改运行时配置
+	AuditPrizeCreate          = "prize.create"           // 新增奖品
+	AuditPrizeUpdate          = "prize.update"           // 修改奖品
+	AuditPrizeDelete          = "prize.delete"           // 删除奖品
+	AuditConsumePointsUpdate  = "consume_points.update"  // 修改抽奖消耗积分
+	AuditDesignatedUserCreate = "designated_user.create" // 新增指定获奖用户
+	AuditDesignatedUserDelete = "designated_user.delete" // 删除指定获奖用户
+	AuditPointsTypeCreate     = "points_type.create"     // 新增积分类型
+	AuditPointsTypeUpdate     = "points_type.update"     // 修改积分类型
+	AuditAccrualRerun         = "accrual.rerun"          // 重新执行资产积分累加
+)
+
+const (
+	auditChangeKey = "audit_change" // 处理函数记录的操作对象与前后数据在上下文中的键
+
+	maxAuditReplySize = 64 << 10 // 解析响应状态时最多缓存的响应体大小
+)
+
+// auditChange 处理函数记录的操作对象与修改前后的数据
+type auditChange struct {
+	target string
+	before any
+	after  any
+}
+
+// adminAuditView 管理员账号在审计记录中的数据
+type adminAuditView struct {
+	cmn.TAdminUser
+	PasswordChanged bool `json:"passwordChanged,omitempty"` // 是否修改了密码
+}
+
+// auditFieldDiff 单个字段的修改
+type auditFieldDiff struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// auditWriter 在写出响应的同时缓存响应体，用于获取响应状态
+type auditWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditWriter) Write(b []byte) (int, error) {
+	if w.body.Len() < maxAuditReplySize {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	if w.body.Len() < maxAuditReplySize {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Audit 管理操作审计中间件，请求处理完成后记录操作人、操作、对象、前后数据与处理结果
+// 需要在AuthMiddleware之后、RequirePermission之前使用，权限不足的请求同样会被记录
+func Audit(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+
+		c.Next()
+
+		record := cmn.TAdminAuditLog{
+			Action:    action,
+			Target:    auditTargetFromPath(c),
+			Ip:        c.ClientIP(),
+			RequestId: cmn.RequestIdFrom(c),
+		}
+		if adminUser, ok := GetCurrentAdmin(c); ok {
+			record.ActorId = adminUser.Id
+			record.ActorName = adminUser.UserName
+		}
+
+		var reply struct {
+			Status int    `json:"status"`
+			Msg    string `json:"msg"`
+		}
+		err := json.Unmarshal(w.body.Bytes(), &reply)
+		if err != nil {
+			reply.Status, reply.Msg = -1, "无法解析响应"
+		}
+		record.Status, record.Msg = reply.Status, reply.Msg
+
+		if v, ok := c.Get(auditChangeKey); ok {
+			change := v.(auditChange)
+			if change.target != "" {
+				record.Target = change.target
+			}
+			err = fillAuditData(&record, change.before, change.after)
+			if err != nil {
+				cmn.LoggerFrom(c).Error("failed to marshal audit data", zap.String("action", action), zap.Error(err))
+			}
+		}
+
+		err = cmn.GormDB.WithContext(c).Create(&record).Error
+		if err != nil {
+			cmn.LoggerFrom(c).Error("failed to save admin audit log",
+				zap.String("action", action),
+				zap.String("target", record.Target),
+				zap.Int("status", record.Status),
+				zap.Error(err))
+		}
+	}
+}
+
+// SetAuditChange 记录本次管理操作的对象与修改前后的数据，由Audit中间件在请求结束后写入审计表
+// 新增操作的 before 与删除操作的 after 传 nil，多次调用时以最后一次为准
+func SetAuditChange(c *gin.Context, target string, before, after any) {
+	c.Set(auditChangeKey, auditChange{target: target, before: before, after: after})
+}
+
+// AuditTarget 生成审计对象标识，如 prize:12
+func AuditTarget(kind string, id any) string {
+	return fmt.Sprintf("%s:%v", kind, id)
+}
+
+// auditTargetFromPath 处理函数未记录操作对象时，以路由与路径参数作为操作对象
+func auditTargetFromPath(c *gin.Context) string {
+	target := c.FullPath()
+	for _, p := range c.Params {
+		target += fmt.Sprintf(" %s=%s", p.Key, p.Value)
+	}
+	return target
+}
+
+// fillAuditData 序列化修改前后的数据，两者均为对象时计算不同的字段
+func fillAuditData(record *cmn.TAdminAuditLog, before, after any) error {
+	var err error
+	if before != nil {
+		record.Before, err = json.Marshal(before)
+		if err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		record.After, err = json.Marshal(after)
+		if err != nil {
+			return err
+		}
+	}
+
+	diff := diffAuditData(record.Before, record.After)
+	if len(diff) > 0 {
+		record.Diff, err = json.Marshal(diff)
+	}
+	return err
+}
+
+// diffAuditData 比较两个JSON对象的顶层字段，任一方不是对象时返回空
+func diffAuditData(before, after []byte) []auditFieldDiff {
+	var o, n map[string]any
+	if json.Unmarshal(before, &o) != nil || json.Unmarshal(after, &n) != nil {
+		return nil
+	}
+
+	fields := map[string]bool{}
+	for k := range o {
+		fields[k] = true
+	}
+	for k := range n {
+		fields[k] = true
+	}
+
+	var diff []auditFieldDiff
+	for field := range fields {
+		if reflect.DeepEqual(o[field], n[field]) {
+			continue
+		}
+		diff = append(diff, auditFieldDiff{Field: field, Old: o[field], New: n[field]})
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].Field < diff[j].Field
+	})
+	return diff
+}
+
+const auditExportBatchSize = 500 // 导出审计记录时每批读取的记录数
+
+// 审计记录查询结果筛选
+const (
+	auditResultSuccess = "success" // 处理成功
+	auditResultFailure = "failure" // 处理失败或被拒绝
+)
+
+// auditLogQuery 根据查询参数构造审计记录查询
+func auditLogQuery(c *gin.Context) (*gorm.DB, error) {
+	query := cmn.GormDB.WithContext(c).Model(&cmn.TAdminAuditLog{})
+
+	if v := c.Query("actorId"); v != "" {
+		actorId, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("操作人ID格式无效")
+		}
+		query = query.Where("actor_id = ?", actorId)
+	}
+	if v := c.Query("action"); v != "" {
+		query = query.Where("action = ?", v)
+	}
+	if v := c.Query("target"); v != "" {
+		query = query.Where("target LIKE ?", escapeLike(v)+"%")
+	}
+	switch c.Query("result") {
+	case "":
+	case auditResultSuccess:
+		query = query.Where("status = 0")
+	case auditResultFailure:
+		query = query.Where("status <> 0")
+	default:
+		return nil, fmt.Errorf("结果筛选可选值：success、failure")
+	}
+	for param, cond := range map[string]string{"from": "created_at >= ?", "to": "created_at < ?"} {
+		v := c.Query(param)
+		if v == "" {
+			continue
+		}
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("时间范围格式无效，应为毫秒时间戳")
+		}
+		query = query.Where(cond, ms)
+	}
+	return query, nil
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// writeAuditLogCSV 分批读取审计记录并写出CSV
+func writeAuditLogCSV(w io.Writer, query *gorm.DB) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"id", "createdAt", "actorId", "actorName", "action", "target", "status", "msg", "ip", "requestId", "diff", "before", "after"})
+	if err != nil {
+		return err
+	}
+
+	var batch []cmn.TAdminAuditLog
+	result := query.FindInBatches(&batch, auditExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, r := range batch {
+			err := cw.Write([]string{
+				strconv.FormatInt(r.Id, 10),
+				time.UnixMilli(r.CreatedAt).Format(time.RFC3339),
+				r.ActorId.String(),
+				csvCell(r.ActorName),
+				r.Action,
+				csvCell(r.Target),
+				strconv.Itoa(r.Status),
+				csvCell(r.Msg),
+				r.Ip,
+				r.RequestId,
+				csvCell(string(r.Diff)),
+				csvCell(string(r.Before)),
+				csvCell(string(r.After)),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell 避免以公式字符开头的内容在电子表格中被当作公式执行
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
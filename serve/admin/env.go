@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/scheduler"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/sessions"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	adminSessionKey = "admin-session" // 管理员session的cookie名称
+)
+
+var (
+	sessionStore *sessions.CookieStore
+)
+
+var z *zap.Logger
+
+func Init(ctx context.Context) {
+	z = cmn.GetLogger()
+
+	err := initSessionStore()
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to initialize admin session store", zap.Error(err))
+	}
+
+	// 初始化超级管理员（仅在管理员表为空时创建）
+	err = initSuperAdmin()
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to initialize super admin", zap.Error(err))
+	}
+
+	err = registerLoginSettings(ctx)
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register admin login settings", zap.Error(err))
+	}
+
+	err = scheduler.Register(scheduler.Job{
+		Name:      JobPurgeLoginFailure,
+		Spec:      "45 * * * *",
+		Enable:    true,
+		Singleton: true,
+		Fn:        purgeLoginFailures,
+	})
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register admin login failure purge job", zap.Error(err))
+	}
+
+	cmn.MiniLogger.Info("[ OK ] admin module initialized")
+}
+
+func initSessionStore() error {
+	cfg := cmn.GetConfig().Session
+	if cfg.AuthKey == "" || cfg.EncryptionKey == "" {
+		return fmt.Errorf("gorilla session store key is empty")
+	}
+
+	// 管理员与终端用户使用独立的cookie，互不影响
+	sessionStore = sessions.NewCookieStore([]byte(cfg.AuthKey), []byte(cfg.EncryptionKey))
+	sessionStore.Options = &sessions.Options{
+		Path:     "/",
+		MaxAge:   3600 * 8, // 8小时
+		HttpOnly: true,
+		Secure:   false, // 开发环境设为false，生产环境应设为true
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	return nil
+}
+
+// initSuperAdmin 根据配置创建初始超级管理员，首次登录后须修改密码
+// 密码不写入配置文件，通过环境变量 WUDANG_ADMIN_SUPER_ADMIN_PASSWORD 或 WUDANG_ADMIN_SUPER_ADMIN_PASSWORD_FILE 提供，
+// 管理员表为空且未提供时拒绝启动
+func initSuperAdmin() error {
+	var count int64
+	err := cmn.GormDB.Model(&cmn.TAdminUser{}).Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to count admin users: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	superAdminConfig := cmn.GetConfig().Admin.SuperAdmin
+	userName := superAdminConfig.UserName
+	password := superAdminConfig.Password
+	if userName == "" || password == "" {
+		return fmt.Errorf("no admin user exists, admin.superAdmin.userName and WUDANG_ADMIN_SUPER_ADMIN_PASSWORD(_FILE) are required to create the super admin")
+	}
+
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	superAdmin := cmn.TAdminUser{
+		Id:                 uuid.New(),
+		UserName:           userName,
+		PasswordHash:       passwordHash,
+		NickName:           userName,
+		Role:               RoleSuperAdmin,
+		Status:             "00",
+		MustChangePassword: true,
+	}
+	err = cmn.GormDB.Create(&superAdmin).Error
+	if err != nil && !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return fmt.Errorf("failed to create super admin: %w", err)
+	}
+
+	z.Info("super admin created", zap.String("userName", userName))
+	return nil
+}
+
+// hashPassword 生成密码哈希
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// checkPassword 校验密码与哈希是否匹配
+func checkPassword(passwordHash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) == nil
+}
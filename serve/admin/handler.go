@@ -0,0 +1,1043 @@
+package admin
+
+import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/scheduler"
+	"WudangMeta/cmn/settings"
+	"WudangMeta/cmn/sms"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type Handler interface {
+	HandleLogin(c *gin.Context)
+	HandleLogout(c *gin.Context)
+	HandleGetCurrentAdmin(c *gin.Context)
+	HandleChangePassword(c *gin.Context)
+	HandleQueryAdmins(c *gin.Context)
+	HandleCreateAdmin(c *gin.Context)
+	HandleUpdateAdmin(c *gin.Context)
+	HandleQueryJobs(c *gin.Context)
+	HandleTriggerJob(c *gin.Context)
+	HandlePauseJob(c *gin.Context)
+	HandleResumeJob(c *gin.Context)
+	HandleGetLogLevel(c *gin.Context)
+	HandleSetLogLevel(c *gin.Context)
+	HandleQuerySettings(c *gin.Context)
+	HandleUpdateSettings(c *gin.Context)
+	HandleQueryAuditLogs(c *gin.Context)
+	HandleExportAuditLogs(c *gin.Context)
+	HandleQuerySmsProviders(c *gin.Context)
+}
+
+type handler struct {
+}
+
+func NewHandler() Handler {
+	return &handler{}
+}
+
+// HandleLogin 处理管理员账号密码登录
+func (h *handler) HandleLogin(c *gin.Context) {
+	var req cmn.ReqProto
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求参数错误，请检查是否符合请求协议",
+		})
+		return
+	}
+
+	var d struct {
+		UserName string `json:"userName"`
+		Password string `json:"password"`
+	}
+	err = json.Unmarshal(req.Data, &d)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求数据格式错误",
+		})
+		return
+	}
+
+	if d.UserName == "" || d.Password == "" {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "用户名和密码不能为空",
+		})
+		return
+	}
+
+	allowed, retryAfter, err := checkLoginAllowed(c, nil, d.UserName, c.ClientIP())
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "检查登录限制失败",
+		})
+		return
+	}
+	if !allowed {
+		retrySeconds := int64(math.Ceil(retryAfter.Seconds()))
+		retryJson, _ := json.Marshal(map[string]int64{"retryAfter": retrySeconds})
+		cmn.LoggerFrom(c).Warn("admin login throttled", zap.String("userName", d.UserName), zap.String("ip", c.ClientIP()))
+		c.Header("Retry-After", strconv.FormatInt(retrySeconds, 10))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: http.StatusTooManyRequests,
+			Msg:    fmt.Sprintf("登录失败次数过多，请%d秒后再试", retrySeconds),
+			Data:   retryJson,
+		})
+		return
+	}
+
+	var adminUser cmn.TAdminUser
+	err = cmn.GormDB.Where("user_name = ?", d.UserName).First(&adminUser).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			cmn.LoggerFrom(c).Warn("admin login with unknown user name", zap.String("userName", d.UserName))
+			recordLoginFailure(c, nil, d.UserName, c.ClientIP())
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "用户名或密码错误",
+			})
+			return
+		}
+		cmn.LoggerFrom(c).Error("failed to query admin user", zap.Error(err), zap.String("userName", d.UserName))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询管理员失败",
+		})
+		return
+	}
+
+	if !checkPassword(adminUser.PasswordHash, d.Password) {
+		cmn.LoggerFrom(c).Warn("admin login with wrong password", zap.String("userName", d.UserName))
+		recordLoginFailure(c, nil, d.UserName, c.ClientIP())
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "用户名或密码错误",
+		})
+		return
+	}
+
+	if adminUser.Status != "00" {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 403,
+			Msg:    "管理员已被禁用",
+		})
+		return
+	}
+
+	session, err := sessionStore.Get(c.Request, adminSessionKey)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to get admin session", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "创建session失败",
+		})
+		return
+	}
+
+	session.Values["admin_id"] = adminUser.Id.String()
+	session.Values["login_time"] = time.Now().Unix()
+
+	err = session.Save(c.Request, c.Writer)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to save admin session", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "保存session失败",
+		})
+		return
+	}
+
+	clearLoginFailures(c, nil, adminUser.UserName)
+
+	err = cmn.GormDB.Model(&adminUser).Update("login_time", time.Now().UnixMilli()).Error
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to update admin login time", zap.Error(err))
+	}
+
+	cmn.LoggerFrom(c).Info("admin logged in", zap.String("adminId", adminUser.Id.String()), zap.String("role", adminUser.Role))
+
+	adminJson, err := json.Marshal(adminUser)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal admin user", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "响应数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "登录成功",
+		Data:   adminJson,
+	})
+}
+
+// HandleLogout 处理管理员退出登录
+func (h *handler) HandleLogout(c *gin.Context) {
+	session, err := sessionStore.Get(c.Request, adminSessionKey)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to get admin session", zap.Error(err))
+	}
+
+	// 将MaxAge置为负数以删除cookie
+	session.Options.MaxAge = -1
+	err = session.Save(c.Request, c.Writer)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to delete admin session", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "退出登录失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "已退出登录",
+	})
+}
+
+// HandleGetCurrentAdmin 获取当前登录管理员信息及权限
+func (h *handler) HandleGetCurrentAdmin(c *gin.Context) {
+	adminUser, ok := GetCurrentAdmin(c)
+	if !ok {
+		cmn.LoggerFrom(c).Error("failed to get current admin from context")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 401,
+			Msg:    "管理员未登录或登录已过期",
+		})
+		return
+	}
+
+	responseData := map[string]interface{}{
+		"admin":       adminUser,
+		"permissions": rolePermissions[adminUser.Role],
+	}
+
+	responseJson, err := json.Marshal(responseData)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal response data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "响应数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "success",
+		Data:   responseJson,
+	})
+}
+
+// HandleChangePassword 当前管理员修改自己的密码，修改后清除须修改密码的标记
+func (h *handler) HandleChangePassword(c *gin.Context) {
+	adminUser, ok := GetCurrentAdmin(c)
+	if !ok {
+		cmn.LoggerFrom(c).Error("failed to get current admin from context")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 401,
+			Msg:    "管理员未登录或登录已过期",
+		})
+		return
+	}
+
+	var req cmn.ReqProto
+	if err := c.ShouldBindJSON(&req); err != nil {
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
+		})
+		return
+	}
+
+	var d struct {
+		OldPassword string `json:"oldPassword"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := json.Unmarshal(req.Data, &d); err != nil {
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
+		})
+		return
+	}
+
+	if !checkPassword(adminUser.PasswordHash, d.OldPassword) {
+		cmn.LoggerFrom(c).Warn("admin change password with wrong old password", zap.String("userName", adminUser.UserName))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "原密码错误",
+		})
+		return
+	}
+	if len(d.NewPassword) < 8 {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "密码长度不能少于8位",
+		})
+		return
+	}
+	if d.NewPassword == d.OldPassword {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "新密码不能与原密码相同",
+		})
+		return
+	}
+
+	passwordHash, err := hashPassword(d.NewPassword)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to hash password", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "生成密码失败",
+		})
+		return
+	}
+
+	err = cmn.GormDB.Model(adminUser).Updates(map[string]interface{}{
+		"password_hash":        passwordHash,
+		"must_change_password": false,
+	}).Error
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to update admin password", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "修改密码失败",
+		})
+		return
+	}
+
+	after := adminAuditView{TAdminUser: *adminUser, PasswordChanged: true}
+	after.MustChangePassword = false
+	SetAuditChange(c, AuditTarget("admin", adminUser.UserName), adminAuditView{TAdminUser: *adminUser}, after)
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "密码修改成功",
+	})
+}
+
+// HandleQueryAdmins 分页查询管理员列表
+func (h *handler) HandleQueryAdmins(c *gin.Context) {
+	pageStr := c.Query("page")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	sizeStr := c.Query("pageSize")
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size < 1 {
+		size = 10
+	}
+
+	// 限制每页最大数量
+	if size > 100 {
+		size = 100
+	}
+
+	offset := (page - 1) * size
+
+	var admins []cmn.TAdminUser
+	var total int64
+
+	if err = cmn.GormDB.Model(&cmn.TAdminUser{}).Count(&total).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to count admin users", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询管理员总数失败",
+		})
+		return
+	}
+
+	if err = cmn.GormDB.Model(&cmn.TAdminUser{}).
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(size).
+		Find(&admins).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to query admin users", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询管理员列表失败",
+		})
+		return
+	}
+
+	adminsJson, err := json.Marshal(admins)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal admin users", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status:   0,
+		Msg:      "success",
+		Data:     adminsJson,
+		RowCount: total,
+	})
+}
+
+// HandleCreateAdmin 新增管理员
+func (h *handler) HandleCreateAdmin(c *gin.Context) {
+	var req cmn.ReqProto
+	if err := c.ShouldBindJSON(&req); err != nil {
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
+		})
+		return
+	}
+
+	var d struct {
+		UserName string `json:"userName"`
+		Password string `json:"password"`
+		NickName string `json:"nickName"`
+		Role     string `json:"role"`
+	}
+	if err := json.Unmarshal(req.Data, &d); err != nil {
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
+		})
+		return
+	}
+
+	if d.UserName == "" {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "用户名不能为空",
+		})
+		return
+	}
+
+	if len(d.Password) < 8 {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "密码长度不能少于8位",
+		})
+		return
+	}
+
+	if !IsValidRole(d.Role) {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "角色无效",
+		})
+		return
+	}
+
+	// 检查用户名是否已存在
+	var existing cmn.TAdminUser
+	if err := cmn.GormDB.Where("user_name = ?", d.UserName).First(&existing).Error; err == nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "用户名已存在",
+		})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		cmn.LoggerFrom(c).Error("failed to check existing admin user", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "检查用户名失败",
+		})
+		return
+	}
+
+	passwordHash, err := hashPassword(d.Password)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to hash password", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "生成密码失败",
+		})
+		return
+	}
+
+	if d.NickName == "" {
+		d.NickName = d.UserName
+	}
+
+	newAdmin := cmn.TAdminUser{
+		Id:           uuid.New(),
+		UserName:     d.UserName,
+		PasswordHash: passwordHash,
+		NickName:     d.NickName,
+		Role:         d.Role,
+		Status:       "00",
+	}
+	if err = cmn.GormDB.Create(&newAdmin).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to create admin user", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "创建管理员失败",
+		})
+		return
+	}
+
+	SetAuditChange(c, AuditTarget("admin", newAdmin.UserName), nil, newAdmin)
+
+	newAdminJson, err := json.Marshal(newAdmin)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal new admin user", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "管理员创建成功",
+		Data:   newAdminJson,
+	})
+}
+
+// HandleUpdateAdmin 修改管理员角色、状态或密码
+func (h *handler) HandleUpdateAdmin(c *gin.Context) {
+	adminId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "管理员ID格式无效",
+		})
+		return
+	}
+
+	var req cmn.ReqProto
+	if err = c.ShouldBindJSON(&req); err != nil {
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
+		})
+		return
+	}
+
+	var d struct {
+		NickName string `json:"nickName"`
+		Role     string `json:"role"`
+		Status   string `json:"status"`
+		Password string `json:"password"`
+	}
+	if err = json.Unmarshal(req.Data, &d); err != nil {
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
+		})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if d.NickName != "" {
+		updates["nick_name"] = d.NickName
+	}
+	if d.Role != "" {
+		if !IsValidRole(d.Role) {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "角色无效",
+			})
+			return
+		}
+		updates["role"] = d.Role
+	}
+	if d.Status != "" {
+		if d.Status != "00" && d.Status != "01" {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "状态无效",
+			})
+			return
+		}
+		updates["status"] = d.Status
+	}
+	if d.Password != "" {
+		if len(d.Password) < 8 {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "密码长度不能少于8位",
+			})
+			return
+		}
+		passwordHash, err := hashPassword(d.Password)
+		if err != nil {
+			cmn.LoggerFrom(c).Error("failed to hash password", zap.Error(err))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: -1,
+				Msg:    "生成密码失败",
+			})
+			return
+		}
+		updates["password_hash"] = passwordHash
+	}
+
+	if len(updates) == 0 {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "没有需要更新的字段",
+		})
+		return
+	}
+
+	// 禁止管理员降级或禁用自己，避免系统失去超级管理员
+	currentAdminId, _ := GetCurrentAdminID(c)
+	if currentAdminId == adminId && (d.Role != "" && d.Role != RoleSuperAdmin || d.Status == "01") {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "不能修改自己的角色或禁用自己",
+		})
+		return
+	}
+
+	var existing cmn.TAdminUser
+	if err = cmn.GormDB.Where("id = ?", adminId).First(&existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "管理员不存在",
+			})
+			return
+		}
+		cmn.LoggerFrom(c).Error("failed to query admin user", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询管理员失败",
+		})
+		return
+	}
+
+	before := existing
+	if err = cmn.GormDB.Model(&existing).Updates(updates).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to update admin user", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "更新管理员失败",
+		})
+		return
+	}
+
+	// 密码哈希不输出，审计记录中只标记密码是否修改
+	after := adminAuditView{PasswordChanged: d.Password != ""}
+	if err = cmn.GormDB.Where("id = ?", adminId).First(&after.TAdminUser).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to query updated admin user", zap.Error(err))
+	}
+	SetAuditChange(c, AuditTarget("admin", before.UserName), adminAuditView{TAdminUser: before}, after)
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "管理员信息更新成功",
+	})
+}
+
+// HandleQueryJobs 查询所有定时任务的配置、暂停状态与最近一次执行结果
+func (h *handler) HandleQueryJobs(c *gin.Context) {
+	jobs, err := scheduler.QueryJobs(c)
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询定时任务失败",
+		})
+		return
+	}
+
+	jobsJson, err := json.Marshal(jobs)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal jobs", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status:   0,
+		Msg:      "success",
+		Data:     jobsJson,
+		RowCount: int64(len(jobs)),
+	})
+}
+
+// HandleTriggerJob 立即执行一次指定的定时任务，任务在后台执行
+func (h *handler) HandleTriggerJob(c *gin.Context) {
+	jobName := c.Param("name")
+	SetAuditChange(c, AuditTarget("job", jobName), nil, nil)
+	err := scheduler.Trigger(jobName)
+	if err != nil {
+		switch {
+		case errors.Is(err, scheduler.ErrJobNotFound):
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "定时任务不存在",
+			})
+		case errors.Is(err, scheduler.ErrJobRunning):
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "定时任务正在执行，请稍后再试",
+			})
+		default:
+			cmn.LoggerFrom(c).Error("failed to trigger job", zap.String("job", jobName), zap.Error(err))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: -1,
+				Msg:    "触发定时任务失败",
+			})
+		}
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "定时任务已开始执行",
+	})
+}
+
+// HandlePauseJob 暂停定时任务的计划执行，暂停期间仍可手动触发
+func (h *handler) HandlePauseJob(c *gin.Context) {
+	h.setJobPaused(c, true)
+}
+
+// HandleResumeJob 恢复定时任务的计划执行
+func (h *handler) HandleResumeJob(c *gin.Context) {
+	h.setJobPaused(c, false)
+}
+
+func (h *handler) setJobPaused(c *gin.Context, paused bool) {
+	jobName := c.Param("name")
+	SetAuditChange(c, AuditTarget("job", jobName), nil, nil)
+	err := scheduler.SetJobPaused(c, jobName, paused)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrJobNotFound) {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "定时任务不存在",
+			})
+			return
+		}
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "更新定时任务状态失败",
+		})
+		return
+	}
+
+	msg := "定时任务已恢复"
+	if paused {
+		msg = "定时任务已暂停"
+	}
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    msg,
+	})
+}
+
+// HandleGetLogLevel 查询本实例当前的日志级别
+func (h *handler) HandleGetLogLevel(c *gin.Context) {
+	statusJson, err := json.Marshal(cmn.GetLogLevel())
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal log level", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "success",
+		Data:   statusJson,
+	})
+}
+
+// HandleSetLogLevel 调整本实例的日志级别，可指定持续时间，到期后恢复为配置的级别
+// 多实例部署时仅对处理该请求的实例生效
+func (h *handler) HandleSetLogLevel(c *gin.Context) {
+	var req cmn.ReqProto
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求参数错误，请检查是否符合请求协议",
+		})
+		return
+	}
+
+	var d struct {
+		Level    string `json:"level"`    // 日志级别：debug/info/warn/error
+		Duration string `json:"duration"` // 持续时间，如 30m，为空表示一直生效到重启
+	}
+	err = json.Unmarshal(req.Data, &d)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求数据格式错误",
+		})
+		return
+	}
+
+	var duration time.Duration
+	if d.Duration != "" {
+		duration, err = time.ParseDuration(d.Duration)
+		if err != nil || duration <= 0 {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "持续时间格式错误",
+			})
+			return
+		}
+	}
+
+	before := cmn.GetLogLevel()
+	err = cmn.SetLogLevel(d.Level, duration)
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "日志级别错误，可选值：debug、info、warn、error",
+		})
+		return
+	}
+
+	adminId, _ := GetCurrentAdminID(c)
+	cmn.LoggerFrom(c).Warn("log level changed by admin",
+		zap.String("adminId", adminId.String()),
+		zap.String("level", d.Level),
+		zap.Duration("duration", duration))
+	SetAuditChange(c, "log:level", before, cmn.GetLogLevel())
+
+	h.HandleGetLogLevel(c)
+}
+
+// HandleQuerySettings 查询所有已注册的运行时配置
+func (h *handler) HandleQuerySettings(c *gin.Context) {
+	list := settings.List()
+	listJson, err := json.Marshal(list)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal settings", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status:   0,
+		Msg:      "success",
+		Data:     listJson,
+		RowCount: int64(len(list)),
+	})
+}
+
+// HandleUpdateSettings 修改运行时配置，data 为配置键到新值的映射，全部校验通过后一起生效
+// 字符串值按原样保存，其他值保存为 JSON 文本，如 {"raffle.consumePointsValue": 200}
+func (h *handler) HandleUpdateSettings(c *gin.Context) {
+	var req cmn.ReqProto
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求参数错误，请检查是否符合请求协议",
+		})
+		return
+	}
+
+	var d map[string]json.RawMessage
+	err = json.Unmarshal(req.Data, &d)
+	if err != nil || len(d) == 0 {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求数据格式错误",
+		})
+		return
+	}
+
+	values := make(map[string]string, len(d))
+	for key, raw := range d {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			values[key] = s
+			continue
+		}
+		values[key] = string(raw)
+	}
+
+	before := map[string]string{}
+	for _, s := range settings.List() {
+		if _, ok := values[s.Key]; ok {
+			before[s.Key] = s.Value
+		}
+	}
+
+	err = settings.Set(c, nil, values)
+	if errors.Is(err, settings.ErrUnknownSetting) || errors.Is(err, settings.ErrInvalidValue) {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "保存配置失败",
+		})
+		return
+	}
+
+	SetAuditChange(c, "settings", before, values)
+
+	adminId, _ := GetCurrentAdminID(c)
+	for key, value := range values {
+		cmn.LoggerFrom(c).Warn("setting changed by admin",
+			zap.String("adminId", adminId.String()),
+			zap.String("key", key),
+			zap.String("value", value))
+	}
+
+	h.HandleQuerySettings(c)
+}
+
+// HandleQueryAuditLogs 分页查询管理操作审计记录，按时间倒序
+// 查询参数：actorId、action、target（前缀匹配）、result（success/failure）、from、to（毫秒时间戳）
+func (h *handler) HandleQueryAuditLogs(c *gin.Context) {
+	query, err := auditLogQuery(c)
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    err.Error(),
+		})
+		return
+	}
+
+	pageStr := c.Query("page")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	sizeStr := c.Query("pageSize")
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size < 1 {
+		size = 10
+	}
+
+	// 限制每页最大数量
+	if size > 100 {
+		size = 100
+	}
+
+	var total int64
+	if err = query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to count audit logs", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询审计记录总数失败",
+		})
+		return
+	}
+
+	var logs []cmn.TAdminAuditLog
+	if err = query.Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&logs).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to query audit logs", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询审计记录失败",
+		})
+		return
+	}
+
+	logsJson, err := json.Marshal(logs)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal audit logs", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status:   0,
+		Msg:      "success",
+		Data:     logsJson,
+		RowCount: total,
+	})
+}
+
+// HandleExportAuditLogs 按查询条件导出管理操作审计记录为CSV，按时间正序分批写出
+func (h *handler) HandleExportAuditLogs(c *gin.Context) {
+	query, err := auditLogQuery(c)
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    err.Error(),
+		})
+		return
+	}
+
+	fileName := fmt.Sprintf("audit-log-%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Status(http.StatusOK)
+
+	err = writeAuditLogCSV(c.Writer, query)
+	if err != nil {
+		// 响应头已发送，只能记录日志
+		cmn.LoggerFrom(c).Error("failed to export audit logs", zap.Error(err))
+	}
+}
+
+// HandleQuerySmsProviders 查询短信服务商的优先级与熔断状态
+func (h *handler) HandleQuerySmsProviders(c *gin.Context) {
+	list := sms.Health()
+	listJson, err := json.Marshal(list)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal sms providers", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status:   0,
+		Msg:      "success",
+		Data:     listJson,
+		RowCount: int64(len(list)),
+	})
+}
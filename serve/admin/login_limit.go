@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/settings"
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 管理员登录失败限制
+//
+// 登录失败（用户名不存在或密码错误）写入 t_admin_login_failure，登录前按用户名与IP统计窗口期内的失败次数，
+// 达到上限后拒绝登录直到窗口期内的失败记录过期。登录成功后清除该用户名的失败记录。
+// 记录保存在数据库中，多实例部署时同样有效。
+
+// 登录限制配置键
+const (
+	settingLoginMaxFailuresPerUser = "admin.login.maxFailuresPerUser" // 单个用户名窗口期内的最大失败次数
+	settingLoginMaxFailuresPerIp   = "admin.login.maxFailuresPerIp"   // 单个IP窗口期内的最大失败次数
+	settingLoginFailureWindow      = "admin.login.failureWindow"      // 统计失败次数的窗口期
+)
+
+const (
+	JobPurgeLoginFailure = "admin_login_failure_purge" // 清理过期登录失败记录的定时任务
+
+	loginFailureRetention = 24 * time.Hour // 登录失败记录的保留时长，不短于窗口期
+)
+
+func registerLoginSettings(ctx context.Context) error {
+	positive := func(ctx context.Context, value any) error {
+		if value.(int64) < 1 {
+			return fmt.Errorf("limit %d < 1", value.(int64))
+		}
+		return nil
+	}
+	return settings.Register(ctx,
+		settings.Definition{Key: settingLoginMaxFailuresPerUser, Kind: settings.KindInt, Default: "5", Description: "单个管理员用户名在窗口期内允许的登录失败次数", Validate: positive},
+		settings.Definition{Key: settingLoginMaxFailuresPerIp, Kind: settings.KindInt, Default: "20", Description: "单个IP在窗口期内允许的管理员登录失败次数", Validate: positive},
+		settings.Definition{
+			Key:         settingLoginFailureWindow,
+			Kind:        settings.KindDuration,
+			Default:     "15m",
+			Description: "统计管理员登录失败次数的窗口期",
+			Validate: func(ctx context.Context, value any) error {
+				window := value.(time.Duration)
+				if window < time.Minute || window > loginFailureRetention {
+					return fmt.Errorf("window %v out of range [1m, %v]", window, loginFailureRetention)
+				}
+				return nil
+			},
+		},
+	)
+}
+
+// checkLoginAllowed 返回用户名与IP是否允许尝试登录，拒绝时返回距可再次尝试的时长
+func checkLoginAllowed(ctx context.Context, db *gorm.DB, userName, ip string) (bool, time.Duration, error) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	window := settings.Duration(settingLoginFailureWindow)
+	since := time.Now().Add(-window).UnixMilli()
+	rules := []struct {
+		column string
+		value  string
+		limit  int64
+	}{
+		{column: "user_name", value: userName, limit: settings.Int(settingLoginMaxFailuresPerUser)},
+		{column: "ip", value: ip, limit: settings.Int(settingLoginMaxFailuresPerIp)},
+	}
+	for _, rule := range rules {
+		var failures []int64
+		err := db.WithContext(ctx).Model(&cmn.TAdminLoginFailure{}).
+			Where(rule.column+" = ? AND created_at > ?", rule.value, since).
+			Order("created_at DESC").
+			Limit(int(rule.limit)).
+			Pluck("created_at", &failures).Error
+		if err != nil {
+			e := fmt.Errorf("failed to count admin login failures: %w", err)
+			cmn.LoggerFrom(ctx).Error(e.Error(), zap.String("userName", userName), zap.String("ip", ip))
+			return false, 0, e
+		}
+		if int64(len(failures)) < rule.limit {
+			continue
+		}
+		// 最早的一次失败记录过期后才能再次尝试
+		retryAfter := time.Until(time.UnixMilli(failures[len(failures)-1]).Add(window))
+		if retryAfter < time.Second {
+			retryAfter = time.Second
+		}
+		return false, retryAfter, nil
+	}
+	return true, 0, nil
+}
+
+// recordLoginFailure 记录一次登录失败
+func recordLoginFailure(ctx context.Context, db *gorm.DB, userName, ip string) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	err := db.WithContext(ctx).Create(&cmn.TAdminLoginFailure{UserName: userName, Ip: ip}).Error
+	if err != nil {
+		cmn.LoggerFrom(ctx).Error("failed to record admin login failure", zap.Error(err), zap.String("userName", userName))
+	}
+}
+
+// clearLoginFailures 登录成功后清除用户名的失败记录
+func clearLoginFailures(ctx context.Context, db *gorm.DB, userName string) {
+	if db == nil {
+		db = cmn.GormDB
+	}
+
+	err := db.WithContext(ctx).Where("user_name = ?", userName).Delete(&cmn.TAdminLoginFailure{}).Error
+	if err != nil {
+		cmn.LoggerFrom(ctx).Error("failed to clear admin login failures", zap.Error(err), zap.String("userName", userName))
+	}
+}
+
+// purgeLoginFailures 清理超过保留时长的登录失败记录
+func purgeLoginFailures(ctx context.Context) error {
+	before := time.Now().Add(-loginFailureRetention).UnixMilli()
+	result := cmn.GormDB.WithContext(ctx).Where("created_at <= ?", before).Delete(&cmn.TAdminLoginFailure{})
+	if result.Error != nil {
+		e := fmt.Errorf("failed to purge admin login failures: %w", result.Error)
+		z.Error(e.Error())
+		return e
+	}
+	z.Info("expired admin login failures purged", zap.Int64("deleted", result.RowsAffected))
+	return nil
+}
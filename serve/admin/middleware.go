@@ -0,0 +1,193 @@
+package admin
+
+import (
+	"WudangMeta/cmn"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AuthMiddleware 管理员认证中间件
+// 验证管理员是否已登录，并将管理员信息存储到上下文中
+func AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 获取session
+		session, err := sessionStore.Get(c.Request, adminSessionKey)
+		if err != nil {
+			cmn.LoggerFrom(c).Error("failed to get admin session", zap.Error(err))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 401,
+				Msg:    "管理员未登录或登录已过期",
+			})
+			c.Abort()
+			return
+		}
+
+		// 检查session中是否有管理员ID
+		adminIdStr, ok := session.Values["admin_id"].(string)
+		if !ok || adminIdStr == "" {
+			cmn.LoggerFrom(c).Error("admin_id not found in session")
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 401,
+				Msg:    "管理员未登录或登录已过期",
+			})
+			c.Abort()
+			return
+		}
+
+		adminId, err := uuid.Parse(adminIdStr)
+		if err != nil {
+			cmn.LoggerFrom(c).Error("invalid admin_id in session", zap.Error(err), zap.String("admin_id", adminIdStr))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 401,
+				Msg:    "管理员信息无效",
+			})
+			c.Abort()
+			return
+		}
+
+		// 每次请求都从数据库读取，保证角色变更与禁用即时生效
+		var adminUser cmn.TAdminUser
+		err = cmn.GormDB.Where("id = ?", adminId).First(&adminUser).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				cmn.LoggerFrom(c).Error("admin user not found", zap.String("admin_id", adminIdStr))
+				cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+					Status: 401,
+					Msg:    "管理员不存在",
+				})
+				c.Abort()
+				return
+			}
+			cmn.LoggerFrom(c).Error("failed to query admin user", zap.Error(err), zap.String("admin_id", adminIdStr))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: -1,
+				Msg:    "查询管理员信息失败",
+			})
+			c.Abort()
+			return
+		}
+
+		if adminUser.Status != "00" {
+			cmn.LoggerFrom(c).Error("admin user is disabled", zap.String("admin_id", adminIdStr), zap.String("status", adminUser.Status))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 403,
+				Msg:    "管理员已被禁用",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("current_admin", adminUser)
+		c.Set("admin_id", adminUser.Id.String())
+		c.Set("admin_role", adminUser.Role)
+		cmn.AddLogFields(c, zap.String("adminId", adminUser.Id.String()))
+
+		c.Next()
+	}
+}
+
+// RequirePasswordChanged 须修改密码的管理员（如初始超级管理员）修改密码前拒绝访问
+// 该中间件需要在AuthMiddleware之后使用，修改密码、退出登录等接口不应使用
+func RequirePasswordChanged() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUser, ok := GetCurrentAdmin(c)
+		if !ok {
+			cmn.LoggerFrom(c).Error("failed to get current admin from context")
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 401,
+				Msg:    "管理员未登录或登录已过期",
+			})
+			c.Abort()
+			return
+		}
+
+		if adminUser.MustChangePassword {
+			cmn.LoggerFrom(c).Warn("admin must change password before access",
+				zap.String("admin_id", adminUser.Id.String()),
+				zap.String("path", c.FullPath()))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 403,
+				Msg:    "请先修改初始密码",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission 管理员权限校验中间件
+// 该中间件需要在AuthMiddleware之后使用
+func RequirePermission(perm Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUser, ok := GetCurrentAdmin(c)
+		if !ok {
+			cmn.LoggerFrom(c).Error("failed to get current admin from context")
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 401,
+				Msg:    "管理员未登录或登录已过期",
+			})
+			c.Abort()
+			return
+		}
+
+		if !HasPermission(adminUser.Role, perm) {
+			cmn.LoggerFrom(c).Warn("admin permission denied",
+				zap.String("admin_id", adminUser.Id.String()),
+				zap.String("role", adminUser.Role),
+				zap.String("permission", string(perm)),
+				zap.String("path", c.FullPath()))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 403,
+				Msg:    "权限不足",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GetCurrentAdmin 从上下文中获取当前登录管理员信息
+// 该函数需要在AuthMiddleware之后使用
+func GetCurrentAdmin(c *gin.Context) (*cmn.TAdminUser, bool) {
+	adminUser, exists := c.Get("current_admin")
+	if !exists {
+		return nil, false
+	}
+
+	currentAdmin, ok := adminUser.(cmn.TAdminUser)
+	if !ok {
+		return nil, false
+	}
+
+	return &currentAdmin, true
+}
+
+// GetCurrentAdminID 从上下文中获取当前登录管理员ID
+// 该函数需要在AuthMiddleware之后使用
+func GetCurrentAdminID(c *gin.Context) (uuid.UUID, bool) {
+	adminIdStr, exists := c.Get("admin_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+
+	adminId, ok := adminIdStr.(string)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(adminId)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,85 @@
+package admin
+
+// 管理员角色
+const (
+	RoleSuperAdmin = "superadmin" // 超级管理员，拥有全部权限
+	RoleOperator   = "operator"   // 运营人员，可管理奖品、抽奖配置与积分类型
+	RoleAuditor    = "auditor"    // 审计人员，只读
+)
+
+// Permission 管理接口权限
+type Permission string
+
+const (
+	PermPrizeRead           Permission = "prize:read"            // 查询奖品
+	PermPrizeWrite          Permission = "prize:write"           // 新增、修改、删除奖品
+	PermRaffleConfigRead    Permission = "raffle_config:read"    // 查询抽奖配置
+	PermRaffleConfigWrite   Permission = "raffle_config:write"   // 修改抽奖配置
+	PermDesignatedUserRead  Permission = "designated_user:read"  // 查询指定获奖用户
+	PermDesignatedUserWrite Permission = "designated_user:write" // 新增、删除指定获奖用户
+	PermUserRead            Permission = "user:read"             // 查询用户信息
+	PermPointsTypeRead      Permission = "points_type:read"      // 查询积分类型
+	PermPointsTypeWrite     Permission = "points_type:write"     // 新增、修改积分类型
+	PermJobRead             Permission = "job:read"              // 查询定时任务状态与运行记录
+	PermJobManage           Permission = "job:manage"            // 手动执行、暂停、恢复定时任务
+	PermAdminManage         Permission = "admin:manage"          // 管理管理员账号
+	PermLogLevelManage      Permission = "log_level:manage"      // 查询与调整运行时日志级别
+	PermSettingsRead        Permission = "settings:read"         // 查询运行时配置
+	PermSettingsWrite       Permission = "settings:write"        // 修改运行时配置
+	PermAuditLogRead        Permission = "audit_log:read"        // 查询与导出管理操作审计记录
+	PermSmsProviderRead     Permission = "sms_provider:read"     // 查询短信服务商健康状态
+)
+
+// rolePermissions 角色与权限的对应关系
+var rolePermissions = map[string][]Permission{
+	RoleSuperAdmin: {
+		PermPrizeRead, PermPrizeWrite,
+		PermRaffleConfigRead, PermRaffleConfigWrite,
+		PermDesignatedUserRead, PermDesignatedUserWrite,
+		PermUserRead,
+		PermPointsTypeRead, PermPointsTypeWrite,
+		PermJobRead, PermJobManage,
+		PermAdminManage,
+		PermLogLevelManage,
+		PermSettingsRead, PermSettingsWrite,
+		PermAuditLogRead,
+		PermSmsProviderRead,
+	},
+	RoleOperator: {
+		PermPrizeRead, PermPrizeWrite,
+		PermRaffleConfigRead, PermRaffleConfigWrite,
+		PermDesignatedUserRead,
+		PermUserRead,
+		PermPointsTypeRead, PermPointsTypeWrite,
+		PermJobRead,
+		PermSettingsRead,
+		PermSmsProviderRead,
+	},
+	RoleAuditor: {
+		PermPrizeRead,
+		PermRaffleConfigRead,
+		PermDesignatedUserRead,
+		PermUserRead,
+		PermPointsTypeRead,
+		PermJobRead,
+		PermSettingsRead,
+		PermAuditLogRead,
+		PermSmsProviderRead,
+	},
+}
+
+// IsValidRole 检查角色是否合法
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission 检查角色是否拥有指定权限
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
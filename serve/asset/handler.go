@@ -30,8 +30,8 @@ func (h *handler) HandleQueryMyAsset(c *gin.Context) {
 	// 获取当前用户ID
 	userId, ok := user.GetCurrentUserID(c)
 	if !ok {
-		z.Error("failed to get current user ID")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to get current user ID")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 401,
 			Msg:    "未登录或登录已过期",
 		})
@@ -65,8 +65,8 @@ func (h *handler) HandleQueryMyAsset(c *gin.Context) {
 	var totalCount int64 = 0
 	err = cmn.GormDB.Model(&cmn.VUserAssetMeta{}).Where("user_id = ?", userId).Count(&totalCount).Error
 	if err != nil {
-		z.Error("failed to count user assets", zap.Error(err), zap.String("user_id", userId.String()))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to count user assets", zap.Error(err), zap.String("user_id", userId.String()))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询资产总数失败",
 		})
@@ -81,8 +81,8 @@ func (h *handler) HandleQueryMyAsset(c *gin.Context) {
 		Offset(offset).
 		Find(&userAssets).Error
 	if err != nil {
-		z.Error("failed to query user assets", zap.Error(err), zap.String("user_id", userId.String()))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to query user assets", zap.Error(err), zap.String("user_id", userId.String()))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询用户资产失败",
 		})
@@ -96,8 +96,8 @@ func (h *handler) HandleQueryMyAsset(c *gin.Context) {
 		Select("COALESCE(MAX(created_at), 0)").
 		Scan(&latestCreatedAt).Error
 	if err != nil {
-		z.Error("failed to query latest created_at", zap.Error(err), zap.String("user_id", userId.String()))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to query latest created_at", zap.Error(err), zap.String("user_id", userId.String()))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询最新创建时间失败",
 		})
@@ -113,8 +113,8 @@ func (h *handler) HandleQueryMyAsset(c *gin.Context) {
 	// 序列化响应数据
 	responseJson, err := json.Marshal(responseData)
 	if err != nil {
-		z.Error("failed to marshal response data", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to marshal response data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "响应数据序列化失败",
 		})
@@ -122,7 +122,7 @@ func (h *handler) HandleQueryMyAsset(c *gin.Context) {
 	}
 
 	// 返回成功响应
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status:   0,
 		Msg:      "查询资产成功",
 		Data:     responseJson,
@@ -159,8 +159,8 @@ func (h *handler) HandleQueryMetaAssets(c *gin.Context) {
 	var totalCount int64 = 0
 	err = cmn.GormDB.Model(&cmn.TMetaAsset{}).Count(&totalCount).Error
 	if err != nil {
-		z.Error("failed to count meta assets", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to count meta assets", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询元资产总数失败",
 		})
@@ -174,8 +174,8 @@ func (h *handler) HandleQueryMetaAssets(c *gin.Context) {
 		Offset(offset).
 		Find(&metaAssets).Error
 	if err != nil {
-		z.Error("failed to query meta assets", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to query meta assets", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询元资产失败",
 		})
@@ -185,8 +185,8 @@ func (h *handler) HandleQueryMetaAssets(c *gin.Context) {
 	// 序列化响应数据
 	responseJson, err := json.Marshal(metaAssets)
 	if err != nil {
-		z.Error("failed to marshal meta assets", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to marshal meta assets", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "响应数据序列化失败",
 		})
@@ -194,7 +194,7 @@ func (h *handler) HandleQueryMetaAssets(c *gin.Context) {
 	}
 
 	// 返回成功响应
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status:   0,
 		Msg:      "查询元资产成功",
 		Data:     responseJson,
@@ -207,7 +207,7 @@ func (h *handler) HandleQueryUserAssetsByPhone(c *gin.Context) {
 	// 获取手机号参数
 	mobilePhone := c.Query("mobilePhone")
 	if mobilePhone == "" {
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "手机号参数不能为空",
 		})
@@ -241,8 +241,8 @@ func (h *handler) HandleQueryUserAssetsByPhone(c *gin.Context) {
 	var totalCount int64 = 0
 	err = cmn.GormDB.Model(&cmn.VUserAssetMeta{}).Where("mobile_phone = ?", mobilePhone).Count(&totalCount).Error
 	if err != nil {
-		z.Error("failed to count user assets by phone", zap.Error(err), zap.String("mobile_phone", mobilePhone))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to count user assets by phone", zap.Error(err), zap.String("mobile_phone", mobilePhone))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询用户资产总数失败",
 		})
@@ -257,8 +257,8 @@ func (h *handler) HandleQueryUserAssetsByPhone(c *gin.Context) {
 		Offset(offset).
 		Find(&userAssets).Error
 	if err != nil {
-		z.Error("failed to query user assets by phone", zap.Error(err), zap.String("mobile_phone", mobilePhone))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to query user assets by phone", zap.Error(err), zap.String("mobile_phone", mobilePhone))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询用户资产失败",
 		})
@@ -272,8 +272,8 @@ func (h *handler) HandleQueryUserAssetsByPhone(c *gin.Context) {
 		Select("COALESCE(MAX(created_at), 0)").
 		Scan(&latestCreatedAt).Error
 	if err != nil {
-		z.Error("failed to query latest created_at by phone", zap.Error(err), zap.String("mobile_phone", mobilePhone))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to query latest created_at by phone", zap.Error(err), zap.String("mobile_phone", mobilePhone))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询最新创建时间失败",
 		})
@@ -289,8 +289,8 @@ func (h *handler) HandleQueryUserAssetsByPhone(c *gin.Context) {
 	// 序列化响应数据
 	responseJson, err := json.Marshal(responseData)
 	if err != nil {
-		z.Error("failed to marshal response data", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to marshal response data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "响应数据序列化失败",
 		})
@@ -298,7 +298,7 @@ func (h *handler) HandleQueryUserAssetsByPhone(c *gin.Context) {
 	}
 
 	// 返回成功响应
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status:   0,
 		Msg:      "查询用户资产成功",
 		Data:     responseJson,
@@ -0,0 +1,24 @@
+package health
+
+import (
+	"WudangMeta/cmn"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	dbPingTimeout = 2 * time.Second // 数据库连通性检查超时时间
+)
+
+var z *zap.Logger
+
+var appCtx context.Context // 服务生命周期上下文，取消后就绪检查返回未就绪，使负载均衡停止转发新请求
+
+func Init(ctx context.Context) {
+	z = cmn.GetLogger()
+	appCtx = ctx
+
+	cmn.MiniLogger.Info("[ OK ] health module initialized")
+}
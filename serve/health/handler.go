@@ -0,0 +1,62 @@
+package health
+
+import (
+	"WudangMeta/cmn"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Handler interface {
+	HandleHealthz(c *gin.Context)
+	HandleReadyz(c *gin.Context)
+}
+
+type handler struct {
+}
+
+func NewHandler() Handler {
+	return &handler{}
+}
+
+// HandleHealthz 存活检查，进程能够处理请求即返回成功
+func (h *handler) HandleHealthz(c *gin.Context) {
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "ok",
+	})
+}
+
+// HandleReadyz 就绪检查，返回各组件的检查结果
+// 供编排系统使用，未就绪时返回 HTTP 503
+func (h *handler) HandleReadyz(c *gin.Context) {
+	report := CheckReadiness(c)
+
+	reportJson, err := json.Marshal(report)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal readiness report", zap.Error(err))
+		cmn.Reply(c, http.StatusInternalServerError, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
+		})
+		return
+	}
+
+	if !report.Ready {
+		cmn.LoggerFrom(c).Warn("readiness check failed", zap.Any("components", report.Components))
+		cmn.Reply(c, http.StatusServiceUnavailable, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "not ready",
+			Data:   reportJson,
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "ready",
+		Data:   reportJson,
+	})
+}
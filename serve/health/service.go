@@ -0,0 +1,176 @@
+package health
+
+import (
+	"WudangMeta/cmn"
+	"WudangMeta/cmn/llm"
+	"WudangMeta/cmn/sms"
+	"WudangMeta/cmn/ubanquan_core"
+	"WudangMeta/serve/raffle"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 组件状态
+const (
+	StatusUp       = "up"       // 正常
+	StatusDown     = "down"     // 不可用，服务未就绪
+	StatusDegraded = "degraded" // 可用但功能受限，不影响就绪
+	StatusDisabled = "disabled" // 未启用
+)
+
+// ComponentStatus 单个组件的检查结果
+type ComponentStatus struct {
+	Name    string `json:"name"`              // 组件名称
+	Status  string `json:"status"`            // 组件状态
+	Message string `json:"message,omitempty"` // 状态说明
+	Latency int64  `json:"latency"`           // 检查耗时（毫秒）
+}
+
+// ReadinessReport 就绪检查报告
+type ReadinessReport struct {
+	Ready      bool              `json:"ready"`      // 是否就绪
+	Components []ComponentStatus `json:"components"` // 各组件检查结果
+	CheckedAt  int64             `json:"checkedAt"`  // 检查时间
+}
+
+// checker 组件检查函数
+type checker struct {
+	name  string
+	check func(ctx context.Context) (string, string)
+}
+
+var checkers = []checker{
+	{name: "lifecycle", check: checkLifecycle},
+	{name: "postgres", check: checkPostgres},
+	{name: "ubanquan_token", check: checkUbanquanToken},
+	{name: "raffle_prize_pool", check: checkRafflePrizePool},
+	{name: "sms", check: checkSms},
+	{name: "llm", check: checkLlm},
+}
+
+// CheckReadiness 依次检查所有组件，任一组件不可用时服务未就绪
+func CheckReadiness(ctx context.Context) ReadinessReport {
+	report := ReadinessReport{
+		Ready:      true,
+		Components: make([]ComponentStatus, 0, len(checkers)),
+		CheckedAt:  time.Now().UnixMilli(),
+	}
+
+	for _, c := range checkers {
+		start := time.Now()
+		status, message := c.check(ctx)
+		report.Components = append(report.Components, ComponentStatus{
+			Name:    c.name,
+			Status:  status,
+			Message: message,
+			Latency: time.Since(start).Milliseconds(),
+		})
+		if status == StatusDown {
+			report.Ready = false
+		}
+	}
+
+	return report
+}
+
+// checkLifecycle 服务收到关闭信号后不再就绪
+func checkLifecycle(ctx context.Context) (string, string) {
+	if appCtx != nil && appCtx.Err() != nil {
+		return StatusDown, "服务正在关闭"
+	}
+	return StatusUp, ""
+}
+
+// checkPostgres 检查数据库连接池是否可用
+// 就绪检查接口对外公开，驱动错误与连接池状态只写入日志
+func checkPostgres(ctx context.Context) (string, string) {
+	if cmn.GormDB == nil {
+		return StatusDown, "数据库未初始化"
+	}
+	sqlDB, err := cmn.GormDB.DB()
+	if err != nil {
+		cmn.LoggerFrom(ctx).Error("failed to get sql.DB for readiness check", zap.Error(err))
+		return StatusDown, "数据库不可用"
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+	defer cancel()
+	err = sqlDB.PingContext(pingCtx)
+	if err != nil {
+		cmn.LoggerFrom(ctx).Error("failed to ping database for readiness check", zap.Error(err))
+		return StatusDown, "数据库不可用"
+	}
+
+	stats := sqlDB.Stats()
+	cmn.LoggerFrom(ctx).Debug("database pool stats",
+		zap.Int("open", stats.OpenConnections),
+		zap.Int("inUse", stats.InUse),
+		zap.Int("idle", stats.Idle))
+	return StatusUp, ""
+}
+
+// checkUbanquanToken 检查优版权全局token是否有效
+// token只影响依赖优版权的接口，不可用时功能受限但不影响就绪，避免第三方故障使所有实例退出负载均衡
+func checkUbanquanToken(ctx context.Context) (string, string) {
+	valid, expiresAt := ubanquan_core.TokenValid()
+	if expiresAt.IsZero() {
+		return StatusDegraded, "token未获取"
+	}
+	if !valid {
+		return StatusDegraded, "token已过期: " + expiresAt.Format(time.RFC3339)
+	}
+	return StatusUp, "token有效期至: " + expiresAt.Format(time.RFC3339)
+}
+
+// checkRafflePrizePool 检查抽奖机是否已加载奖池，奖池为空时仍可正常服务其他接口
+func checkRafflePrizePool(ctx context.Context) (string, string) {
+	loaded, available := raffle.PrizePoolStatus()
+	if !loaded {
+		return StatusDown, "奖池未加载"
+	}
+	if available == 0 {
+		return StatusDegraded, "奖池中没有可抽取的奖品"
+	}
+	return StatusUp, fmt.Sprintf("可抽取奖品数: %d", available)
+}
+
+// checkSms 检查短信服务商状态，全部服务商熔断时功能受限但不影响就绪
+func checkSms(ctx context.Context) (string, string) {
+	if !cmn.GetConfig().Sms.Enable {
+		return StatusDisabled, ""
+	}
+	providers := sms.Health()
+	if len(providers) == 0 {
+		return StatusDown, "短信服务未初始化"
+	}
+
+	states := make([]string, 0, len(providers))
+	open := 0
+	for _, p := range providers {
+		states = append(states, p.Name+": "+p.State)
+		if p.State == sms.BreakerOpen {
+			open++
+		}
+	}
+	message := "providers: " + strings.Join(states, ", ")
+	if open == len(providers) {
+		return StatusDegraded, message
+	}
+	return StatusUp, message
+}
+
+// checkLlm 检查大模型服务是否已配置
+func checkLlm(ctx context.Context) (string, string) {
+	enabled, platform := llm.Configured()
+	if !enabled {
+		return StatusDisabled, ""
+	}
+	if platform == "" {
+		return StatusDown, "大模型服务未初始化"
+	}
+	return StatusUp, "platform: " + platform
+}
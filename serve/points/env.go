@@ -2,6 +2,8 @@ package points
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/points_core"
+	"WudangMeta/cmn/scheduler"
 	"context"
 	"sync"
 
@@ -12,13 +14,47 @@ var z *zap.Logger
 
 var once sync.Once
 
-func Init() {
-	z = cmn.GetLogger()
+var appCtx context.Context // 服务生命周期上下文，服务关闭时取消
+
+const (
+	upcomingExpirationDays = 30   // 查询我的积分时展示未来多少天内即将过期的积分
+	maxPointsExpireDays    = 3650 // 积分类型允许设置的最长有效天数
+)
 
-	ctx := context.Background()
+func Init(ctx context.Context) {
+	z = cmn.GetLogger()
+	appCtx = ctx
 
 	once.Do(func() {
-		go userAssetPointsMaintainer(ctx, cmn.GormDB)
+		err := scheduler.Register(scheduler.Job{
+			Name:      jobAssetAccrual,
+			Spec:      "0 0 * * *",
+			Enable:    true,
+			Singleton: true,
+			Fn:        userAssetPointsMaintainer(cmn.GormDB),
+		})
+		if err != nil {
+			z.Fatal("[ FAIL ] failed to register asset accrual job", zap.Error(err))
+		}
+
+		err = scheduler.Register(scheduler.Job{
+			Name:      jobPointsExpiry,
+			Spec:      "0 0 * * *",
+			Enable:    true,
+			Singleton: true,
+			Fn:        pointsExpirySettler(cmn.GormDB),
+		})
+		if err != nil {
+			z.Fatal("[ FAIL ] failed to register points expiry job", zap.Error(err))
+		}
+
+		// 恢复因重启或崩溃而中断的累加任务
+		cmn.GoBackground("resume-asset-accrual", func() {
+			errs := points_core.ResumeUnfinishedAssetAccrual(ctx, cmn.GormDB)
+			if len(errs) > 0 {
+				z.Error("failed to resume unfinished asset accrual", zap.Any("errors", errs))
+			}
+		})
 	})
 
 	cmn.MiniLogger.Info("[ OK ] points module initialized")
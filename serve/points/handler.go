@@ -2,10 +2,14 @@ package points
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/points_core"
+	"WudangMeta/serve/admin"
 	"WudangMeta/serve/user"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -14,6 +18,12 @@ import (
 
 type Handler interface {
 	HandleQueryMyPoints(c *gin.Context)
+	HandleQueryMyPointsHistory(c *gin.Context)
+	HandleQueryPointsTypes(c *gin.Context)
+	HandleCreatePointsType(c *gin.Context)
+	HandleUpdatePointsType(c *gin.Context)
+	HandleQueryAssetAccrualRuns(c *gin.Context)
+	HandleRerunAssetAccrual(c *gin.Context)
 }
 
 type handler struct {
@@ -28,51 +38,480 @@ func (h *handler) HandleQueryMyPoints(c *gin.Context) {
 	// 获取当前用户ID
 	userId, ok := user.GetCurrentUserID(c)
 	if !ok {
-		z.Error("failed to get current user ID")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to get current user ID")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 401,
 			Msg:    "未登录或登录已过期",
 		})
 		return
 	}
 
-	// 查询用户积分
-	var userPoints cmn.TUserPoints
-	err := cmn.GormDB.Where("user_id = ?", userId).First(&userPoints).Error
+	// 查询用户各类型积分
+	balances, err := points_core.QueryUserBalances(c, userId)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			z.Info("user points not found", zap.String("user_id", userId.String()))
-			c.JSON(http.StatusOK, cmn.ReplyProto{
-				Status: 1,
-				Msg:    "未找到用户积分记录",
-			})
-			return
-		}
-		z.Error("failed to query user points", zap.Error(err), zap.String("user_id", userId.String()))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询用户积分失败",
 		})
 		return
 	}
 
+	// points 字段保持为默认积分，兼容旧版客户端
+	var defaultPoints *float64
+	for i := range balances {
+		if balances[i].PointsType == points_core.PointsTypeDefault {
+			defaultPoints = &balances[i].Balance
+			break
+		}
+	}
+	if defaultPoints == nil {
+		cmn.LoggerFrom(c).Info("user points not found", zap.String("user_id", userId.String()))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "未找到用户积分记录",
+		})
+		return
+	}
+
+	// 查询即将过期的积分
+	upcomingExpirations, err := points_core.QueryUserUpcomingExpirations(c, userId, upcomingExpirationDays)
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询即将过期积分失败",
+		})
+		return
+	}
+
 	responseData := map[string]interface{}{
-		"points": userPoints.DefaultPoints,
+		"points":              *defaultPoints,
+		"balances":            balances,
+		"upcomingExpirations": upcomingExpirations,
 	}
 
 	responseJson, err := json.Marshal(responseData)
 	if err != nil {
-		z.Error("failed to marshal response data", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to marshal response data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "响应数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "success",
 		Data:   responseJson,
 	})
 }
+
+// HandleQueryMyPointsHistory 分页查询当前用户积分流水
+func (h *handler) HandleQueryMyPointsHistory(c *gin.Context) {
+	userId, ok := user.GetCurrentUserID(c)
+	if !ok {
+		cmn.LoggerFrom(c).Error("failed to get current user ID")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 401,
+			Msg:    "未登录或登录已过期",
+		})
+		return
+	}
+
+	// 获取分页参数
+	pageStr := c.Query("page")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	sizeStr := c.Query("pageSize")
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size < 1 {
+		size = 10
+	}
+
+	// 限制每页最大数量
+	if size > 100 {
+		size = 100
+	}
+
+	// 获取积分类型与变动原因过滤参数（可选）
+	pointsType := c.Query("pointsType")
+	reason := c.Query("reason")
+
+	records, total, err := points_core.QueryUserPointsLedger(c, userId, pointsType, reason, page, size)
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询积分流水失败",
+		})
+		return
+	}
+
+	recordsJson, err := json.Marshal(records)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal points ledger", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "响应数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status:   0,
+		Msg:      "success",
+		Data:     recordsJson,
+		RowCount: total,
+	})
+}
+
+// HandleQueryPointsTypes 查询所有积分类型
+func (h *handler) HandleQueryPointsTypes(c *gin.Context) {
+	pointsTypes, err := points_core.QueryPointsTypes(c)
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询积分类型失败",
+		})
+		return
+	}
+
+	pointsTypesJson, err := json.Marshal(pointsTypes)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal points types", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "响应数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status:   0,
+		Msg:      "success",
+		Data:     pointsTypesJson,
+		RowCount: int64(len(pointsTypes)),
+	})
+}
+
+// HandleCreatePointsType 新增积分类型
+func (h *handler) HandleCreatePointsType(c *gin.Context) {
+	var req cmn.ReqProto
+	if err := c.ShouldBindJSON(&req); err != nil {
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
+		})
+		return
+	}
+
+	var d struct {
+		Code        string `json:"code"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		ExpireDays  int    `json:"expireDays"`
+	}
+	if err := json.Unmarshal(req.Data, &d); err != nil {
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
+		})
+		return
+	}
+
+	if !points_core.IsValidPointsTypeCode(d.Code) {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "积分类型编码只能包含小写字母、数字和下划线，以字母开头且不超过30个字符",
+		})
+		return
+	}
+
+	if d.Name == "" {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "积分类型名称不能为空",
+		})
+		return
+	}
+
+	if d.ExpireDays < 0 || d.ExpireDays > maxPointsExpireDays {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    fmt.Sprintf("积分有效天数必须在0到%d之间，0表示永不过期", maxPointsExpireDays),
+		})
+		return
+	}
+
+	// 检查编码是否已存在
+	var existing cmn.TPointsType
+	if err := cmn.GormDB.Where("code = ?", d.Code).First(&existing).Error; err == nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "积分类型编码已存在",
+		})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		cmn.LoggerFrom(c).Error("failed to check existing points type", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "检查积分类型编码失败",
+		})
+		return
+	}
+
+	pointsType := cmn.TPointsType{
+		Code:        d.Code,
+		Name:        d.Name,
+		Description: d.Description,
+		ExpireDays:  d.ExpireDays,
+		Status:      "00",
+	}
+	if err := cmn.GormDB.Create(&pointsType).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to create points type", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "创建积分类型失败",
+		})
+		return
+	}
+
+	admin.SetAuditChange(c, admin.AuditTarget("points_type", pointsType.Code), nil, pointsType)
+
+	pointsTypeJson, err := json.Marshal(pointsType)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal points type", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "积分类型创建成功",
+		Data:   pointsTypeJson,
+	})
+}
+
+// HandleUpdatePointsType 修改积分类型名称、描述、有效天数或状态
+// 积分类型编码创建后不可修改，有效天数的修改只影响之后获得的积分
+func (h *handler) HandleUpdatePointsType(c *gin.Context) {
+	pointsTypeId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "积分类型ID格式无效",
+		})
+		return
+	}
+
+	var req cmn.ReqProto
+	if err = c.ShouldBindJSON(&req); err != nil {
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
+		})
+		return
+	}
+
+	var d struct {
+		Name        string  `json:"name"`
+		Description *string `json:"description"`
+		ExpireDays  *int    `json:"expireDays"`
+		Status      string  `json:"status"`
+	}
+	if err = json.Unmarshal(req.Data, &d); err != nil {
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
+		})
+		return
+	}
+
+	var pointsType cmn.TPointsType
+	if err = cmn.GormDB.Where("id = ?", pointsTypeId).First(&pointsType).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "积分类型不存在",
+			})
+			return
+		}
+		cmn.LoggerFrom(c).Error("failed to query points type", zap.Error(err), zap.Int64("id", pointsTypeId))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询积分类型失败",
+		})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if d.Name != "" {
+		updates["name"] = d.Name
+	}
+	if d.Description != nil {
+		updates["description"] = *d.Description
+	}
+	if d.ExpireDays != nil {
+		if *d.ExpireDays < 0 || *d.ExpireDays > maxPointsExpireDays {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    fmt.Sprintf("积分有效天数必须在0到%d之间，0表示永不过期", maxPointsExpireDays),
+			})
+			return
+		}
+		updates["expire_days"] = *d.ExpireDays
+	}
+	if d.Status != "" {
+		if d.Status != "00" && d.Status != "01" {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "状态无效",
+			})
+			return
+		}
+		// 默认积分被签到、资产等流程依赖，不允许停用
+		if pointsType.Code == points_core.PointsTypeDefault && d.Status != "00" {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "默认积分类型不可停用",
+			})
+			return
+		}
+		updates["status"] = d.Status
+	}
+
+	if len(updates) == 0 {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "没有需要更新的字段",
+		})
+		return
+	}
+
+	before := pointsType
+	if err = cmn.GormDB.Model(&pointsType).Updates(updates).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to update points type", zap.Error(err), zap.Int64("id", pointsTypeId))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "更新积分类型失败",
+		})
+		return
+	}
+
+	var after cmn.TPointsType
+	if err = cmn.GormDB.Where("id = ?", pointsTypeId).First(&after).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to query updated points type", zap.Error(err), zap.Int64("id", pointsTypeId))
+	}
+	admin.SetAuditChange(c, admin.AuditTarget("points_type", before.Code), before, after)
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "积分类型更新成功",
+	})
+}
+
+// HandleQueryAssetAccrualRuns 分页查询每日资产积分累加的运行记录
+func (h *handler) HandleQueryAssetAccrualRuns(c *gin.Context) {
+	// 获取分页参数
+	pageStr := c.Query("page")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	sizeStr := c.Query("pageSize")
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size < 1 {
+		size = 10
+	}
+
+	// 限制每页最大数量
+	if size > 100 {
+		size = 100
+	}
+
+	jobRuns, total, err := points_core.QueryJobRuns(c, points_core.JobAssetAccrual, page, size)
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询任务运行记录失败",
+		})
+		return
+	}
+
+	jobRunsJson, err := json.Marshal(jobRuns)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to marshal job runs", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "响应数据序列化失败",
+		})
+		return
+	}
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status:   0,
+		Msg:      "success",
+		Data:     jobRunsJson,
+		RowCount: total,
+	})
+}
+
+// HandleRerunAssetAccrual 重新执行指定业务日期的资产积分累加
+// 已累加过的用户会被跳过，仅允许补跑近期失败或中断的业务日期
+func (h *handler) HandleRerunAssetAccrual(c *gin.Context) {
+	bizDate := c.Param("bizDate")
+	if _, err := points_core.ParseBizDate(bizDate); err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "业务日期格式无效或晚于今天，格式为yyyy-mm-dd",
+		})
+		return
+	}
+
+	err := points_core.CheckAssetAccrualRerunnable(c, cmn.GormDB, bizDate)
+	if err != nil {
+		reply := cmn.ReplyProto{Status: 1}
+		switch {
+		case errors.Is(err, points_core.ErrJobRunTooOld):
+			reply.Msg = "业务日期超出允许补跑的范围"
+		case errors.Is(err, points_core.ErrJobRunNotFound):
+			reply.Msg = "该日期没有资产积分累加运行记录，不能补跑"
+		case errors.Is(err, points_core.ErrJobRunSucceeded):
+			reply.Msg = "该日期的资产积分累加已全部完成，无需补跑"
+		case errors.Is(err, points_core.ErrJobRunInProgress):
+			reply.Msg = "该日期的资产积分累加正在运行中"
+		default:
+			reply.Status, reply.Msg = -1, "查询任务运行记录失败"
+		}
+		cmn.Reply(c, http.StatusOK, reply)
+		return
+	}
+
+	// 累加耗时较长，后台执行，结果通过运行记录查询
+	// 服务关闭时任务随 appCtx 取消，未处理的用户可再次提交补跑
+	// 请求结束后 c 会被复用，需先取出请求日志
+	reqLogger := cmn.LoggerFrom(c)
+	cmn.GoBackground("rerun-asset-accrual", func() {
+		_, err := points_core.RunDailyAssetAccrual(appCtx, cmn.GormDB, bizDate)
+		if err != nil {
+			reqLogger.Error("failed to rerun asset accrual", zap.Error(err), zap.String("bizDate", bizDate))
+		}
+	})
+
+	admin.SetAuditChange(c, admin.AuditTarget("accrual", bizDate), nil, nil)
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "资产积分累加任务已提交",
+	})
+}
@@ -0,0 +1,31 @@
+package points
+
+import (
+	"WudangMeta/cmn"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandleRerunAssetAccrualRejectsBizDate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/points/accrual/runs/:bizDate", NewHandler().HandleRerunAssetAccrual)
+
+	// 均在查询数据库前被拒绝，不会提交补跑任务
+	for _, bizDate := range []string{"2020-01-01", "2999-01-01", "20200101"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/points/accrual/runs/"+bizDate, nil))
+
+		var reply cmn.ReplyProto
+		if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil {
+			t.Fatalf("bizDate %s: unmarshal reply failed: %v", bizDate, err)
+		}
+		if reply.Status != 1 {
+			t.Errorf("bizDate %s: status = %d, want 1, msg: %s", bizDate, reply.Status, reply.Msg)
+		}
+	}
+}
@@ -1,47 +1,56 @@
 package points
 
 import (
-	"WudangMeta/cmn"
 	"WudangMeta/cmn/points_core"
 	"WudangMeta/cmn/ubanquan_core"
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-func userAssetPointsMaintainer(ctx context.Context, db *gorm.DB) {
-	for {
-		// 计算距离下一次 00:00 的时间
-		duration, err := cmn.GetDurationUntilNextTargetTime(0, 0, 0, "Asia/Shanghai")
+// 定时任务名称
+const (
+	jobAssetAccrual = "asset_accrual" // 每日资产积分累加与优版权资产同步
+	jobPointsExpiry = "points_expiry" // 每日积分过期结算
+)
+
+// userAssetPointsMaintainer 每天 00:00 根据资产累加用户积分，并同步用户优版权资产
+func userAssetPointsMaintainer(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var errs []error
+
+		// 计算现有资产价值并累加到用户积分，同一业务日期重复执行不会重复累加
+		bizDate := points_core.BizDateOf(time.Now())
+		_, err := points_core.RunDailyAssetAccrual(ctx, db, bizDate)
+		if err != nil && !errors.Is(err, points_core.ErrJobRunInProgress) {
+			z.Error("failed to add all user points from assets", zap.Error(err), zap.String("bizDate", bizDate))
+			errs = append(errs, err)
+		}
+
+		// 更新用户优版权资产
+		_, err = ubanquan_core.UpdateAllUsersAssets(ctx)
 		if err != nil {
-			z.Error("failed to get duration until next target time", zap.Error(err))
-			return
+			z.Error("failed to update all users' ubanquan assets", zap.Error(err))
+			errs = append(errs, fmt.Errorf("failed to update all users' ubanquan assets: %w", err))
 		}
-		z.Info("userAssetPointsMaintainer sleep until next target time", zap.Duration("duration", duration))
-
-		timer := time.NewTimer(duration)
-
-		select {
-		case <-ctx.Done():
-			z.Info("userAssetPointsMaintainer stopped")
-			timer.Stop()
-			return
-		case <-timer.C:
-			// 每天 00:00 更新一次用户积分
-			go func() {
-				// 计算现有资产价值并累加到用户积分
-				errs := points_core.AddAllUserPointsFromAssets(ctx, db)
-				if len(errs) > 0 {
-					z.Error("failed to add all user points from assets", zap.Any("errors", errs))
-				}
-				// 更新用户优版权资产
-				_, err = ubanquan_core.UpdateAllUsersAssets(ctx)
-				if err != nil {
-					z.Error("failed to update all users' ubanquan assets", zap.Error(err))
-				}
-			}()
+
+		return errors.Join(errs...)
+	}
+}
+
+// pointsExpirySettler 每天 00:00 将到期的积分批次清零并写入过期流水
+func pointsExpirySettler(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		expiredCount, errs := points_core.ExpireDuePointsLots(ctx, db)
+		if len(errs) > 0 {
+			z.Error("failed to expire some points lots", zap.Int("expired", expiredCount), zap.Any("errors", errs))
+			return errors.Join(errs...)
 		}
+		z.Info("expired due points lots", zap.Int("expired", expiredCount))
+		return nil
 	}
 }
@@ -2,7 +2,10 @@ package raffle
 
 import (
 	"WudangMeta/cmn"
-	"strconv"
+	"WudangMeta/cmn/points_core"
+	"WudangMeta/cmn/settings"
+	"context"
+	"fmt"
 	"sync"
 
 	"go.uber.org/zap"
@@ -11,7 +14,9 @@ import (
 const (
 	noPrizeSign = "未中奖"
 
-	cfgKeyConsumePointsKey   = "raffle.consumePointsKey"   // 抽奖消耗积分键的配置键
+	legacyDefaultPointsKey = "default_points" // 旧版本以积分表列名作为消耗积分键，等同于默认积分类型
+
+	cfgKeyConsumePointsKey   = "raffle.consumePointsKey"   // 抽奖消耗积分类型的配置键
 	cfgKeyConsumePointsValue = "raffle.consumePointsValue" // 抽奖消耗积分值的配置键
 )
 
@@ -21,39 +26,82 @@ var (
 	z       *zap.Logger
 )
 
-func Init() {
+func Init(ctx context.Context) {
 	z = cmn.GetLogger()
 
-	// 从配置表读取抽奖消耗积分键
-	pointsKey, err := cmn.GetConfigFromDB(cfgKeyConsumePointsKey, "default_points")
+	err := settings.Register(ctx,
+		settings.Definition{
+			Key:         cfgKeyConsumePointsKey,
+			Kind:        settings.KindString,
+			Default:     points_core.PointsTypeDefault,
+			Description: "抽奖消耗的积分类型编码",
+			Validate: func(ctx context.Context, value any) error {
+				return points_core.ValidatePointsType(ctx, nil, normalizePointsType(value.(string)))
+			},
+		},
+		settings.Definition{
+			Key:         cfgKeyConsumePointsValue,
+			Kind:        settings.KindInt,
+			Default:     "100",
+			Description: "每次抽奖消耗的积分",
+			Validate: func(ctx context.Context, value any) error {
+				if value.(int64) < 0 {
+					return fmt.Errorf("consume points %d < 0", value.(int64))
+				}
+				return nil
+			},
+		},
+	)
 	if err != nil {
-		z.Fatal("[ FAIL ] failed to get consume points key from config table", zap.Error(err))
-	}
-	if pointsKey == "" {
-		z.Warn("[ WARN ] raffle consume points key is empty, using default 'default_points'")
-		pointsKey = "default_points"
-	}
-
-	// 从配置表读取抽奖消耗积分值
-	consumePointsValueStr, err := cmn.GetConfigFromDB(cfgKeyConsumePointsValue, "100")
-	if err != nil {
-		z.Fatal("[ FAIL ] failed to get consume points from config table", zap.Error(err))
-	}
-	pointsValue, err := strconv.ParseInt(consumePointsValueStr, 10, 64)
-	if err != nil {
-		z.Fatal("[ FAIL ] invalid consume points value in config table", zap.String("value", consumePointsValueStr), zap.Error(err))
-	}
-	if pointsValue < 0 {
-		z.Fatal("[ FAIL ] raffle consume points must be greater than or equal to 0")
+		z.Fatal("[ FAIL ] failed to register raffle settings", zap.Error(err))
 	}
 
+	pointsType, pointsValue := consumePoints()
 	once.Do(func() {
 		var err error
-		machine, err = NewMachine(pointsKey, pointsValue)
+		machine, err = NewMachine(pointsType, pointsValue)
 		if err != nil {
 			z.Fatal("[ FAIL ] failed to create raffle machine", zap.Error(err))
 		}
 	})
 
-	cmn.MiniLogger.Info("[ OK ] raffle module initialized", zap.String("consumePointsKey", machine.consumePointsKey), zap.Int64("consumePointsValue", pointsValue))
+	// 配置变更（包括其他实例修改）后重置抽奖机消耗积分
+	settings.Subscribe([]string{cfgKeyConsumePointsKey, cfgKeyConsumePointsValue}, func(ctx context.Context) {
+		pointsType, pointsValue := consumePoints()
+		err := machine.resetConsumePoints(pointsType, pointsValue)
+		if err != nil {
+			z.Error("failed to reset consume points", zap.String("consumePointsType", pointsType), zap.Int64("consumePointsValue", pointsValue), zap.Error(err))
+		}
+	})
+
+	cmn.MiniLogger.Info("[ OK ] raffle module initialized", zap.String("consumePointsType", machine.atomicConsumePoints.Load().pointsType), zap.Int64("consumePointsValue", pointsValue))
+}
+
+// consumePoints 返回配置的抽奖消耗积分类型与积分值
+func consumePoints() (string, int64) {
+	pointsType := normalizePointsType(settings.String(cfgKeyConsumePointsKey))
+	if pointsType == "" {
+		pointsType = points_core.PointsTypeDefault
+	}
+	return pointsType, settings.Int(cfgKeyConsumePointsValue)
+}
+
+// normalizePointsType 将旧版本的积分列名转换为积分类型编码
+func normalizePointsType(pointsType string) string {
+	if pointsType == legacyDefaultPointsKey {
+		return points_core.PointsTypeDefault
+	}
+	return pointsType
+}
+
+// PrizePoolStatus 返回抽奖机是否已加载奖池以及奖池中可抽取的奖品数
+func PrizePoolStatus() (bool, int) {
+	if machine == nil {
+		return false, 0
+	}
+	prizes, ok := machine.atomicPrizes.Load().([]cmn.TRafflePrize)
+	if !ok {
+		return false, 0
+	}
+	return true, len(prizes)
 }
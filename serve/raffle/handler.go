@@ -2,6 +2,8 @@ package raffle
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/settings"
+	"WudangMeta/serve/admin"
 	"WudangMeta/serve/user"
 	"encoding/json"
 	"errors"
@@ -40,64 +42,64 @@ func NewHandler() Handler {
 func (h *handler) HandleDoRaffle(c *gin.Context) {
 	userId, ok := user.GetCurrentUserID(c)
 	if !ok {
-		z.Error("failed to get current userId from context")
-		c.JSON(http.StatusOK, gin.H{
-			"status": 401,
-			"msg":    "未登录或登录已过期",
+		cmn.LoggerFrom(c).Error("failed to get current userId from context")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 401,
+			Msg:    "未登录或登录已过期",
 		})
 		return
 	}
 
 	raffleCountStr := c.Query("raffleCount")
 	if raffleCountStr == "" {
-		z.Error("raffleCount is required")
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "缺少 raffleCount 参数",
+		cmn.LoggerFrom(c).Error("raffleCount is required")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "缺少 raffleCount 参数",
 		})
 		return
 	}
 
 	raffleCount, err := strconv.ParseInt(raffleCountStr, 10, 64)
 	if err != nil {
-		z.Error("invalid raffleCountStr", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "raffleCountStr 参数无效，无法转换为整数",
+		cmn.LoggerFrom(c).Error("invalid raffleCountStr", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "raffleCountStr 参数无效，无法转换为整数",
 		})
 		return
 	}
 
 	if raffleCount <= 0 || raffleCount > 10 {
-		z.Error("raffleCount must be between 1 and 10", zap.Int64("raffleCount", raffleCount))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "抽奖次数必须在 1 到 10 之间",
+		cmn.LoggerFrom(c).Error("raffleCount must be between 1 and 10", zap.Int64("raffleCount", raffleCount))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "抽奖次数必须在 1 到 10 之间",
 		})
 		return
 	}
 
-	prizes, err := machine.doRaffle(userId, raffleCount)
+	prizes, err := machine.doRaffle(c, userId, raffleCount)
 	if err != nil {
-		z.Error("failed to perform raffle", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    err.Error(),
+		cmn.LoggerFrom(c).Error("failed to perform raffle", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    err.Error(),
 		})
 		return
 	}
 
 	prizesJson, err := json.Marshal(prizes)
 	if err != nil {
-		z.Error("failed to marshal prizes", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "抽奖结果序列化失败",
+		cmn.LoggerFrom(c).Error("failed to marshal prizes", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "抽奖结果序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "success",
 		Data:   prizesJson,
@@ -143,10 +145,10 @@ func (h *handler) HandleQueryRaffleWinners(c *gin.Context) {
 
 	// 先查询总数
 	if err = query.Count(&total).Error; err != nil {
-		z.Error("failed to count raffle winners", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询中奖用户总数失败",
+		cmn.LoggerFrom(c).Error("failed to count raffle winners", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询中奖用户总数失败",
 		})
 		return
 	}
@@ -157,10 +159,10 @@ func (h *handler) HandleQueryRaffleWinners(c *gin.Context) {
 		Offset(offset).
 		Limit(size).
 		Find(&winners).Error; err != nil {
-		z.Error("failed to query raffle winners", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询中奖用户信息失败",
+		cmn.LoggerFrom(c).Error("failed to query raffle winners", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询中奖用户信息失败",
 		})
 		return
 	}
@@ -168,15 +170,15 @@ func (h *handler) HandleQueryRaffleWinners(c *gin.Context) {
 	// 将响应数据转换为JSON
 	winnersJSON, err := json.Marshal(winners)
 	if err != nil {
-		z.Error("failed to marshal response data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "数据序列化失败",
+		cmn.LoggerFrom(c).Error("failed to marshal response data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status:   0,
 		Msg:      "success",
 		Data:     winnersJSON,
@@ -189,19 +191,19 @@ func (h *handler) HandleUpdatePrize(c *gin.Context) {
 	// 获取奖品ID
 	prizeIdStr := c.Param("id")
 	if prizeIdStr == "" {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "缺少奖品ID参数",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "缺少奖品ID参数",
 		})
 		return
 	}
 
 	prizeId, err := strconv.ParseInt(prizeIdStr, 10, 64)
 	if err != nil {
-		z.Error("invalid prizeId", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品ID格式无效",
+		cmn.LoggerFrom(c).Error("invalid prizeId", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品ID格式无效",
 		})
 		return
 	}
@@ -209,61 +211,61 @@ func (h *handler) HandleUpdatePrize(c *gin.Context) {
 	// 解析请求体
 	var req cmn.ReqProto
 	if err := c.ShouldBind(&req); err != nil {
-		z.Error("failed to bind request", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体格式错误",
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
 		})
 		return
 	}
 
 	var updateData cmn.TRafflePrize
 	if err := json.Unmarshal(req.Data, &updateData); err != nil {
-		z.Error("failed to bind request data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体data字段格式错误",
+		cmn.LoggerFrom(c).Error("failed to bind request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
 		})
 		return
 	}
 
 	// 验证必要字段
 	if updateData.Name == "" {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品名称不能为空",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品名称不能为空",
 		})
 		return
 	}
 
 	if updateData.Probability < 0 || updateData.Probability > 1 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品概率必须在0到1之间",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品概率必须在0到1之间",
 		})
 		return
 	}
 
 	if updateData.TotalCount < 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品总数不能为负数",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品总数不能为负数",
 		})
 		return
 	}
 
 	if updateData.RemainCount < 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "剩余奖品数量不能为负数",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "剩余奖品数量不能为负数",
 		})
 		return
 	}
 
 	if updateData.RemainCount > updateData.TotalCount {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "剩余奖品数量不能大于总数",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "剩余奖品数量不能大于总数",
 		})
 		return
 	}
@@ -272,37 +274,44 @@ func (h *handler) HandleUpdatePrize(c *gin.Context) {
 	var existingPrize cmn.TRafflePrize
 	if err := cmn.GormDB.First(&existingPrize, prizeId).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusOK, gin.H{
-				"status": 1,
-				"msg":    "奖品不存在",
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "奖品不存在",
 			})
 		} else {
-			z.Error("failed to query prize", zap.Error(err))
-			c.JSON(http.StatusOK, gin.H{
-				"status": -1,
-				"msg":    "查询奖品失败",
+			cmn.LoggerFrom(c).Error("failed to query prize", zap.Error(err))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: -1,
+				Msg:    "查询奖品失败",
 			})
 		}
 		return
 	}
 
 	// 更新奖品信息
+	before := existingPrize
 	updateData.Id = prizeId
 	if err := cmn.GormDB.Model(&existingPrize).Updates(&updateData).Error; err != nil {
-		z.Error("failed to update prize", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "更新奖品信息失败",
+		cmn.LoggerFrom(c).Error("failed to update prize", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "更新奖品信息失败",
 		})
 		return
 	}
 
+	var after cmn.TRafflePrize
+	if err := cmn.GormDB.First(&after, prizeId).Error; err != nil {
+		cmn.LoggerFrom(c).Error("failed to query updated prize", zap.Error(err))
+	}
+	admin.SetAuditChange(c, admin.AuditTarget("prize", prizeId), before, after)
+
 	// 如果奖品信息发生变化，需要重新同步到内存奖池
 	if err := machine.syncPrizesFromDB(); err != nil {
-		z.Error("failed to sync prizes to memory", zap.Error(err))
+		cmn.LoggerFrom(c).Error("failed to sync prizes to memory", zap.Error(err))
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "奖品信息更新成功",
 	})
@@ -313,69 +322,69 @@ func (h *handler) HandleCreatePrize(c *gin.Context) {
 	// 解析请求体
 	var req cmn.ReqProto
 	if err := c.ShouldBind(&req); err != nil {
-		z.Error("failed to bind request", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体格式错误",
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
 		})
 		return
 	}
 
 	var newPrize cmn.TRafflePrize
 	if err := json.Unmarshal(req.Data, &newPrize); err != nil {
-		z.Error("failed to bind JSON", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体data字段格式错误",
+		cmn.LoggerFrom(c).Error("failed to bind JSON", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
 		})
 		return
 	}
 
 	// 验证必要字段
 	if newPrize.Name == "" {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品名称不能为空",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品名称不能为空",
 		})
 		return
 	}
 
 	if newPrize.Probability < 0 || newPrize.Probability > 1 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品概率必须在0到1之间",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品概率必须在0到1之间",
 		})
 		return
 	}
 
 	if newPrize.TotalCount < 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品总数不能为负数",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品总数不能为负数",
 		})
 		return
 	}
 
 	if newPrize.RemainCount < 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "剩余奖品数量不能为负数",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "剩余奖品数量不能为负数",
 		})
 		return
 	}
 
 	if newPrize.RemainCount > newPrize.TotalCount {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "剩余奖品数量不能大于总数",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "剩余奖品数量不能大于总数",
 		})
 		return
 	}
 
 	if newPrize.Cost < 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品成本不能为负数",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品成本不能为负数",
 		})
 		return
 	}
@@ -388,17 +397,17 @@ func (h *handler) HandleCreatePrize(c *gin.Context) {
 	// 检查奖品名称是否已存在
 	var existingPrize cmn.TRafflePrize
 	if err := cmn.GormDB.Where("name = ?", newPrize.Name).First(&existingPrize).Error; err == nil {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品名称已存在",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品名称已存在",
 		})
 		return
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		// 如果不是记录不存在的错误，说明查询出现了其他问题
-		z.Error("failed to check existing prize", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "检查奖品名称失败",
+		cmn.LoggerFrom(c).Error("failed to check existing prize", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "检查奖品名称失败",
 		})
 		return
 	}
@@ -406,50 +415,52 @@ func (h *handler) HandleCreatePrize(c *gin.Context) {
 	// 检查新增奖品后总概率是否超过1
 	var totalProbability float64
 	if err := cmn.GormDB.Model(&cmn.TRafflePrize{}).Select("COALESCE(SUM(probability), 0)").Scan(&totalProbability).Error; err != nil {
-		z.Error("failed to calculate total probability", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "计算总概率失败",
+		cmn.LoggerFrom(c).Error("failed to calculate total probability", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "计算总概率失败",
 		})
 		return
 	}
 
 	// 检查新增奖品后是否超过概率上限
 	if totalProbability+newPrize.Probability > 1.0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    fmt.Sprintf("新增奖品后总概率将超过1.0，当前总概率：%.4f，新奖品概率：%.4f", totalProbability, newPrize.Probability),
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    fmt.Sprintf("新增奖品后总概率将超过1.0，当前总概率：%.4f，新奖品概率：%.4f", totalProbability, newPrize.Probability),
 		})
 		return
 	}
 
 	// 创建新奖品
 	if err := cmn.GormDB.Create(&newPrize).Error; err != nil {
-		z.Error("failed to create prize", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "创建奖品失败",
+		cmn.LoggerFrom(c).Error("failed to create prize", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "创建奖品失败",
 		})
 		return
 	}
 
+	admin.SetAuditChange(c, admin.AuditTarget("prize", newPrize.Id), nil, newPrize)
+
 	// 新增奖品后，需要重新同步到内存奖池
 	if err := machine.syncPrizesFromDB(); err != nil {
-		z.Error("failed to sync prizes to memory", zap.Error(err))
+		cmn.LoggerFrom(c).Error("failed to sync prizes to memory", zap.Error(err))
 	}
 
 	// 将新奖品数据转换为JSON
 	newPrizeJSON, err := json.Marshal(newPrize)
 	if err != nil {
-		z.Error("failed to marshal new prize data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "奖品数据序列化失败",
+		cmn.LoggerFrom(c).Error("failed to marshal new prize data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "奖品数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "奖品创建成功",
 		Data:   newPrizeJSON,
@@ -494,10 +505,10 @@ func (h *handler) HandleQueryPrizes(c *gin.Context) {
 
 	// 先查询总数
 	if err := query.Count(&total).Error; err != nil {
-		z.Error("failed to count prizes", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询奖品总数失败",
+		cmn.LoggerFrom(c).Error("failed to count prizes", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询奖品总数失败",
 		})
 		return
 	}
@@ -508,10 +519,10 @@ func (h *handler) HandleQueryPrizes(c *gin.Context) {
 		Offset(offset).
 		Limit(size).
 		Find(&prizes).Error; err != nil {
-		z.Error("failed to query prizes", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询奖品信息失败",
+		cmn.LoggerFrom(c).Error("failed to query prizes", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询奖品信息失败",
 		})
 		return
 	}
@@ -519,15 +530,15 @@ func (h *handler) HandleQueryPrizes(c *gin.Context) {
 	// 将响应数据转换为JSON
 	prizesJSON, err := json.Marshal(prizes)
 	if err != nil {
-		z.Error("failed to marshal response data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "数据序列化失败",
+		cmn.LoggerFrom(c).Error("failed to marshal response data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status:   0,
 		Msg:      "success",
 		Data:     prizesJSON,
@@ -540,10 +551,10 @@ func (h *handler) HandleQueryMyWinnings(c *gin.Context) {
 	// 获取当前用户ID
 	userId, ok := user.GetCurrentUserID(c)
 	if !ok {
-		z.Error("failed to get current userId from context")
-		c.JSON(http.StatusOK, gin.H{
-			"status": 401,
-			"msg":    "未登录或登录已过期",
+		cmn.LoggerFrom(c).Error("failed to get current userId from context")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 401,
+			Msg:    "未登录或登录已过期",
 		})
 		return
 	}
@@ -575,10 +586,10 @@ func (h *handler) HandleQueryMyWinnings(c *gin.Context) {
 
 	// 先查询总数
 	if err := cmn.GormDB.Model(&cmn.TRaffleWinners{}).Where("user_id = ?", userId).Count(&total).Error; err != nil {
-		z.Error("failed to count my winnings", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询我的中奖总数失败",
+		cmn.LoggerFrom(c).Error("failed to count my winnings", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询我的中奖总数失败",
 		})
 		return
 	}
@@ -590,10 +601,10 @@ func (h *handler) HandleQueryMyWinnings(c *gin.Context) {
 		Offset(offset).
 		Limit(size).
 		Find(&myWinnings).Error; err != nil {
-		z.Error("failed to query my winnings", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询我的中奖信息失败",
+		cmn.LoggerFrom(c).Error("failed to query my winnings", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询我的中奖信息失败",
 		})
 		return
 	}
@@ -601,15 +612,15 @@ func (h *handler) HandleQueryMyWinnings(c *gin.Context) {
 	// 将响应数据转换为JSON
 	winningsJSON, err := json.Marshal(myWinnings)
 	if err != nil {
-		z.Error("failed to marshal response data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "数据序列化失败",
+		cmn.LoggerFrom(c).Error("failed to marshal response data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status:   0,
 		Msg:      "success",
 		Data:     winningsJSON,
@@ -622,10 +633,10 @@ func (h *handler) HandleUpdateConsumePoints(c *gin.Context) {
 	// 解析请求体
 	var req cmn.ReqProto
 	if err := c.ShouldBind(&req); err != nil {
-		z.Error("failed to bind request", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体格式错误",
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
 		})
 		return
 	}
@@ -635,57 +646,46 @@ func (h *handler) HandleUpdateConsumePoints(c *gin.Context) {
 		ConsumePointsValue int64  `json:"consumePointsValue"`
 	}
 	if err := json.Unmarshal(req.Data, &updateData); err != nil {
-		z.Error("failed to unmarshal request data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体data字段格式错误",
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
 		})
 		return
 	}
 
-	// 验证参数
-	if updateData.ConsumePointsValue < 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "消耗积分不能为负数",
-		})
-		return
+	// 只在 ConsumePointsKey 不为空时更新消耗积分类型，校验与抽奖机重置由配置服务完成
+	beforeKey, beforeValue := consumePoints()
+	values := map[string]string{
+		cfgKeyConsumePointsValue: strconv.FormatInt(updateData.ConsumePointsValue, 10),
 	}
-
-	// 更新数据库配置表中的消耗积分值
-	consumePointsValueStr := strconv.FormatInt(updateData.ConsumePointsValue, 10)
-	if err := cmn.GormDB.Model(&cmn.TCfgCommon{}).Where("key = ?", cfgKeyConsumePointsValue).Update("value", consumePointsValueStr).Error; err != nil {
-		z.Error("failed to update consume points value in config table", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "更新消耗积分值配置失败",
+	if updateData.ConsumePointsKey != "" {
+		values[cfgKeyConsumePointsKey] = normalizePointsType(updateData.ConsumePointsKey)
+	}
+	err := settings.Set(c, nil, values)
+	if errors.Is(err, settings.ErrInvalidValue) {
+		cmn.LoggerFrom(c).Error("invalid consume points config", zap.Error(err), zap.String("consumePointsKey", updateData.ConsumePointsKey))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "积分类型不存在或已停用，或消耗积分为负数",
 		})
 		return
 	}
-
-	// 只在 ConsumePointsKey 不为空时更新配置表中的消耗积分键
-	if updateData.ConsumePointsKey != "" {
-		if err := cmn.GormDB.Model(&cmn.TCfgCommon{}).Where("key = ?", cfgKeyConsumePointsKey).Update("value", updateData.ConsumePointsKey).Error; err != nil {
-			z.Error("failed to update consume points key in config table", zap.Error(err))
-			c.JSON(http.StatusOK, gin.H{
-				"status": -1,
-				"msg":    "更新消耗积分键配置失败",
-			})
-			return
-		}
-	}
-
-	// 调用machine的resetConsumePoints方法重置抽奖机消耗积分
-	if err := machine.resetConsumePoints(updateData.ConsumePointsKey, updateData.ConsumePointsValue); err != nil {
-		z.Error("failed to reset consume points", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "重置抽奖机消耗积分失败",
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to update consume points config", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "更新消耗积分配置失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	afterKey, afterValue := consumePoints()
+	admin.SetAuditChange(c, "raffle:consumePoints",
+		map[string]any{"consumePointsKey": beforeKey, "consumePointsValue": beforeValue},
+		map[string]any{"consumePointsKey": afterKey, "consumePointsValue": afterValue})
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "抽奖消耗积分配置更新成功",
 	})
@@ -693,57 +693,26 @@ func (h *handler) HandleUpdateConsumePoints(c *gin.Context) {
 
 // HandleQueryConsumePoints 查询当前抽奖消耗积分配置
 func (h *handler) HandleQueryConsumePoints(c *gin.Context) {
-	// 查询消耗积分键配置
-	var consumePointsKeyConfig cmn.TCfgCommon
-	if err := cmn.GormDB.Where("key = ?", cfgKeyConsumePointsKey).First(&consumePointsKeyConfig).Error; err != nil {
-		z.Error("failed to query consume points key config", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询消耗积分键配置失败",
-		})
-		return
-	}
-
-	// 查询消耗积分值配置
-	var consumePointsValueConfig cmn.TCfgCommon
-	if err := cmn.GormDB.Where("key = ?", cfgKeyConsumePointsValue).First(&consumePointsValueConfig).Error; err != nil {
-		z.Error("failed to query consume points value config", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询消耗积分值配置失败",
-		})
-		return
-	}
-
-	// 转换积分值为整数
-	consumePointsValue, err := strconv.ParseInt(consumePointsValueConfig.Value, 10, 64)
-	if err != nil {
-		z.Error("failed to parse consume points value", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "消耗积分值格式错误",
-		})
-		return
-	}
+	consumePointsKey, consumePointsValue := consumePoints()
 
 	// 构造响应数据
 	responseData := map[string]interface{}{
-		"consumePointsKey":   consumePointsKeyConfig.Value,
+		"consumePointsKey":   consumePointsKey,
 		"consumePointsValue": consumePointsValue,
 	}
 
 	// 序列化响应数据
 	responseJson, err := json.Marshal(responseData)
 	if err != nil {
-		z.Error("failed to marshal response data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "响应数据序列化失败",
+		cmn.LoggerFrom(c).Error("failed to marshal response data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "响应数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "查询消耗积分配置成功",
 		Data:   responseJson,
@@ -755,10 +724,10 @@ func (h *handler) HandleDeletePrizes(c *gin.Context) {
 	// 解析请求体
 	var req cmn.ReqProto
 	if err := c.ShouldBind(&req); err != nil {
-		z.Error("failed to bind request", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体格式错误",
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
 		})
 		return
 	}
@@ -767,28 +736,28 @@ func (h *handler) HandleDeletePrizes(c *gin.Context) {
 		PrizeIds []int64 `json:"prizeIds"`
 	}
 	if err := json.Unmarshal(req.Data, &deleteData); err != nil {
-		z.Error("failed to unmarshal request data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体data字段格式错误",
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
 		})
 		return
 	}
 
 	// 验证参数
 	if len(deleteData.PrizeIds) == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品ID列表不能为空",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品ID列表不能为空",
 		})
 		return
 	}
 
 	// 限制批量删除数量
 	if len(deleteData.PrizeIds) > 100 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "单次最多删除100个奖品",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "单次最多删除100个奖品",
 		})
 		return
 	}
@@ -796,19 +765,19 @@ func (h *handler) HandleDeletePrizes(c *gin.Context) {
 	// 检查奖品是否存在
 	var existingPrizes []cmn.TRafflePrize
 	if err := cmn.GormDB.Where("id IN ?", deleteData.PrizeIds).Find(&existingPrizes).Error; err != nil {
-		z.Error("failed to query existing prizes", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询奖品失败",
+		cmn.LoggerFrom(c).Error("failed to query existing prizes", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询奖品失败",
 		})
 		return
 	}
 
 	// 检查是否所有奖品都存在
 	if len(existingPrizes) != len(deleteData.PrizeIds) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "部分奖品不存在",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "部分奖品不存在",
 		})
 		return
 	}
@@ -816,10 +785,10 @@ func (h *handler) HandleDeletePrizes(c *gin.Context) {
 	// 开启事务进行删除和同步操作
 	tx := cmn.GormDB.Begin()
 	if tx.Error != nil {
-		z.Error("failed to begin transaction", zap.Error(tx.Error))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "开启事务失败",
+		cmn.LoggerFrom(c).Error("failed to begin transaction", zap.Error(tx.Error))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "开启事务失败",
 		})
 		return
 	}
@@ -827,37 +796,39 @@ func (h *handler) HandleDeletePrizes(c *gin.Context) {
 	// 批量删除奖品
 	result := tx.Where("id IN ?", deleteData.PrizeIds).Delete(&cmn.TRafflePrize{})
 	if result.Error != nil {
-		z.Error("failed to delete prizes", zap.Error(result.Error))
+		cmn.LoggerFrom(c).Error("failed to delete prizes", zap.Error(result.Error))
 		tx.Rollback()
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "删除奖品失败",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "删除奖品失败",
 		})
 		return
 	}
 
 	// 删除奖品后，需要重新同步到内存奖池
 	if err := machine.syncPrizesFromDB(); err != nil {
-		z.Error("failed to sync prizes to memory", zap.Error(err))
+		cmn.LoggerFrom(c).Error("failed to sync prizes to memory", zap.Error(err))
 		tx.Rollback()
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "同步奖品到内存失败，已回滚删除操作",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "同步奖品到内存失败，已回滚删除操作",
 		})
 		return
 	}
 
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
-		z.Error("failed to commit transaction", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "提交事务失败",
+		cmn.LoggerFrom(c).Error("failed to commit transaction", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "提交事务失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	admin.SetAuditChange(c, admin.AuditTarget("prize", deleteData.PrizeIds), existingPrizes, nil)
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    fmt.Sprintf("成功删除%d个奖品", result.RowsAffected),
 	})
@@ -868,10 +839,10 @@ func (h *handler) HandleCreateDesignatedUser(c *gin.Context) {
 	// 解析请求体
 	var req cmn.ReqProto
 	if err := c.ShouldBind(&req); err != nil {
-		z.Error("failed to bind request", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体格式错误",
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
 		})
 		return
 	}
@@ -881,27 +852,27 @@ func (h *handler) HandleCreateDesignatedUser(c *gin.Context) {
 		PrizeId     int64  `json:"prizeId"`
 	}
 	if err := json.Unmarshal(req.Data, &requestData); err != nil {
-		z.Error("failed to unmarshal request data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体data字段格式错误",
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
 		})
 		return
 	}
 
 	// 验证必要字段
 	if requestData.MobilePhone == "" {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "用户手机号不能为空",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "用户手机号不能为空",
 		})
 		return
 	}
 
 	if requestData.PrizeId <= 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "奖品ID不能为空",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "奖品ID不能为空",
 		})
 		return
 	}
@@ -910,15 +881,15 @@ func (h *handler) HandleCreateDesignatedUser(c *gin.Context) {
 	var u cmn.TUser
 	if err := cmn.GormDB.First(&u, "mobile_phone = ?", requestData.MobilePhone).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusOK, gin.H{
-				"status": 1,
-				"msg":    "用户不存在",
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "用户不存在",
 			})
 		} else {
-			z.Error("failed to query user by mobile phone", zap.Error(err))
-			c.JSON(http.StatusOK, gin.H{
-				"status": -1,
-				"msg":    "查询用户失败",
+			cmn.LoggerFrom(c).Error("failed to query user by mobile phone", zap.Error(err))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: -1,
+				Msg:    "查询用户失败",
 			})
 		}
 		return
@@ -928,15 +899,15 @@ func (h *handler) HandleCreateDesignatedUser(c *gin.Context) {
 	var prize cmn.TRafflePrize
 	if err := cmn.GormDB.First(&prize, "id = ?", requestData.PrizeId).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusOK, gin.H{
-				"status": 1,
-				"msg":    "奖品不存在",
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: 1,
+				Msg:    "奖品不存在",
 			})
 		} else {
-			z.Error("failed to query prize", zap.Error(err))
-			c.JSON(http.StatusOK, gin.H{
-				"status": -1,
-				"msg":    "查询奖品失败",
+			cmn.LoggerFrom(c).Error("failed to query prize", zap.Error(err))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: -1,
+				Msg:    "查询奖品失败",
 			})
 		}
 		return
@@ -945,16 +916,16 @@ func (h *handler) HandleCreateDesignatedUser(c *gin.Context) {
 	// 检查是否已存在相同的指定获奖用户记录
 	var existingRecord cmn.TRaffleDesignatedUser
 	if err := cmn.GormDB.Where("user_id = ? AND prize_id = ?", u.Id, requestData.PrizeId).First(&existingRecord).Error; err == nil {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "该用户已被指定为此奖品的获奖者",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "该用户已被指定为此奖品的获奖者",
 		})
 		return
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		z.Error("failed to check existing designated user", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "检查指定获奖用户失败",
+		cmn.LoggerFrom(c).Error("failed to check existing designated user", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "检查指定获奖用户失败",
 		})
 		return
 	}
@@ -965,26 +936,28 @@ func (h *handler) HandleCreateDesignatedUser(c *gin.Context) {
 		PrizeId: requestData.PrizeId,
 	}
 	if err := cmn.GormDB.Create(&createData).Error; err != nil {
-		z.Error("failed to create designated user", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "创建指定获奖用户失败",
+		cmn.LoggerFrom(c).Error("failed to create designated user", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "创建指定获奖用户失败",
 		})
 		return
 	}
 
+	admin.SetAuditChange(c, admin.AuditTarget("designated_user", createData.Id), nil, createData)
+
 	// 将创建的数据转换为JSON
 	createdDataJSON, err := json.Marshal(createData)
 	if err != nil {
-		z.Error("failed to marshal created data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "数据序列化失败",
+		cmn.LoggerFrom(c).Error("failed to marshal created data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "指定获奖用户创建成功",
 		Data:   createdDataJSON,
@@ -996,10 +969,10 @@ func (h *handler) HandleDeleteDesignatedUsers(c *gin.Context) {
 	// 解析请求体
 	var req cmn.ReqProto
 	if err := c.ShouldBind(&req); err != nil {
-		z.Error("failed to bind request", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体格式错误",
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体格式错误",
 		})
 		return
 	}
@@ -1008,28 +981,28 @@ func (h *handler) HandleDeleteDesignatedUsers(c *gin.Context) {
 		Ids []int64 `json:"ids"`
 	}
 	if err := json.Unmarshal(req.Data, &deleteData); err != nil {
-		z.Error("failed to unmarshal request data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体data字段格式错误",
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体data字段格式错误",
 		})
 		return
 	}
 
 	// 验证参数
 	if len(deleteData.Ids) == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "ID列表不能为空",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "ID列表不能为空",
 		})
 		return
 	}
 
 	// 限制批量删除数量
 	if len(deleteData.Ids) > 100 {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "单次最多删除100条记录",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "单次最多删除100条记录",
 		})
 		return
 	}
@@ -1037,19 +1010,19 @@ func (h *handler) HandleDeleteDesignatedUsers(c *gin.Context) {
 	// 检查记录是否存在
 	var existingRecords []cmn.TRaffleDesignatedUser
 	if err := cmn.GormDB.Where("id IN ?", deleteData.Ids).Find(&existingRecords).Error; err != nil {
-		z.Error("failed to query existing designated users", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询指定获奖用户失败",
+		cmn.LoggerFrom(c).Error("failed to query existing designated users", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询指定获奖用户失败",
 		})
 		return
 	}
 
 	// 检查是否所有记录都存在
 	if len(existingRecords) != len(deleteData.Ids) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "部分记录不存在",
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "部分记录不存在",
 		})
 		return
 	}
@@ -1057,15 +1030,17 @@ func (h *handler) HandleDeleteDesignatedUsers(c *gin.Context) {
 	// 批量删除记录
 	result := cmn.GormDB.Where("id IN ?", deleteData.Ids).Delete(&cmn.TRaffleDesignatedUser{})
 	if result.Error != nil {
-		z.Error("failed to delete designated users", zap.Error(result.Error))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "删除指定获奖用户失败",
+		cmn.LoggerFrom(c).Error("failed to delete designated users", zap.Error(result.Error))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "删除指定获奖用户失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	admin.SetAuditChange(c, admin.AuditTarget("designated_user", deleteData.Ids), existingRecords, nil)
+
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    fmt.Sprintf("成功删除%d条指定获奖用户记录", result.RowsAffected),
 	})
@@ -1100,10 +1075,10 @@ func (h *handler) HandleQueryDesignatedUsers(c *gin.Context) {
 
 	// 先查询总数
 	if err := cmn.GormDB.Model(&cmn.VRaffleDesignatedUserPrizeInfo{}).Count(&total).Error; err != nil {
-		z.Error("failed to count designated users", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询指定获奖用户总数失败",
+		cmn.LoggerFrom(c).Error("failed to count designated users", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询指定获奖用户总数失败",
 		})
 		return
 	}
@@ -1114,10 +1089,10 @@ func (h *handler) HandleQueryDesignatedUsers(c *gin.Context) {
 		Offset(offset).
 		Limit(size).
 		Find(&designatedUsers).Error; err != nil {
-		z.Error("failed to query designated users", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "查询指定获奖用户信息失败",
+		cmn.LoggerFrom(c).Error("failed to query designated users", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "查询指定获奖用户信息失败",
 		})
 		return
 	}
@@ -1125,15 +1100,15 @@ func (h *handler) HandleQueryDesignatedUsers(c *gin.Context) {
 	// 将响应数据转换为JSON
 	designatedUsersJSON, err := json.Marshal(designatedUsers)
 	if err != nil {
-		z.Error("failed to marshal response data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "数据序列化失败",
+		cmn.LoggerFrom(c).Error("failed to marshal response data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status:   0,
 		Msg:      "success",
 		Data:     designatedUsersJSON,
@@ -2,9 +2,13 @@ package raffle
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/metrics"
+	"WudangMeta/cmn/points_core"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync/atomic"
 
 	"github.com/google/uuid"
@@ -16,29 +20,36 @@ import (
 
 // Machine 抽奖机
 type Machine struct {
-	atomicPrizes       atomic.Value // 内存奖池
-	consumePointsValue int64        // 单次抽奖消耗积分
-	consumePointsKey   string       // 消耗的积分类型
+	atomicPrizes        atomic.Value                  // 内存奖池
+	atomicConsumePoints atomic.Pointer[consumeConfig] // 单次抽奖消耗的积分，配置变更时整体替换
 }
 
-func NewMachine(pointsKey string, consumePoints int64) (*Machine, error) {
+// consumeConfig 单次抽奖消耗的积分
+type consumeConfig struct {
+	pointsType  string // 消耗的积分类型编码
+	pointsValue int64  // 单次抽奖消耗积分
+}
+
+func NewMachine(pointsType string, consumePoints int64) (*Machine, error) {
 	if consumePoints < 0 {
 		e := fmt.Errorf("consumePointsValue %d < 0", consumePoints)
 		return nil, e
 	}
-	if pointsKey == "" {
-		pointsKey = "default_points"
+	if pointsType == "" {
+		pointsType = points_core.PointsTypeDefault
 	}
-
-	m := &Machine{
-		consumePointsValue: consumePoints,
-		consumePointsKey:   pointsKey,
+	err := points_core.ValidatePointsType(context.Background(), nil, pointsType)
+	if err != nil {
+		return nil, err
 	}
 
+	m := &Machine{}
+	m.atomicConsumePoints.Store(&consumeConfig{pointsType: pointsType, pointsValue: consumePoints})
+
 	var emptyPrizes []cmn.TRafflePrize
 	m.atomicPrizes.Store(emptyPrizes)
 
-	err := m.syncPrizesFromDB()
+	err = m.syncPrizesFromDB()
 	if err != nil {
 		z.Error("failed to sync prizes from db", zap.Error(err))
 		return nil, err
@@ -77,17 +88,23 @@ func (m *Machine) syncPrizesFromDB() error {
 }
 
 // 重置单词抽奖消耗积分
-func (m *Machine) resetConsumePoints(pointsKey string, pointsValue int64) error {
+func (m *Machine) resetConsumePoints(pointsType string, pointsValue int64) error {
 	if pointsValue < 0 {
 		e := fmt.Errorf("consumePointsValue %d < 0", pointsValue)
 		return e
 	}
 
-	m.consumePointsValue = pointsValue
+	if pointsType != "" {
+		err := points_core.ValidatePointsType(context.Background(), nil, pointsType)
+		if err != nil {
+			return err
+		}
+	}
 
-	if pointsKey != "" {
-		m.consumePointsKey = pointsKey
+	if pointsType == "" {
+		pointsType = m.atomicConsumePoints.Load().pointsType
 	}
+	m.atomicConsumePoints.Store(&consumeConfig{pointsType: pointsType, pointsValue: pointsValue})
 
 	return nil
 }
@@ -130,7 +147,7 @@ func (m *Machine) buildRafflePoolByProbability() []weightedrand.Choice[string, u
 }
 
 // doRaffle 执行抽奖逻辑，支持多次抽奖
-func (m *Machine) doRaffle(userId uuid.UUID, raffleCount int64) ([]string, error) {
+func (m *Machine) doRaffle(ctx context.Context, userId uuid.UUID, raffleCount int64) ([]string, error) {
 	// 获取当前奖池
 	prizes := m.atomicPrizes.Load().([]cmn.TRafflePrize)
 	if len(prizes) == 0 {
@@ -141,27 +158,34 @@ func (m *Machine) doRaffle(userId uuid.UUID, raffleCount int64) ([]string, error
 	var prizesWon []string
 
 	err := cmn.GormDB.Transaction(func(tx *gorm.DB) error {
-		// 查询用户积分是否足够抽奖
-		var userPoints float64
-		err := tx.Model(&cmn.TUserPoints{}).
-			Where("user_id = ?", userId).
-			Pluck(m.consumePointsKey, &userPoints).Error
+		// 先创建抽奖日志，作为积分流水的关联ID
+		raffleLog := cmn.TRaffleLog{
+			UserId: userId,
+			Count:  raffleCount,
+			Prizes: datatypes.JSON("[]"),
+		}
+		err := tx.Create(&raffleLog).Error
 		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				e := fmt.Errorf("user points not found for user_id %s", userId.String())
-				z.Error(e.Error())
-				return e
-			}
-			z.Error("failed to query user points", zap.Error(err), zap.String("user_id", userId.String()))
+			z.Error("failed to create raffle log", zap.Error(err), zap.String("user_id", userId.String()))
 			return err
 		}
 
-		// 检查积分是否足够
-		if userPoints < float64(m.consumePointsValue)*float64(raffleCount) {
-			z.Sugar().Error("insufficient points for user_id %s, current points: %.2f, required: %d", userId.String(), userPoints, m.consumePointsValue*raffleCount)
-			return fmt.Errorf("您的积分不足，无法进行抽奖\n当前积分：%.2f，抽奖所需积分：%d", userPoints, m.consumePointsValue*raffleCount)
+		// 原子扣除本次抽奖所需的全部积分，余额不足时整个事务回滚
+		consume := m.atomicConsumePoints.Load()
+		required := consume.pointsValue * raffleCount
+		if required > 0 {
+			_, err = points_core.Debit(ctx, tx, userId, consume.pointsType, float64(required), points_core.ReasonRaffle, strconv.FormatInt(raffleLog.Id, 10))
+			if err != nil {
+				var insufficient *points_core.InsufficientBalanceError
+				if errors.As(err, &insufficient) {
+					z.Sugar().Errorf("insufficient points for user_id %s, current points: %.2f, required: %d", userId.String(), insufficient.Balance, required)
+					return fmt.Errorf("您的积分不足，无法进行抽奖\n当前积分：%.2f，抽奖所需积分：%d", insufficient.Balance, required)
+				}
+				e := fmt.Errorf("failed to deduct user points: %w", err)
+				z.Error(e.Error())
+				return e
+			}
 		}
-		remainPoints := userPoints
 
 		// 检查是否有指定获奖记录
 		var designatedPrizes []cmn.VRaffleDesignatedUserPrizeInfo
@@ -229,17 +253,6 @@ func (m *Machine) doRaffle(userId uuid.UUID, raffleCount int64) ([]string, error
 				prizesWon = append(prizesWon, selectedPrizeName)
 			}
 
-			// 扣除用户积分
-			remainPoints -= float64(m.consumePointsValue)
-			err = tx.Model(&cmn.TUserPoints{}).
-				Where("user_id = ?", userId).
-				Update(m.consumePointsKey, remainPoints).Error
-			if err != nil {
-				e := fmt.Errorf("failed to deduct user points: %w", err)
-				z.Error(e.Error())
-				return e
-			}
-
 			// 如果中奖（不是 noPrizeSign），则更新奖品剩余数量
 			if selectedPrize != nil {
 				// 更新奖品剩余数量
@@ -262,7 +275,7 @@ func (m *Machine) doRaffle(userId uuid.UUID, raffleCount int64) ([]string, error
 			}
 		}
 
-		// 创建抽奖日志
+		// 回写抽奖日志中的获得奖品
 		var prizeDataJson []byte
 		if len(prizesWon) > 0 {
 			// 只记录奖品名数组，如果没有中奖，则记录空数组
@@ -276,14 +289,9 @@ func (m *Machine) doRaffle(userId uuid.UUID, raffleCount int64) ([]string, error
 			return err
 		}
 
-		raffleLog := cmn.TRaffleLog{
-			UserId: userId,
-			Count:  raffleCount,
-			Prizes: datatypes.JSON(prizeDataJson),
-		}
-		err = tx.Create(&raffleLog).Error
+		err = tx.Model(&raffleLog).Update("prizes", datatypes.JSON(prizeDataJson)).Error
 		if err != nil {
-			z.Error("failed to create raffle log", zap.Error(err), zap.String("user_id", userId.String()))
+			z.Error("failed to update raffle log", zap.Error(err), zap.String("user_id", userId.String()))
 			return err
 		}
 
@@ -299,6 +307,7 @@ func (m *Machine) doRaffle(userId uuid.UUID, raffleCount int64) ([]string, error
 		return nil
 	})
 
+	metrics.ObserveRaffle(raffleCount, prizesWon, err)
 	if err != nil {
 		z.Error("raffle transaction failed", zap.Error(err), zap.String("user_id", userId.String()))
 		return []string{}, err
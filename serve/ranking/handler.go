@@ -25,8 +25,8 @@ func (h *handler) HandleQueryRankingList(c *gin.Context) {
 	var req cmn.ReqProto
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		z.Error("failed to bind request", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "请求参数错误，请检查是否符合请求协议",
 		})
@@ -51,8 +51,8 @@ func (h *handler) HandleQueryRankingList(c *gin.Context) {
 		if req.Filter != nil {
 			err = json.Unmarshal(req.Filter, &filter)
 			if err != nil {
-				z.Error("failed to unmarshal filter", zap.Error(err))
-				c.JSON(http.StatusOK, cmn.ReplyProto{
+				cmn.LoggerFrom(c).Error("failed to unmarshal filter", zap.Error(err))
+				cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 					Status: -1,
 					Msg:    "过滤条件解析错误",
 				})
@@ -62,8 +62,8 @@ func (h *handler) HandleQueryRankingList(c *gin.Context) {
 
 		rankingList, rowCount, err := QueryAssetRankingList(c, req.Page, req.PageSize, filter.AssetIds, filter.MinValue)
 		if err != nil {
-			z.Error("failed to query asset ranking list", zap.Error(err))
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.LoggerFrom(c).Error("failed to query asset ranking list", zap.Error(err))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: -1,
 				Msg:    "查询排行榜失败",
 			})
@@ -72,15 +72,15 @@ func (h *handler) HandleQueryRankingList(c *gin.Context) {
 
 		rankingListJson, err := json.Marshal(rankingList)
 		if err != nil {
-			z.Error("failed to marshal ranking list", zap.Error(err))
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.LoggerFrom(c).Error("failed to marshal ranking list", zap.Error(err))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: -1,
 				Msg:    "排行榜数据序列化失败",
 			})
 			return
 		}
 
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status:   0,
 			Msg:      "查询成功",
 			Data:     rankingListJson,
@@ -88,8 +88,8 @@ func (h *handler) HandleQueryRankingList(c *gin.Context) {
 		})
 		return
 	default:
-		z.Error("unknown action", zap.String("action", req.Action))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("unknown action", zap.String("action", req.Action))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "未知的操作",
 		})
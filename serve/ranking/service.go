@@ -38,7 +38,7 @@ func QueryAssetRankingList(ctx context.Context, page, pageSize int64, filterMeta
 	var totalCount int64
 	err := cmn.GormDB.Table("(?) as sub", subQuery).Count(&totalCount).Error
 	if err != nil {
-		z.Error("failed to query total count", zap.Error(err))
+		cmn.LoggerFrom(ctx).Error("failed to query total count", zap.Error(err))
 		return nil, 0, fmt.Errorf("failed to query total count: %w", err)
 	}
 
@@ -64,7 +64,7 @@ func QueryAssetRankingList(ctx context.Context, page, pageSize int64, filterMeta
 
 	err = rankedQuery.Scan(&rankingResults).Error
 	if err != nil {
-		z.Error("failed to query ranking data", zap.Error(err))
+		cmn.LoggerFrom(ctx).Error("failed to query ranking data", zap.Error(err))
 		return nil, 0, fmt.Errorf("failed to query ranking data: %w", err)
 	}
 
@@ -2,68 +2,145 @@ package task
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/points_core"
+	"WudangMeta/cmn/scheduler"
+	"context"
 	"fmt"
+	"reflect"
+	"sync/atomic"
 
-	"github.com/spf13/viper"
+	"github.com/go-viper/mapstructure/v2"
 	"go.uber.org/zap"
 )
 
 var z *zap.Logger
 
-var (
-	dailyCheckInPoints    float64 // 每日签到积分
-	fortuneAnalysisPoints float64 // 运势分析积分
-	llmPrompt             LlmPrompt
+const (
+	jobFortuneRefresh = "fortune_refresh" // 每日零点刷新所有用户运势
 )
 
-func Init() {
+// taskSettings 任务奖励与大模型提示词，配置热更新时整体替换
+type taskSettings struct {
+	dailyCheckInPoints        float64 // 每日签到积分
+	dailyCheckInPointsType    string  // 每日签到奖励的积分类型
+	fortuneAnalysisPoints     float64 // 运势分析积分
+	fortuneAnalysisPointsType string  // 运势分析奖励的积分类型
+	llmPrompt                 LlmPrompt
+}
+
+var settings atomic.Pointer[taskSettings]
+
+func Init(ctx context.Context) {
 	z = cmn.GetLogger()
 
-	enable := viper.GetBool("task.enable")
-	if !enable {
+	cfg := cmn.GetConfig().Task
+	if !cfg.Enable {
 		cmn.MiniLogger.Info("[ -- ] task module disabled")
 		return
 	}
 
-	// 初始化每日签到积分
-	dailyCheckInPoints = viper.GetFloat64("task.reward.dailyCheckInPoints")
-	if dailyCheckInPoints <= 0 {
-		z.Fatal("[ FAIL ] daily check in points must be greater than 0")
+	s, err := loadSettings(ctx, cfg)
+	if err != nil {
+		z.Fatal("[ FAIL ] invalid task config", zap.Error(err))
 	}
-	// 初始化运势分析积分
-	fortuneAnalysisPoints = viper.GetFloat64("task.reward.fortuneAnalysisPoints")
-	if fortuneAnalysisPoints <= 0 {
-		z.Fatal("[ FAIL ] luck tendency points must be greater than 0")
+	settings.Store(s)
+
+	// 奖励积分与提示词修改后热更新，积分类型不存在时拒绝本次变更
+	cmn.SubscribeConfig("task", func(ctx context.Context, old, new *cmn.Config) error {
+		if reflect.DeepEqual(old.Task, new.Task) {
+			return nil
+		}
+		s, err := loadSettings(ctx, new.Task)
+		if err != nil {
+			return err
+		}
+		settings.Store(s)
+		return nil
+	})
+
+	// 注册运势刷新任务，默认不启用
+	err = scheduler.Register(scheduler.Job{
+		Name:      jobFortuneRefresh,
+		Spec:      "0 0 * * *",
+		Enable:    false,
+		Singleton: true,
+		Fn:        RefreshAllUsersFortune,
+	})
+	if err != nil {
+		z.Fatal("[ FAIL ] failed to register fortune refresh job", zap.Error(err))
+	}
+
+	cmn.MiniLogger.Info("[ OK ] task module initialed",
+		zap.Float64("dailyCheckInPoints", s.dailyCheckInPoints), zap.String("dailyCheckInPointsType", s.dailyCheckInPointsType),
+		zap.Float64("fortuneAnalysisPoints", s.fortuneAnalysisPoints), zap.String("fortuneAnalysisPointsType", s.fortuneAnalysisPointsType))
+}
+
+// currentSettings 返回当前生效的任务配置，模块未启用时返回空配置
+func currentSettings() *taskSettings {
+	s := settings.Load()
+	if s == nil {
+		return &taskSettings{}
+	}
+	return s
+}
+
+// loadSettings 解析并校验任务配置，奖励积分的取值范围已在 cmn.Config.Validate 中校验
+func loadSettings(ctx context.Context, cfg cmn.TaskConfig) (*taskSettings, error) {
+	s := &taskSettings{
+		dailyCheckInPoints:    cfg.Reward.DailyCheckInPoints,
+		fortuneAnalysisPoints: cfg.Reward.FortuneAnalysisPoints,
+	}
+
+	// 初始化奖励积分类型，未配置时使用默认积分
+	var err error
+	s.dailyCheckInPointsType, err = initRewardPointsType(ctx, cfg.Reward.DailyCheckInPointsType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid daily check in points type: %w", err)
+	}
+	s.fortuneAnalysisPointsType, err = initRewardPointsType(ctx, cfg.Reward.FortuneAnalysisPointsType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fortune analysis points type: %w", err)
 	}
 
 	// 初始化大模型提示词
-	err := initLlmPrompt()
+	s.llmPrompt, err = initLlmPrompt(cfg.LlmPrompt)
 	if err != nil {
-		z.Fatal("[ FAIL ] failed to init llmPrompt", zap.Error(err))
+		return nil, fmt.Errorf("failed to init llmPrompt: %w", err)
 	}
 
-	//ctx := context.Background()
-	//go fortuneRefresher(ctx)
+	return s, nil
+}
+
+// initRewardPointsType 校验任务奖励的积分类型，未配置时使用默认积分
+func initRewardPointsType(ctx context.Context, pointsType string) (string, error) {
+	if pointsType == "" {
+		pointsType = points_core.PointsTypeDefault
+	}
+
+	err := points_core.ValidatePointsType(ctx, nil, pointsType)
+	if err != nil {
+		return "", err
+	}
 
-	cmn.MiniLogger.Info("[ OK ] task module initialed", zap.Float64("dailyCheckInPoints", dailyCheckInPoints), zap.Float64("fortuneAnalysisPoints", fortuneAnalysisPoints))
+	return pointsType, nil
 }
 
-func initLlmPrompt() error {
-	subTree := viper.Sub("task.llmPrompt")
-	if subTree == nil {
-		z.Error("task.llmPrompt subTree is nil")
-		return fmt.Errorf("task.llmPrompt subTree is nil")
+func initLlmPrompt(raw map[string]any) (LlmPrompt, error) {
+	var llmPrompt LlmPrompt
+	if raw == nil {
+		z.Error("task.llmPrompt is not set")
+		return llmPrompt, fmt.Errorf("task.llmPrompt is not set")
 	}
 
-	if err := subTree.Unmarshal(&llmPrompt); err != nil {
+	if err := mapstructure.Decode(raw, &llmPrompt); err != nil {
 		z.Error("failed to unmarshal llmPrompt config", zap.Error(err))
-		return err
+		return llmPrompt, err
 	}
 
 	if llmPrompt.Prompt == "" {
 		z.Error("llmPrompt.Prompt is empty")
-		return fmt.Errorf("llmPrompt.Prompt is empty")
+		return llmPrompt, fmt.Errorf("llmPrompt.Prompt is empty")
 	}
 
-	return nil
+	return llmPrompt, nil
 }
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -31,10 +32,10 @@ func NewHandler() Handler {
 func (h *handler) HandleAnalyzeMyFortune(c *gin.Context) {
 	userId, ok := user.GetCurrentUserID(c)
 	if !ok {
-		z.Error("failed to get current userId from context")
-		c.JSON(http.StatusOK, gin.H{
-			"status": 401,
-			"msg":    "未登录或登录已过期",
+		cmn.LoggerFrom(c).Error("failed to get current userId from context")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 401,
+			Msg:    "未登录或登录已过期",
 		})
 		return
 	}
@@ -42,10 +43,10 @@ func (h *handler) HandleAnalyzeMyFortune(c *gin.Context) {
 	var req cmn.ReqProto
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		z.Error("failed to bind request JSON", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体结构错误",
+		cmn.LoggerFrom(c).Error("failed to bind request JSON", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体结构错误",
 		})
 		return
 	}
@@ -59,10 +60,10 @@ func (h *handler) HandleAnalyzeMyFortune(c *gin.Context) {
 	var reqData ReqData
 	err = json.Unmarshal(req.Data, &reqData)
 	if err != nil {
-		z.Error("failed to unmarshal request data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": 1,
-			"msg":    "请求体数据错误",
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求体数据错误",
 		})
 		return
 	}
@@ -70,18 +71,18 @@ func (h *handler) HandleAnalyzeMyFortune(c *gin.Context) {
 	var fortune Fortune
 	var points float64
 
-	err = cmn.GormDB.Transaction(func(tx *gorm.DB) error {
-		fortune, points, err = AnalyzeAndSaveFortune(c, tx, userId, reqData.Name, reqData.Gender, reqData.Birth)
+	err = cmn.GormDB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		fortune, points, err = AnalyzeAndSaveFortune(c.Request.Context(), tx, userId, reqData.Name, reqData.Gender, reqData.Birth)
 		if err != nil {
 			return err
 		}
 		return nil
 	})
 	if err != nil {
-		z.Error("failed to analyze fortune", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "运势分析失败",
+		cmn.LoggerFrom(c).Error("failed to analyze fortune", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "运势分析失败",
 		})
 		return
 	}
@@ -93,15 +94,15 @@ func (h *handler) HandleAnalyzeMyFortune(c *gin.Context) {
 
 	replyDataJson, err := json.Marshal(replyData)
 	if err != nil {
-		z.Error("failed to marshal reply data", zap.Error(err))
-		c.JSON(http.StatusOK, gin.H{
-			"status": -1,
-			"msg":    "响应数据序列化失败",
+		cmn.LoggerFrom(c).Error("failed to marshal reply data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "响应数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "success",
 		Data:   replyDataJson,
@@ -114,8 +115,8 @@ func (h *handler) HandleDailyCheckIn(c *gin.Context) {
 	// 获取当前用户ID
 	userId, ok := user.GetCurrentUserID(c)
 	if !ok {
-		z.Error("failed to get current user ID")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to get current user ID")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 401,
 			Msg:    "未登录或登录已过期",
 		})
@@ -143,27 +144,28 @@ func (h *handler) HandleDailyCheckIn(c *gin.Context) {
 			return nil
 		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 			// 数据库查询错误
-			z.Error("failed to query check in record", zap.Error(err), zap.String("user_id", userId.String()))
+			cmn.LoggerFrom(c).Error("failed to query check in record", zap.Error(err), zap.String("user_id", userId.String()))
 			return err
 		}
 
 		// 今天还没有签到，创建签到记录
+		s := currentSettings()
 		checkInRecord = cmn.TUserCheckIn{
 			UserId:    userId,
-			Points:    dailyCheckInPoints,
+			Points:    s.dailyCheckInPoints,
 			CreatedAt: now.UnixMilli(),
 		}
 
 		err = tx.Create(&checkInRecord).Error
 		if err != nil {
-			z.Error("failed to create check in record", zap.Error(err), zap.String("user_id", userId.String()))
+			cmn.LoggerFrom(c).Error("failed to create check in record", zap.Error(err), zap.String("user_id", userId.String()))
 			return err
 		}
 
 		// 累加用户积分
-		err = points_core.AddUserPoints(c, tx, userId, dailyCheckInPoints)
+		_, err = points_core.Credit(c, tx, userId, s.dailyCheckInPointsType, s.dailyCheckInPoints, points_core.ReasonCheckIn, strconv.FormatInt(checkInRecord.Id, 10))
 		if err != nil {
-			z.Error("failed to add user points", zap.Error(err), zap.String("user_id", userId.String()))
+			cmn.LoggerFrom(c).Error("failed to add user points", zap.Error(err), zap.String("user_id", userId.String()))
 			return err
 		}
 
@@ -172,8 +174,8 @@ func (h *handler) HandleDailyCheckIn(c *gin.Context) {
 	})
 
 	if err != nil {
-		z.Error("failed to process daily check in", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to process daily check in", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "签到失败",
 		})
@@ -189,8 +191,8 @@ func (h *handler) HandleDailyCheckIn(c *gin.Context) {
 
 	replyDataJson, err := json.Marshal(replyData)
 	if err != nil {
-		z.Error("failed to marshal reply data", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to marshal reply data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "响应数据序列化失败",
 		})
@@ -204,7 +206,7 @@ func (h *handler) HandleDailyCheckIn(c *gin.Context) {
 		msg = "签到成功"
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    msg,
 		Data:   replyDataJson,
@@ -216,8 +218,8 @@ func (h *handler) HandleQueryMyFortune(c *gin.Context) {
 	// 获取当前用户ID
 	userId, ok := user.GetCurrentUserID(c)
 	if !ok {
-		z.Error("failed to get current user ID")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to get current user ID")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 401,
 			Msg:    "未登录或登录已过期",
 		})
@@ -229,13 +231,13 @@ func (h *handler) HandleQueryMyFortune(c *gin.Context) {
 	err := cmn.GormDB.Where("user_id = ?", userId).First(&userFortune).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: 1,
 				Msg:    "暂无运势数据，请先进行运势分析",
 			})
 		} else {
-			z.Error("failed to query user fortune", zap.Error(err), zap.String("user_id", userId.String()))
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.LoggerFrom(c).Error("failed to query user fortune", zap.Error(err), zap.String("user_id", userId.String()))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: -1,
 				Msg:    "查询运势数据失败",
 			})
@@ -246,15 +248,15 @@ func (h *handler) HandleQueryMyFortune(c *gin.Context) {
 	// 将运势数据转换为JSON
 	userFortuneJSON, err := json.Marshal(userFortune)
 	if err != nil {
-		z.Error("failed to marshal user fortune data", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to marshal user fortune data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "运势数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "success",
 		Data:   userFortuneJSON,
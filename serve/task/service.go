@@ -3,7 +3,9 @@ package task
 import (
 	"WudangMeta/cmn"
 	"WudangMeta/cmn/llm"
+	"WudangMeta/cmn/metrics"
 	"WudangMeta/cmn/points_core"
+	"WudangMeta/cmn/tracing"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -19,7 +21,7 @@ func QueryExistFortuneUserData(ctx context.Context) ([]UserData, error) {
 	var records []cmn.TUserFortune
 	if err := cmn.GormDB.Select("user_id", "name", "gender", "birth").
 		Find(&records).Error; err != nil {
-		z.Error("failed to query luck tendency source data", zap.Error(err))
+		cmn.LoggerFrom(ctx).Error("failed to query luck tendency source data", zap.Error(err))
 		return nil, err
 	}
 
@@ -42,13 +44,17 @@ func QueryExistFortuneUserData(ctx context.Context) ([]UserData, error) {
 // 返回: 运势分析数据、错误
 func AnalyzeFortune(ctx context.Context, name, gender, birth string) (Fortune, error) {
 	if name == "" || gender == "" || birth == "" {
-		z.Error("name or gender or birth is empty")
+		cmn.LoggerFrom(ctx).Error("name or gender or birth is empty")
 		return Fortune{}, fmt.Errorf("name or gender or birth is empty")
 	}
 
+	var err error
+	ctx, span := tracing.StartSpan(ctx, "task.AnalyzeFortune")
+	defer func() { tracing.EndSpan(span, err) }()
+
 	llmService := llm.NewService()
 
-	prompt := llmPrompt
+	prompt := currentSettings().llmPrompt
 	prompt.UserInfo.Name = name
 	prompt.UserInfo.Gender = gender
 	prompt.UserInfo.Birth = birth
@@ -56,20 +62,23 @@ func AnalyzeFortune(ctx context.Context, name, gender, birth string) (Fortune, e
 	// 生成提示此字符串
 	promptStr, err := prompt.ToJSONString()
 	if err != nil {
-		z.Error("failed to convert prompt to JSON string", zap.Error(err))
+		cmn.LoggerFrom(ctx).Error("failed to convert prompt to JSON string", zap.Error(err))
 		return Fortune{}, err
 	}
 
 	// llm对话
-	output, err := llmService.Chat(promptStr)
+	output, err := llmService.Chat(ctx, promptStr)
 	if err != nil {
 		return Fortune{}, err
 	}
 
 	// 解析llm的输出
+	_, parseSpan := tracing.StartSpan(ctx, "task.ParseLlmOutput")
 	outputFormatted, err := ParseLlmOutputFormatWithMarkdown(output)
+	tracing.EndSpan(parseSpan, err)
 	if err != nil {
-		z.Error("failed to parse Llm output format", zap.Error(err))
+		cmn.LoggerFrom(ctx).Error("failed to parse Llm output format", zap.Error(err))
+		metrics.ObserveLlmParseFailure("fortune_output")
 		return Fortune{}, err
 	}
 
@@ -81,7 +90,7 @@ func AnalyzeFortune(ctx context.Context, name, gender, birth string) (Fortune, e
 func AnalyzeAndSaveFortune(ctx context.Context, db *gorm.DB, userId uuid.UUID, name, gender, birth string) (Fortune, float64, error) {
 	if userId == uuid.Nil || name == "" || gender == "" || birth == "" {
 		e := fmt.Errorf("invalid userId or name or gender or both: %s", userId.String())
-		z.Error(e.Error())
+		cmn.LoggerFrom(ctx).Error(e.Error())
 		return Fortune{}, 0, e
 	}
 	if db == nil {
@@ -93,6 +102,11 @@ func AnalyzeAndSaveFortune(ctx context.Context, db *gorm.DB, userId uuid.UUID, n
 		return Fortune{}, 0, err
 	}
 
+	// 保存运势与发放积分单独计时，便于区分耗时在模型调用还是数据库
+	ctx, saveSpan := tracing.StartSpan(ctx, "task.SaveFortune")
+	defer func() { tracing.EndSpan(saveSpan, err) }()
+	db = db.WithContext(ctx)
+
 	// 检查用户今天是否已经分析过运势
 	now := time.Now()
 	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
@@ -100,23 +114,23 @@ func AnalyzeAndSaveFortune(ctx context.Context, db *gorm.DB, userId uuid.UUID, n
 	tomorrowStart := todayStart.AddDate(0, 0, 1)
 	tomorrowStartMilli := tomorrowStart.UnixMilli()
 	var todayRowCount int64
-	err = cmn.GormDB.Model(&cmn.TUserFortune{}).
+	err = db.Model(&cmn.TUserFortune{}).
 		Where("user_id = ? AND updated_at >= ? AND updated_at < ?", userId, todayStartMilli, tomorrowStartMilli).
 		Count(&todayRowCount).Error
 	if err != nil {
 		e := fmt.Errorf("failed to check user today fortune: %w", err)
-		z.Error(e.Error(), zap.String("userId", userId.String()))
+		cmn.LoggerFrom(ctx).Error(e.Error(), zap.String("userId", userId.String()))
 		return Fortune{}, 0, err
 	}
 
 	// 检查用户是否存在运势记录（用于判断是插入还是更新）
 	var anyTimeRowCount int64
-	err = cmn.GormDB.Model(&cmn.TUserFortune{}).
+	err = db.Model(&cmn.TUserFortune{}).
 		Where("user_id = ?", userId).
 		Count(&anyTimeRowCount).Error
 	if err != nil {
 		e := fmt.Errorf("failed to check user luck tendency: %w", err)
-		z.Error(e.Error(), zap.String("userId", userId.String()))
+		cmn.LoggerFrom(ctx).Error(e.Error(), zap.String("userId", userId.String()))
 		return Fortune{}, 0, err
 	}
 
@@ -145,11 +159,12 @@ func AnalyzeAndSaveFortune(ctx context.Context, db *gorm.DB, userId uuid.UUID, n
 
 	// 只有当天第一次分析运势才增加积分
 	if todayRowCount == 0 {
-		err = points_core.AddUserPoints(ctx, db, userId, fortuneAnalysisPoints)
+		s := currentSettings()
+		_, err = points_core.Credit(ctx, db, userId, s.fortuneAnalysisPointsType, s.fortuneAnalysisPoints, points_core.ReasonFortune, "")
 		if err != nil {
 			return Fortune{}, 0, err
 		}
-		addedPoints = fortuneAnalysisPoints
+		addedPoints = s.fortuneAnalysisPoints
 	}
 
 	return fortune, addedPoints, nil
@@ -159,7 +174,7 @@ func AnalyzeAndSaveFortune(ctx context.Context, db *gorm.DB, userId uuid.UUID, n
 func InsertFortune(ctx context.Context, db *gorm.DB, userData UserData, tendency Fortune) error {
 	if userData.UserId == uuid.Nil {
 		e := fmt.Errorf("userId is empty")
-		z.Error(e.Error())
+		cmn.LoggerFrom(ctx).Error(e.Error())
 		return e
 	}
 	if db == nil {
@@ -168,7 +183,7 @@ func InsertFortune(ctx context.Context, db *gorm.DB, userData UserData, tendency
 
 	jsonData, err := json.Marshal(tendency)
 	if err != nil {
-		z.Error("failed to marshal luck tendency", zap.String("userId", userData.UserId.String()), zap.Error(err))
+		cmn.LoggerFrom(ctx).Error("failed to marshal luck tendency", zap.String("userId", userData.UserId.String()), zap.Error(err))
 		return err
 	}
 
@@ -182,7 +197,7 @@ func InsertFortune(ctx context.Context, db *gorm.DB, userData UserData, tendency
 
 	err = db.Model(&cmn.TUserFortune{}).Create(&record).Error
 	if err != nil {
-		z.Error("failed to create luck tendency", zap.String("userId", userData.UserId.String()), zap.Error(err))
+		cmn.LoggerFrom(ctx).Error("failed to create luck tendency", zap.String("userId", userData.UserId.String()), zap.Error(err))
 		return err
 	}
 
@@ -193,7 +208,7 @@ func InsertFortune(ctx context.Context, db *gorm.DB, userData UserData, tendency
 func UpdateFortune(ctx context.Context, db *gorm.DB, userData UserData, tendency Fortune) error {
 	if userData.UserId == uuid.Nil {
 		e := fmt.Errorf("userId is empty")
-		z.Error(e.Error())
+		cmn.LoggerFrom(ctx).Error(e.Error())
 		return e
 	}
 	if db == nil {
@@ -202,7 +217,7 @@ func UpdateFortune(ctx context.Context, db *gorm.DB, userData UserData, tendency
 
 	jsonData, err := json.Marshal(tendency)
 	if err != nil {
-		z.Error("failed to marshal luck tendency", zap.String("userId", userData.UserId.String()), zap.Error(err))
+		cmn.LoggerFrom(ctx).Error("failed to marshal luck tendency", zap.String("userId", userData.UserId.String()), zap.Error(err))
 		return err
 	}
 
@@ -216,7 +231,7 @@ func UpdateFortune(ctx context.Context, db *gorm.DB, userData UserData, tendency
 			"updated_at": time.Now().UnixMilli(),
 		}).Error
 	if err != nil {
-		z.Error("failed to update luck tendency", zap.String("userId", userData.UserId.String()), zap.Error(err))
+		cmn.LoggerFrom(ctx).Error("failed to update luck tendency", zap.String("userId", userData.UserId.String()), zap.Error(err))
 		return err
 	}
 
@@ -232,16 +247,21 @@ func RefreshAllUsersFortune(ctx context.Context) error {
 	}
 
 	for _, data := range userData {
+		// 服务关闭时停止处理剩余用户
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		// 获取当前用户的新运势数据
 		luckTendency, err := AnalyzeFortune(ctx, data.Name, data.Gender, data.Birth)
 		if err != nil {
-			z.Error("failed to analyze luck tendency data", zap.Error(err))
+			cmn.LoggerFrom(ctx).Error("failed to analyze luck tendency data", zap.Error(err))
 			continue // 继续处理下一个用户
 		}
 		// 更新用户的运势记录
 		err = UpdateFortune(ctx, nil, data, luckTendency)
 		if err != nil {
-			z.Error("failed to update luck tendency data", zap.String("userId", data.UserId.String()), zap.Error(err))
+			cmn.LoggerFrom(ctx).Error("failed to update luck tendency data", zap.String("userId", data.UserId.String()), zap.Error(err))
 			continue // 继续处理下一个用户
 		}
 	}
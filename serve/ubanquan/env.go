@@ -2,13 +2,14 @@ package ubanquan
 
 import (
 	"WudangMeta/cmn"
+	"context"
 
 	"go.uber.org/zap"
 )
 
 var z *zap.Logger
 
-func Init() {
+func Init(ctx context.Context) {
 	z = cmn.GetLogger()
 
 	cmn.MiniLogger.Info("[ OK ] ubanquan module initialized")
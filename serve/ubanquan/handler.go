@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -35,8 +36,8 @@ func (h *handler) HandleAuthentication(c *gin.Context) {
 	// 从 query 参数获取 code
 	code := c.Query("code") // 如果参数不存在会返回空字符串
 	if code == "" {
-		z.Error("missing query param: code")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("missing query param: code")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "缺少必要的 query 参数 code",
 		})
@@ -46,8 +47,8 @@ func (h *handler) HandleAuthentication(c *gin.Context) {
 	// 获取全局token
 	token := ubanquan_core.GetGlobalToken()
 	if token == nil {
-		z.Error("global token is not available")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("global token is not available")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "优版权token未初始化",
 		})
@@ -72,8 +73,8 @@ func (h *handler) HandleAuthentication(c *gin.Context) {
 	}
 	err := client.Do(fastReq, fastResp)
 	if err != nil {
-		z.Error("failed to send request to ubanquan API", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to send request to ubanquan API", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "向优版权发送用户授权请求失败",
 		})
@@ -95,8 +96,8 @@ func (h *handler) HandleAuthentication(c *gin.Context) {
 	var ubanquanResp UbanquanResponse
 	err = json.Unmarshal(fastResp.Body(), &ubanquanResp)
 	if err != nil {
-		z.Error("failed to unmarshal ubanquan response", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to unmarshal ubanquan response", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "优版权API响应解析失败",
 		})
@@ -105,8 +106,8 @@ func (h *handler) HandleAuthentication(c *gin.Context) {
 
 	// 检查优版权API响应状态
 	if !ubanquanResp.Success {
-		z.Error("ubanquan API returned error", zap.String("code", ubanquanResp.Code), zap.String("message", ubanquanResp.Message))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("ubanquan API returned error", zap.String("code", ubanquanResp.Code), zap.String("message", ubanquanResp.Message))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    fmt.Sprintf("优版权认证失败: %s", ubanquanResp.Message),
 		})
@@ -116,8 +117,8 @@ func (h *handler) HandleAuthentication(c *gin.Context) {
 	// 获取当前用户ID
 	userId, ok := user.GetCurrentUserID(c)
 	if !ok {
-		z.Error("failed to get current user ID")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to get current user ID")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 401,
 			Msg:    "未登录或登录已过期",
 		})
@@ -135,14 +136,14 @@ func (h *handler) HandleAuthentication(c *gin.Context) {
 		err = tx.Where("open_id = ? AND platform = ? AND user_id != ?", ubanquanResp.Data.OpenId, ubanquan_core.PlatformName, userId).First(&existingUserExternal).Error
 		if err == nil {
 			// 该openId已被其他用户绑定
-			z.Error("openId already bound to another user", zap.String("openId", ubanquanResp.Data.OpenId), zap.String("existingUserId", existingUserExternal.UserId.String()))
+			cmn.LoggerFrom(c).Error("openId already bound to another user", zap.String("openId", ubanquanResp.Data.OpenId), zap.String("existingUserId", existingUserExternal.UserId.String()))
 			status = -1
 			msg = "该优版权账号已被其他用户绑定，不允许重复绑定"
 			return fmt.Errorf("openId already bound to user %s", existingUserExternal.UserId.String())
 		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 			// 查询出错
 			e := fmt.Errorf("failed to check existing openId binding: %w", err)
-			z.Error(e.Error())
+			cmn.LoggerFrom(c).Error(e.Error())
 			status = -1
 			msg = "检查优版权账号绑定状态失败"
 			return e
@@ -164,21 +165,21 @@ func (h *handler) HandleAuthentication(c *gin.Context) {
 				err = tx.Create(&userExternal).Error
 				if err != nil {
 					e := fmt.Errorf("failed to create user external record: %w", err)
-					z.Error(e.Error())
+					cmn.LoggerFrom(c).Error(e.Error())
 					status = -1
 					msg = "创建用户外部信息失败"
 					return e
 				}
 			} else {
 				e := fmt.Errorf("failed to query user external record: %w", err)
-				z.Error(e.Error())
+				cmn.LoggerFrom(c).Error(e.Error())
 				status = -1
 				msg = "查询用户外部信息失败"
 				return e
 			}
 		} else {
 			e := fmt.Errorf("user external record already exists")
-			z.Error(e.Error())
+			cmn.LoggerFrom(c).Error(e.Error())
 			status = 1
 			msg = "已绑定优版权帐号，无需重复绑定"
 			return e
@@ -186,14 +187,14 @@ func (h *handler) HandleAuthentication(c *gin.Context) {
 		return nil
 	})
 	if err != nil {
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: status,
 			Msg:    msg,
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "success",
 	})
@@ -206,8 +207,8 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 	// 获取当前用户ID
 	userId, ok := user.GetCurrentUserID(c)
 	if !ok {
-		z.Error("failed to get current user ID")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to get current user ID")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 401,
 			Msg:    "未登录或登录已过期",
 		})
@@ -217,8 +218,8 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 	// 获取用户的外部openId
 	openId, ok := user.GetCurrentUserExternalOpenId(c)
 	if !ok || openId == "" {
-		z.Error("failed to get user external openId", zap.String("user_id", userId.String()))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to get user external openId", zap.String("user_id", userId.String()))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "用户未绑定优版权账号",
 		})
@@ -228,8 +229,8 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 	// 获取全局token
 	token := ubanquan_core.GetGlobalToken()
 	if token == nil {
-		z.Error("global token is not available")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("global token is not available")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "优版权token未初始化",
 		})
@@ -254,8 +255,8 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 	}
 	err := client.Do(fastReq, fastResp)
 	if err != nil {
-		z.Error("failed to send request to ubanquan card API", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to send request to ubanquan card API", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "获取用户资产信息失败",
 		})
@@ -265,8 +266,8 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 	var cardResp ubanquan_core.UbanquanCardResponse
 	err = json.Unmarshal(fastResp.Body(), &cardResp)
 	if err != nil {
-		z.Error("failed to unmarshal ubanquan card response", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to unmarshal ubanquan card response", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "解析用户资产信息失败",
 		})
@@ -275,8 +276,8 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 
 	// 检查API响应状态
 	if !cardResp.Success {
-		z.Error("ubanquan card API returned error", zap.Any("code", cardResp.Code), zap.Any("message", cardResp.Message))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("ubanquan card API returned error", zap.Any("code", cardResp.Code), zap.Any("message", cardResp.Message))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "获取用户资产信息失败",
 		})
@@ -310,7 +311,7 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 				}).Create(&metaAsset).Error
 				if err != nil {
 					e := fmt.Errorf("failed to insert meta asset with OnConflict: %w", err)
-					z.Error(e.Error())
+					cmn.LoggerFrom(c).Error(e.Error())
 					status = -1
 					msg = "插入元资产失败"
 					return e
@@ -321,7 +322,7 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 				err = tx.Where("name = ? AND platform = ?", assetData.MetaProductName, ubanquan_core.PlatformName).First(&queryMetaAsset).Error
 				if err != nil {
 					e := fmt.Errorf("failed to query meta asset after insert: %w", err)
-					z.Error(e.Error())
+					cmn.LoggerFrom(c).Error(e.Error())
 					status = -1
 					msg = "查询元资产失败"
 					return e
@@ -343,7 +344,7 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 				}).Create(&userAsset)
 				if result.Error != nil {
 					e := fmt.Errorf("failed to insert user asset with OnConflict: %w, user_id: %s", result.Error, userId.String())
-					z.Error(e.Error())
+					cmn.LoggerFrom(c).Error(e.Error())
 					status = -1
 					msg = "插入用户资产失败"
 					return e
@@ -351,13 +352,15 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 
 				// 检查是否为新插入的记录
 				if result.RowsAffected > 0 {
-					// 给用户增加该资产积分
-					err = points_core.AddUserPoints(c, tx, userId, queryMetaAsset.Value)
-					if err != nil {
-						e := fmt.Errorf("failed to add user points by asset: %w, user_id: %s, meta_asset_id: %d", err, userId.String(), queryMetaAsset.Id)
-						status = -1
-						msg = "添加用户积分失败"
-						return e
+					// 给用户增加该资产积分（未定价的元资产不产生积分）
+					if queryMetaAsset.Value > 0 {
+						_, err = points_core.Credit(c, tx, userId, points_core.PointsTypeDefault, queryMetaAsset.Value, points_core.ReasonAsset, strconv.FormatInt(userAsset.Id, 10))
+						if err != nil {
+							e := fmt.Errorf("failed to add user points by asset: %w, user_id: %s, meta_asset_id: %d", err, userId.String(), queryMetaAsset.Id)
+							status = -1
+							msg = "添加用户积分失败"
+							return e
+						}
 					}
 					addedCount++
 				} else {
@@ -383,7 +386,7 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 				extraPoints = 180
 			}
 			if extraPoints > 0 {
-				err = points_core.AddUserPoints(c, tx, userId, extraPoints)
+				_, err = points_core.Credit(c, tx, userId, points_core.PointsTypeDefault, extraPoints, points_core.ReasonActivity, "")
 				if err != nil {
 					e := fmt.Errorf("failed to add extra user points by addedCount: %w, user_id: %s", err, userId.String())
 					status = -1
@@ -396,7 +399,7 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 		return nil
 	})
 	if err != nil {
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: status,
 			Msg:    fmt.Sprintf("资产同步失败: %s", msg),
 		})
@@ -412,15 +415,15 @@ func (h *handler) HandleUpdateMyAsset(c *gin.Context) {
 
 	responseJson, err := json.Marshal(responseData)
 	if err != nil {
-		z.Error("failed to marshal response data", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to marshal response data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "响应数据序列化失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    fmt.Sprintf("资产同步完成，新增%d个，跳过%d个", addedCount, skippedCount),
 		Data:   responseJson,
@@ -2,11 +2,11 @@ package user
 
 import (
 	"WudangMeta/cmn"
+	"context"
 	"fmt"
 	"net/http"
 
 	"github.com/gorilla/sessions"
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 )
 
@@ -21,7 +21,7 @@ var (
 
 var z *zap.Logger
 
-func Init() {
+func Init(ctx context.Context) {
 	z = cmn.GetLogger()
 
 	err := initSessionStore()
@@ -33,17 +33,13 @@ func Init() {
 }
 
 func initSessionStore() error {
-	authKeyStr := viper.GetString("session.authKey")
-	if authKeyStr == "" {
+	cfg := cmn.GetConfig().Session
+	if cfg.AuthKey == "" || cfg.EncryptionKey == "" {
 		return fmt.Errorf("gorilla session store key is empty")
 	}
-	encryptionKeyStr := viper.GetString("session.encryptionKey")
-	if encryptionKeyStr == "" {
-		return fmt.Errorf("gorilla session store encryption key is empty")
-	}
 
-	authKey := []byte(authKeyStr)
-	encryptionKey := []byte(encryptionKeyStr)
+	authKey := []byte(cfg.AuthKey)
+	encryptionKey := []byte(cfg.EncryptionKey)
 
 	// 创建session store，配置需要与handler中保持一致
 	sessionStore = sessions.NewCookieStore(authKey, encryptionKey)
@@ -2,11 +2,13 @@ package user
 
 import (
 	"WudangMeta/cmn"
+	"WudangMeta/cmn/captcha"
 	"WudangMeta/cmn/points_core"
 	"WudangMeta/cmn/sms"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -19,6 +21,8 @@ import (
 
 type Handler interface {
 	HandleCheckLoginStatue(c *gin.Context)
+	HandleGetCaptcha(c *gin.Context)
+	HandleVerifyCaptcha(c *gin.Context)
 	HandleSendSMSCode(c *gin.Context)
 	HandleSMSLogin(c *gin.Context)
 	HandleGetCurrentUserInfo(c *gin.Context)
@@ -27,74 +31,231 @@ type Handler interface {
 }
 
 type handler struct {
-	smsSrv sms.Service
 }
 
 func NewHandler() Handler {
-	return &handler{
-		smsSrv: sms.NewService(),
-	}
+	return &handler{}
 }
 
 // HandleCheckIsLogin 检查用户是否已登录
 func (h *handler) HandleCheckLoginStatue(c *gin.Context) {
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "已登录",
 	})
 }
 
+// HandleGetCaptcha 获取图形验证挑战
+func (h *handler) HandleGetCaptcha(c *gin.Context) {
+	challenge, err := captcha.Generate(c, nil, c.ClientIP())
+	var limited *captcha.RateLimitError
+	if errors.As(err, &limited) {
+		retryAfter := int64(math.Ceil(limited.RetryAfter.Seconds()))
+		retryJson, _ := json.Marshal(map[string]int64{"retryAfter": retryAfter})
+		c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: http.StatusTooManyRequests,
+			Msg:    fmt.Sprintf("获取过于频繁，请%d秒后再试", retryAfter),
+			Data:   retryJson,
+		})
+		return
+	}
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "获取图形验证失败，请稍后再试",
+		})
+		return
+	}
+
+	challengeJson, _ := json.Marshal(challenge)
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "获取图形验证成功",
+		Data:   challengeJson,
+	})
+}
+
+// HandleVerifyCaptcha 提交图形验证答案，通过后返回一次性票据
+func (h *handler) HandleVerifyCaptcha(c *gin.Context) {
+	var req cmn.ReqProto
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求参数错误，请检查是否符合请求协议",
+		})
+		return
+	}
+
+	type data struct {
+		Id     string `json:"id"`
+		Answer string `json:"answer"`
+	}
+
+	var d data
+	err = json.Unmarshal(req.Data, &d)
+	if err != nil || d.Id == "" || d.Answer == "" {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "请求数据格式错误",
+		})
+		return
+	}
+
+	ticket, err := captcha.Verify(c, nil, d.Id, d.Answer, c.ClientIP())
+	if err != nil {
+		msg := "图形验证失败，请稍后再试"
+		status := -1
+		switch {
+		case errors.Is(err, captcha.ErrChallengeNotFound):
+			msg, status = "图形验证已过期，请重新获取", 1
+		case errors.Is(err, captcha.ErrWrongAnswer):
+			msg, status = "图形验证答案错误，请重新获取", 1
+		}
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: status,
+			Msg:    msg,
+		})
+		return
+	}
+
+	ticketJson, _ := json.Marshal(ticket)
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+		Status: 0,
+		Msg:    "图形验证通过",
+		Data:   ticketJson,
+	})
+}
+
 // HandleSendSMSCode 处理发送SMS验证码
 func (h *handler) HandleSendSMSCode(c *gin.Context) {
 	phone := c.Query("mobilePhone")
 	if phone == "" {
-		z.Error("phone number is empty")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("phone number is empty")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "手机号不能为空",
 		})
 		return
 	}
 
+	if !sms.IsValidPhone(phone) {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "手机号格式错误",
+		})
+		return
+	}
+
+	// 验证码用途，默认为登录
+	purpose := c.DefaultQuery("purpose", sms.PurposeLogin)
+	if !sms.IsValidPurpose(purpose) {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: 1,
+			Msg:    "验证码用途无效",
+		})
+		return
+	}
+
+	// 风险升高时要求先完成图形验证，票据使用一次后失效
+	captchaRequired, err := sms.CaptchaRequired(c, nil, phone, c.ClientIP())
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "发送短信验证码失败，请稍后再试",
+		})
+		return
+	}
+	if captchaRequired {
+		ticket := c.Query("captchaTicket")
+		err = captcha.ConsumeTicket(c, nil, ticket, c.ClientIP())
+		if errors.Is(err, captcha.ErrTicketInvalid) {
+			msg := "请先完成图形验证"
+			if ticket != "" {
+				msg = "图形验证已失效，请重新验证"
+			}
+			cmn.LoggerFrom(c).Warn("sms code request requires captcha", zap.String("phone", phone), zap.String("ip", c.ClientIP()))
+			requiredJson, _ := json.Marshal(map[string]bool{"captchaRequired": true})
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: http.StatusPreconditionRequired,
+				Msg:    msg,
+				Data:   requiredJson,
+			})
+			return
+		}
+		if err != nil {
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+				Status: -1,
+				Msg:    "发送短信验证码失败，请稍后再试",
+			})
+			return
+		}
+	}
+
+	decision, err := sms.CheckSendLimit(c, nil, phone, c.ClientIP())
+	if err != nil {
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "发送短信验证码失败，请稍后再试",
+		})
+		return
+	}
+	if !decision.Allowed {
+		retryAfter := int64(math.Ceil(decision.RetryAfter.Seconds()))
+		retryJson, _ := json.Marshal(map[string]int64{"retryAfter": retryAfter})
+		c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: http.StatusTooManyRequests,
+			Msg:    fmt.Sprintf("发送过于频繁，请%d秒后再试", retryAfter),
+			Data:   retryJson,
+		})
+		return
+	}
+
 	code := cmn.RandDigits(smsCodeLength)
 	if code == "" {
-		z.Error("failed to generate SMS code, code is empty")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to generate SMS code, code is empty")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "生成短信验证码失败",
 		})
 		return
 	}
 
-	err := h.smsSrv.SendVerifyCode(phone, code)
+	// 先保存再发送，避免用户收到无法校验的验证码；同一手机号同一用途之前发送的验证码随之失效
+	codeId, err := sms.SaveCode(c, nil, phone, purpose, code)
 	if err != nil {
-		c.JSON(http.StatusOK, cmn.ReplyProto{
-			Status: 1,
-			Msg:    "发送短信验证码失败: " + err.Error(),
+		sms.AbortSend(c, nil, decision.LogId, err)
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "发送短信验证码失败，请稍后再试",
 		})
 		return
 	}
 
-	codeRow := cmn.TSmsCodes{
-		MobilePhone: phone,
-		Code:        code,
-		ExpiresAt:   time.Now().UnixMilli() + 5*time.Minute.Milliseconds(), // 设置验证码有效期为5分钟
-	}
-	err = cmn.GormDB.Create(&codeRow).Error
+	// 在未超出每日额度的服务商间按优先级故障转移
+	provider, err := sms.Send(c.Request.Context(), phone, code, decision.Providers)
+	sms.RecordSendResult(c, nil, decision.LogId, provider, err)
 	if err != nil {
-		z.Error("failed to save SMS code", zap.Error(err), zap.String("phone", phone), zap.String("code", code))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
-			Status: 1,
-			Msg:    "保存短信验证码失败: " + err.Error(),
+		sms.MarkCodeSendFailed(c, nil, codeId)
+		cmn.LoggerFrom(c).Error("failed to send sms code", zap.String("phone", phone), zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: -1,
+			Msg:    "发送短信验证码失败，请稍后再试",
 		})
 		return
 	}
 
-	z.Info("sent sms code", zap.String("phone", phone), zap.String("code", code))
+	cmn.LoggerFrom(c).Info("sent sms code", zap.String("phone", phone), zap.String("purpose", purpose))
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	// 返回同一手机号可再次发送前的冷却时间，供客户端倒计时
+	cooldownJson, _ := json.Marshal(map[string]int64{"cooldown": int64(math.Ceil(decision.Cooldown.Seconds()))})
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "短信验证码已发送",
+		Data:   cooldownJson,
 	})
 	return
 }
@@ -104,8 +265,8 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 	var req cmn.ReqProto
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
-		z.Error("failed to bind request", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to bind request", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "请求参数错误，请检查是否符合请求协议",
 		})
@@ -120,8 +281,8 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 	var d data
 	err = json.Unmarshal(req.Data, &d)
 	if err != nil {
-		z.Error("failed to unmarshal request data", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to unmarshal request data", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "请求数据格式错误",
 		})
@@ -129,8 +290,8 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 	}
 
 	if d.MobilePhone == "" {
-		z.Error("phone number is empty")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("phone number is empty")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "手机号不能为空",
 		})
@@ -138,14 +299,32 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 	}
 
 	if d.Code == "" {
-		z.Error("verification code is empty")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("verification code is empty")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "验证码不能为空",
 		})
 		return
 	}
 
+	// 验证短信验证码，验证失败次数过多后验证码失效，验证成功后不可再次使用
+	err = sms.VerifyCode(c, nil, d.MobilePhone, sms.PurposeLogin, d.Code)
+	if err != nil {
+		msg := "验证码验证失败，请稍后再试"
+		status := -1
+		switch {
+		case errors.Is(err, sms.ErrCodeNotFound), errors.Is(err, sms.ErrCodeMismatch):
+			msg, status = "验证码错误或已过期，请重新获取", 1
+		case errors.Is(err, sms.ErrCodeLocked):
+			msg, status = "验证码错误次数过多，请重新获取", 1
+		}
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
+			Status: status,
+			Msg:    msg,
+		})
+		return
+	}
+
 	var user cmn.TUser
 
 	var (
@@ -154,30 +333,6 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 	)
 
 	err = cmn.GormDB.Transaction(func(tx *gorm.DB) error {
-		// 验证短信验证码
-		var smsCode cmn.TSmsCodes
-		err = tx.Where("mobile_phone = ? AND code = ? AND expires_at > ?", d.MobilePhone, d.Code, time.Now().UnixMilli()).First(&smsCode).Error
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				e := fmt.Errorf("verification code not found or expired, phone: %s, code: %s", d.MobilePhone, d.Code)
-				z.Error(e.Error())
-				status = 1
-				msg = "验证码错误或已过期，请重新获取"
-				return e
-			}
-			e := fmt.Errorf("failed to query verification code: %w, phone: %s, code: %s", err, d.MobilePhone, d.Code)
-			z.Error(e.Error())
-			status = -1
-			msg = "验证码验证失败，请稍后再试"
-			return e
-		}
-
-		// 验证成功后删除验证码
-		err = tx.Delete(&smsCode).Error
-		if err != nil {
-			z.Error("failed to delete verification code", zap.Error(err))
-		}
-
 		// 查找或创建用户
 		err = tx.Where("mobile_phone = ?", d.MobilePhone).First(&user).Error
 		if err != nil {
@@ -193,15 +348,15 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 				err = tx.Create(&user).Error
 				if err != nil {
 					e := fmt.Errorf("failed to create user: %w, phone: %s", err, d.MobilePhone)
-					z.Error(e.Error())
+					cmn.LoggerFrom(c).Error(e.Error())
 					msg = "创建用户失败"
 					status = -1
 					return e
 				}
-				z.Info("new user registered", zap.String("phone", d.MobilePhone), zap.String("userId", user.Id.String()))
+				cmn.LoggerFrom(c).Info("new user registered", zap.String("phone", d.MobilePhone), zap.String("userId", user.Id.String()))
 			} else {
 				e := fmt.Errorf("failed to query user: %w, phone: %s", err, d.MobilePhone)
-				z.Error(e.Error())
+				cmn.LoggerFrom(c).Error(e.Error())
 				msg = "查询用户失败"
 				status = -1
 				return e
@@ -213,7 +368,7 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 				"login_time": time.Now().UnixMilli(),
 			}).Error
 			if err != nil {
-				z.Error("failed to update user login time", zap.Error(err))
+				cmn.LoggerFrom(c).Error("failed to update user login time", zap.Error(err))
 			}
 		}
 
@@ -229,7 +384,7 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 		// 检查用户状态
 		if user.Status != "00" {
 			e := fmt.Errorf("user is disabled, userId: %s, status: %s", user.Id.String(), user.Status)
-			z.Error(e.Error())
+			cmn.LoggerFrom(c).Error(e.Error())
 			msg = "用户已被禁用"
 			status = 1
 			return e
@@ -239,7 +394,7 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 		session, err := sessionStore.Get(c.Request, userSessionKey)
 		if err != nil {
 			e := fmt.Errorf("failed to get session: %w", err)
-			z.Error(e.Error())
+			cmn.LoggerFrom(c).Error(e.Error())
 			msg = "创建session失败"
 			status = -1
 			return e
@@ -254,7 +409,7 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 		err = session.Save(c.Request, c.Writer)
 		if err != nil {
 			e := fmt.Errorf("failed to save session: %w", err)
-			z.Error(e.Error())
+			cmn.LoggerFrom(c).Error(e.Error())
 			msg = "保存session失败"
 			status = -1
 			return e
@@ -263,14 +418,14 @@ func (h *handler) HandleSMSLogin(c *gin.Context) {
 		return nil
 	})
 	if err != nil {
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: status,
 			Msg:    fmt.Sprintf("登录失败: %s", msg),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "登录成功",
 	})
@@ -282,8 +437,8 @@ func (h *handler) HandleGetCurrentUserInfo(c *gin.Context) {
 	// 获取当前用户ID
 	userID, exists := GetCurrentUserID(c)
 	if !exists {
-		z.Error("failed to get current user ID from context")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to get current user ID from context")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 401,
 			Msg:    "用户未登录或登录已过期",
 		})
@@ -295,15 +450,15 @@ func (h *handler) HandleGetCurrentUserInfo(c *gin.Context) {
 	err := cmn.GormDB.Where("id = ?", userID).First(&userInfo).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			z.Error("user not found in VUserInfo", zap.String("userID", userID.String()))
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.LoggerFrom(c).Error("user not found in VUserInfo", zap.String("userID", userID.String()))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: 1,
 				Msg:    "用户信息不存在",
 			})
 			return
 		}
-		z.Error("failed to query user info from VUserInfo", zap.Error(err), zap.String("userID", userID.String()))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to query user info from VUserInfo", zap.Error(err), zap.String("userID", userID.String()))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询用户信息失败",
 		})
@@ -313,15 +468,15 @@ func (h *handler) HandleGetCurrentUserInfo(c *gin.Context) {
 	// 将用户信息序列化为JSON并返回
 	userInfoJSON, err := json.Marshal(userInfo)
 	if err != nil {
-		z.Error("failed to marshal user info", zap.Error(err), zap.String("userID", userID.String()))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to marshal user info", zap.Error(err), zap.String("userID", userID.String()))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "序列化用户信息失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "获取用户信息成功",
 		Data:   userInfoJSON,
@@ -334,8 +489,8 @@ func (h *handler) HandleGetUserInfoByPhone(c *gin.Context) {
 	// 从query参数获取手机号
 	mobilePhone := c.Query("mobilePhone")
 	if mobilePhone == "" {
-		z.Error("mobile phone is empty")
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("mobile phone is empty")
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: 1,
 			Msg:    "手机号不能为空",
 		})
@@ -347,15 +502,15 @@ func (h *handler) HandleGetUserInfoByPhone(c *gin.Context) {
 	err := cmn.GormDB.Where("mobile_phone = ?", mobilePhone).First(&userInfo).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			z.Error("user not found by mobile phone", zap.String("mobilePhone", mobilePhone))
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.LoggerFrom(c).Error("user not found by mobile phone", zap.String("mobilePhone", mobilePhone))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: 1,
 				Msg:    "用户不存在",
 			})
 			return
 		}
-		z.Error("failed to query user info by mobile phone", zap.Error(err), zap.String("mobilePhone", mobilePhone))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to query user info by mobile phone", zap.Error(err), zap.String("mobilePhone", mobilePhone))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询用户信息失败",
 		})
@@ -365,15 +520,15 @@ func (h *handler) HandleGetUserInfoByPhone(c *gin.Context) {
 	// 将用户信息序列化为JSON并返回
 	userInfoJSON, err := json.Marshal(userInfo)
 	if err != nil {
-		z.Error("failed to marshal user info", zap.Error(err), zap.String("mobilePhone", mobilePhone))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to marshal user info", zap.Error(err), zap.String("mobilePhone", mobilePhone))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "序列化用户信息失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status: 0,
 		Msg:    "查询用户信息成功",
 		Data:   userInfoJSON,
@@ -414,8 +569,8 @@ func (h *handler) HandleQueryUserInfoList(c *gin.Context) {
 	// 查询总记录数
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
-		z.Error("failed to count user info", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to count user info", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询用户总数失败",
 		})
@@ -427,8 +582,8 @@ func (h *handler) HandleQueryUserInfoList(c *gin.Context) {
 	offset := (page - 1) * pageSize
 	err = query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&userInfoList).Error
 	if err != nil {
-		z.Error("failed to query user info list", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to query user info list", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "查询用户信息列表失败",
 		})
@@ -438,15 +593,15 @@ func (h *handler) HandleQueryUserInfoList(c *gin.Context) {
 	// 将响应数据序列化为JSON
 	userInfoListJSON, err := json.Marshal(userInfoList)
 	if err != nil {
-		z.Error("failed to marshal user info list", zap.Error(err))
-		c.JSON(http.StatusOK, cmn.ReplyProto{
+		cmn.LoggerFrom(c).Error("failed to marshal user info list", zap.Error(err))
+		cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 			Status: -1,
 			Msg:    "序列化用户信息列表失败",
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, cmn.ReplyProto{
+	cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 		Status:   0,
 		Msg:      "查询用户信息列表成功",
 		Data:     userInfoListJSON,
@@ -18,8 +18,8 @@ func AuthMiddleware() gin.HandlerFunc {
 		// 获取session
 		session, err := sessionStore.Get(c.Request, userSessionKey)
 		if err != nil {
-			z.Error("failed to get session", zap.Error(err))
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.LoggerFrom(c).Error("failed to get session", zap.Error(err))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: 401,
 				Msg:    "未登录或登录已过期",
 			})
@@ -30,8 +30,8 @@ func AuthMiddleware() gin.HandlerFunc {
 		// 检查session中是否有用户ID
 		userIdStr, ok := session.Values["user_id"].(string)
 		if !ok || userIdStr == "" {
-			z.Error("user_id not found in session")
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.LoggerFrom(c).Error("user_id not found in session")
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: 401,
 				Msg:    "未登录或登录已过期",
 			})
@@ -42,8 +42,8 @@ func AuthMiddleware() gin.HandlerFunc {
 		// 解析用户ID
 		userId, err := uuid.Parse(userIdStr)
 		if err != nil {
-			z.Error("invalid user_id in session", zap.Error(err), zap.String("user_id", userIdStr))
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.LoggerFrom(c).Error("invalid user_id in session", zap.Error(err), zap.String("user_id", userIdStr))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: 401,
 				Msg:    "用户信息无效",
 			})
@@ -56,16 +56,16 @@ func AuthMiddleware() gin.HandlerFunc {
 		err = cmn.GormDB.Where("id = ?", userId).First(&user).Error
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
-				z.Error("user not found", zap.String("user_id", userIdStr))
-				c.JSON(http.StatusOK, cmn.ReplyProto{
+				cmn.LoggerFrom(c).Error("user not found", zap.String("user_id", userIdStr))
+				cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 					Status: 401,
 					Msg:    "用户不存在",
 				})
 				c.Abort()
 				return
 			}
-			z.Error("failed to query user", zap.Error(err), zap.String("user_id", userIdStr))
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.LoggerFrom(c).Error("failed to query user", zap.Error(err), zap.String("user_id", userIdStr))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: -1,
 				Msg:    "查询用户信息失败",
 			})
@@ -75,8 +75,8 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		// 检查用户状态
 		if user.Status != "00" {
-			z.Error("user is disabled", zap.String("user_id", userIdStr), zap.String("status", user.Status))
-			c.JSON(http.StatusOK, cmn.ReplyProto{
+			cmn.LoggerFrom(c).Error("user is disabled", zap.String("user_id", userIdStr), zap.String("status", user.Status))
+			cmn.Reply(c, http.StatusOK, cmn.ReplyProto{
 				Status: 403,
 				Msg:    "用户已被禁用",
 			})
@@ -89,13 +89,14 @@ func AuthMiddleware() gin.HandlerFunc {
 		err = cmn.GormDB.Where("user_id = ?", userId).First(&userExternal).Error
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 			// 如果是其他错误（非记录不存在），记录日志但不中断请求
-			z.Warn("failed to query user external info", zap.Error(err), zap.String("user_id", userIdStr))
+			cmn.LoggerFrom(c).Warn("failed to query user external info", zap.Error(err), zap.String("user_id", userIdStr))
 		}
 
 		// 将用户信息存储到上下文中，供后续处理器使用
 		c.Set("current_user", user)
 		c.Set("user_id", user.Id.String())
 		c.Set("mobile_phone", user.MobilePhone)
+		cmn.AddLogFields(c, zap.String("userId", user.Id.String()))
 
 		// 存储外部用户信息（可能为空）
 		if err == nil {
@@ -104,7 +105,7 @@ func AuthMiddleware() gin.HandlerFunc {
 			c.Set("external_open_id", userExternal.OpenId)
 			c.Set("external_nick_name", userExternal.NickName)
 			c.Set("external_avatar", userExternal.Avatar)
-			z.Debug("user authenticated with external info",
+			cmn.LoggerFrom(c).Debug("user authenticated with external info",
 				zap.String("user_id", user.Id.String()),
 				zap.String("mobile_phone", user.MobilePhone),
 				zap.String("external_open_id", userExternal.OpenId))